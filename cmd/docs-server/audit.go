@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"docs-server/internal/app"
+	"docs-server/internal/jose"
+	"docs-server/internal/service"
+)
+
+// runAuditVerify проверяет цепочку хешей журнала аудита и подписи контрольных точек:
+//
+//	docs-server audit-verify [-keys pub1.pem,pub2.pem]
+//
+// Без -keys подписи проверяются ключами audit.checkpoint_key и audit.checkpoint_verification_keys
+func runAuditVerify(auditService *service.AuditService, cfg *app.Config, args []string) error {
+	flags := flag.NewFlagSet("audit-verify", flag.ContinueOnError)
+	keyFiles := flags.String("keys", "", "comma-separated PEM files of checkpoint verification keys")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var keys *jose.KeySet
+	var err error
+	if *keyFiles != "" {
+		keys, err = jose.LoadVerificationKeySet(strings.Split(*keyFiles, ","))
+	} else {
+		keys, err = cfg.NewAuditCheckpointKeys()
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := auditService.VerifyChain(context.Background(), keys)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("events: %d, unchained: %d, checkpoints: %d, signatures checked: %t\n",
+		report.Events, report.Unchained, report.Checkpoints, report.SignaturesChecked)
+	if broken := report.Broken; broken != nil {
+		fmt.Printf("first broken link: event %d, checkpoint %q: %s\n", broken.EventID, broken.Checkpoint, broken.Reason)
+		return errors.New("audit chain verification failed")
+	}
+
+	fmt.Println("audit chain OK")
+	return nil
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"docs-server/internal/controller"
 	"log"
+	"os"
 
 	"docs-server/internal/app"
 	"docs-server/internal/cache"
@@ -28,13 +30,136 @@ func main() {
 	docRepo := repository.NewDocumentRepository(cfg.Database.DSN)
 	defer docRepo.Close()
 
+	sessionRepo := repository.NewSessionRepository(cfg.Database.DSN)
+	defer sessionRepo.Close()
+
+	apiKeyRepo := repository.NewAPIKeyRepository(cfg.Database.DSN)
+	defer apiKeyRepo.Close()
+
+	auditRepo := repository.NewAuditRepository(cfg.Database.DSN)
+	defer auditRepo.Close()
+
+	webhookRepo := repository.NewWebhookRepository(cfg.Database.DSN)
+	defer webhookRepo.Close()
+
 	// Инициализация кеша
 	cache := cache.NewMemoryCache()
 
 	// Инициализация сервисов
-	authService := service.NewAuthService(userRepo, cfg.Auth.AdminToken, []byte(cfg.Auth.JWTSecret), cache)
-	docService := service.NewDocumentService(docRepo, userRepo, cache, cfg.Storage.UploadDir)
-	userService := service.NewUserService(userRepo)
+	hasher, err := cfg.NewPasswordHasher()
+	if err != nil {
+		log.Fatalf("Failed to configure password hashing: %v", err)
+	}
+	policy, err := cfg.NewCredentialPolicy()
+	if err != nil {
+		log.Fatalf("Failed to configure credential policy: %v", err)
+	}
+	authenticators, err := cfg.NewAuthenticators(userRepo, hasher, policy)
+	if err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
+	}
+	tokens, err := cfg.NewTokenIssuer()
+	if err != nil {
+		log.Fatalf("Failed to configure JWT: %v", err)
+	}
+	denylist := cfg.NewTokenDenylist()
+	authService := service.NewAuthService(userRepo, sessionRepo, apiKeyRepo, cfg.Auth.AdminToken, hasher, policy, tokens, denylist, cache, service.PasswordReset{
+		Notifier: cfg.NewNotifier(),
+		TTL:      cfg.PasswordReset.TTL,
+		URL:      cfg.PasswordReset.URL,
+	}, cfg.NewLoginGuard(cache), service.MFAPolicy{
+		Required:     cfg.MFA.Required,
+		Issuer:       cfg.MFA.Issuer,
+		ChallengeTTL: cfg.MFA.ChallengeTTL,
+	}, cfg.NewOIDCLogin(), authenticators)
+	webhookService := service.NewWebhookService(webhookRepo, service.WebhookPolicy{
+		Timeout:      cfg.Webhooks.Timeout,
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		AllowPrivate: cfg.Webhooks.AllowPrivate,
+	})
+	eventStream := cfg.NewEventStream()
+	outbox, err := cfg.NewOutbox(webhookService, eventStream)
+	if err != nil {
+		log.Fatalf("Failed to configure outbox: %v", err)
+	}
+	docService := service.NewDocumentService(docRepo, userRepo, cache, cfg.Storage.UploadDir, service.Quota{
+		MaxBytes:     cfg.Quota.MaxBytes,
+		MaxDocuments: cfg.Quota.MaxDocuments,
+	}, service.RetentionPolicy{
+		Default: cfg.Retention.Default,
+		ByMime:  cfg.Retention.ByMime,
+		ByUser:  cfg.Retention.ByUser,
+	}, outbox)
+	userService := service.NewUserService(userRepo, authService, docService)
+	auditService := service.NewAuditService(auditRepo)
+	checkpointKeys, err := cfg.NewAuditCheckpointKeys()
+	if err != nil {
+		log.Fatalf("Failed to configure audit checkpoints: %v", err)
+	}
+
+	// Подкоманда сверки каталога загрузок с БД
+	if len(os.Args) > 1 && os.Args[1] == "gc" {
+		if err := runGC(docService, cfg, os.Args[2:]); err != nil {
+			log.Fatalf("gc: %v", err)
+		}
+		return
+	}
+
+	// Подкоманда проверки цепочки журнала аудита
+	if len(os.Args) > 1 && os.Args[1] == "audit-verify" {
+		if err := runAuditVerify(auditService, cfg, os.Args[2:]); err != nil {
+			log.Fatalf("audit-verify: %v", err)
+		}
+		return
+	}
+
+	// Фоновое удаление файлов из очереди pending_deletes
+	if cfg.Storage.DeleteRetryInterval > 0 {
+		pendingDeleteWorker := service.NewPendingDeleteWorker(docService, cfg.Storage.DeleteRetryInterval)
+		go pendingDeleteWorker.Run(context.Background())
+	}
+
+	// Фоновая очистка корзины
+	if cfg.Storage.TrashPurgeInterval > 0 {
+		trashPurger := service.NewTrashPurger(docService, cfg.Storage.TrashRetention, cfg.Storage.TrashPurgeInterval)
+		go trashPurger.Run(context.Background())
+	}
+
+	// Фоновое удаление документов с истекшим сроком хранения
+	if cfg.Retention.CheckInterval > 0 {
+		expiryScheduler := service.NewExpiryScheduler(docService, cfg.Retention.CheckInterval)
+		go expiryScheduler.Run(context.Background())
+	}
+
+	// Синхронизация списка отозванных токенов между экземплярами
+	if denylist != nil {
+		go denylist.Run(context.Background())
+	}
+
+	// Фоновая сверка каталога загрузок с БД
+	if cfg.GC.Interval > 0 {
+		garbageCollector := service.NewGarbageCollector(docService, cfg.GC.Interval, cfg.GC.GracePeriod, cfg.GC.Fix)
+		go garbageCollector.Run(context.Background())
+	}
+
+	// Публикация событий документов из outbox
+	outboxRelay := service.NewOutboxRelay(outbox, cfg.Outbox.RelayInterval)
+	go outboxRelay.Run(context.Background())
+
+	// Доставка событий документов на webhook
+	if cfg.Webhooks.Enabled {
+		webhookDispatcher := service.NewWebhookDispatcher(webhookService, cfg.Webhooks.RetryInterval)
+		go webhookDispatcher.Run(context.Background())
+	}
+
+	// Журнал событий для потока /api/events
+	go eventStream.Run(context.Background())
+
+	// Подпись контрольных точек журнала аудита
+	if cfg.Audit.CheckpointInterval > 0 && checkpointKeys != nil {
+		auditCheckpointer := service.NewAuditCheckpointer(auditService, checkpointKeys, cfg.Audit.CheckpointInterval)
+		go auditCheckpointer.Run(context.Background())
+	}
 
 	// Создание Fiber приложения
 	application := fiber.New(fiber.Config{
@@ -48,24 +173,102 @@ func main() {
 	application.Use(controller.UnifiedErrorHandler)
 
 	// Инициализация контроллеров
-	authController := controller.NewAuthController(authService)
+	authController := controller.NewAuthController(authService, auditService)
 
-	docsController := controller.NewDocsController(docService, userService)
+	docsController := controller.NewDocsController(docService, userService, auditService)
+	adminController := controller.NewAdminController(userService, authService)
+	accountController := controller.NewAccountController(userService, authService)
+	auditController := controller.NewAuditController(auditService)
+	webhookController := controller.NewWebhookController(webhookService, false)
+	adminWebhookController := controller.NewWebhookController(webhookService, true)
+	eventsController := controller.NewEventsController(eventStream, cfg.Events.Heartbeat)
 
 	// Настройка маршрутов
+	application.Get("/.well-known/jwks.json", authController.JWKS)
+
 	api := application.Group("/api")
+	requireAuth := controller.AuthMiddleware(authService, cfg.Auth.AllowRawToken)
 
 	api.Post("/register", authController.Register)
 	api.Post("/auth", authController.Authenticate)
-	api.Delete("/auth/:token", authController.Logout)
+	api.Post("/auth/refresh", authController.Refresh)
+	api.Post("/auth/mfa", authController.CompleteMFA)
+	api.Post("/auth/mfa/enroll", authController.BeginMFAEnrollment)
+	api.Get("/auth/oidc/login", authController.OIDCLogin)
+	api.Get("/auth/oidc/callback", authController.OIDCCallback)
+	api.Post("/auth/password-reset", authController.RequestPasswordReset)
+	api.Post("/auth/password-reset/confirm", authController.ResetPassword)
+	api.Delete("/auth", requireAuth, controller.SessionOnly, authController.Logout)
 
-	docs := api.Group("/docs", controller.AuthMiddleware(authService))
+	docs := api.Group("/docs", requireAuth)
 
 	docs.Post("/", docsController.UploadDocument)
 	docs.Get("/", docsController.GetDocumentsList)
 	docs.Get("/:id", docsController.GetDocument)
+	docs.Patch("/:id", docsController.UpdateDocument)
 	docs.Delete("/:id", docsController.DeleteDocument)
 
+	// Учетная запись текущего пользователя
+	me := api.Group("/me", requireAuth)
+	me.Get("/", accountController.GetProfile)
+	me.Get("/usage", docsController.GetUsage)
+	me.Post("/password", controller.SessionOnly, accountController.ChangePassword)
+	me.Delete("/", controller.SessionOnly, accountController.DeleteAccount)
+	me.Post("/totp", controller.SessionOnly, accountController.EnrollTOTP)
+	me.Post("/totp/confirm", controller.SessionOnly, accountController.ConfirmTOTP)
+	me.Delete("/totp", controller.SessionOnly, accountController.DisableTOTP)
+	me.Post("/oidc", controller.SessionOnly, accountController.LinkOIDC)
+
+	// Корзина
+	trash := api.Group("/trash", requireAuth)
+	trash.Get("/", docsController.GetTrash)
+	trash.Post("/:id/restore", docsController.RestoreDocument)
+	trash.Delete("/:id", docsController.PurgeDocument)
+
+	// Сессии
+	sessions := api.Group("/sessions", requireAuth)
+	sessions.Get("/", authController.ListSessions)
+	sessions.Delete("/:id", authController.RevokeSession)
+
+	// API-ключи
+	keys := api.Group("/keys", requireAuth, controller.SessionOnly)
+	keys.Post("/", authController.CreateAPIKey)
+	keys.Get("/", authController.ListAPIKeys)
+	keys.Delete("/:id", authController.RevokeAPIKey)
+
+	// Webhook
+	hooks := api.Group("/webhooks", requireAuth)
+	hooks.Post("/", webhookController.CreateWebhook)
+	hooks.Get("/", webhookController.ListWebhooks)
+	hooks.Delete("/:id", webhookController.DeleteWebhook)
+	hooks.Get("/:id/deliveries", webhookController.ListDeliveries)
+	hooks.Post("/:id/deliveries/:delivery/redeliver", webhookController.RedeliverDelivery)
+
+	// Поток событий документов
+	api.Get("/events", requireAuth, eventsController.Stream)
+
+	// Администрирование пользователей
+	admin := api.Group("/admin", requireAuth, controller.AdminOnly)
+	admin.Get("/users", adminController.ListUsers)
+	admin.Post("/users", adminController.CreateUser)
+	admin.Get("/users/:id", adminController.GetUser)
+	admin.Patch("/users/:id", adminController.UpdateUser)
+	admin.Delete("/users/:id", adminController.DeleteUser)
+	admin.Post("/users/:id/password-reset", adminController.ResetPassword)
+	admin.Post("/users/:id/unlock", adminController.UnlockUser)
+	admin.Delete("/users/:id/totp", adminController.ResetTOTP)
+
+	// Журнал аудита
+	admin.Get("/audit", auditController.ListEvents)
+	admin.Get("/audit/export", auditController.ExportEvents)
+
+	// Глобальные webhook
+	admin.Post("/webhooks", adminWebhookController.CreateWebhook)
+	admin.Get("/webhooks", adminWebhookController.ListWebhooks)
+	admin.Delete("/webhooks/:id", adminWebhookController.DeleteWebhook)
+	admin.Get("/webhooks/:id/deliveries", adminWebhookController.ListDeliveries)
+	admin.Post("/webhooks/:id/deliveries/:delivery/redeliver", adminWebhookController.RedeliverDelivery)
+
 	// Запуск сервера
 	log.Fatal(application.Listen(":" + cfg.Server.Port))
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"docs-server/internal/app"
+	"docs-server/internal/service"
+)
+
+// runGC выполняет однократную сверку каталога загрузок с БД:
+//
+//	docs-server gc [-fix] [-grace 1h]
+//
+// Без -fix только выводит отчет.
+func runGC(docService *service.DocumentService, cfg *app.Config, args []string) error {
+	flags := flag.NewFlagSet("gc", flag.ContinueOnError)
+	fix := flags.Bool("fix", false, "remove orphan files and documents without files (default: only report)")
+	grace := flags.Duration("grace", cfg.GC.GracePeriod, "files younger than this are not considered orphaned")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := docService.CollectGarbage(context.Background(), *grace, *fix)
+	if report != nil {
+		for _, path := range report.OrphanFiles {
+			fmt.Printf("orphan file: %s\n", path)
+		}
+		for _, id := range report.DanglingDocuments {
+			fmt.Printf("dangling document: %s\n", id)
+		}
+		for _, id := range report.HeldDocuments {
+			fmt.Printf("dangling document under legal hold, kept: %s\n", id)
+		}
+		fmt.Printf("orphan files: %d, dangling documents: %d, removed files: %d, removed documents: %d\n",
+			len(report.OrphanFiles), len(report.DanglingDocuments), report.RemovedFiles, report.RemovedDocuments)
+	}
+
+	return err
+}
@@ -0,0 +1,220 @@
+package audit_test
+
+import (
+	"bufio"
+	"context"
+	"docs-server/internal/controller"
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore журнал в памяти с цепочкой хешей и фильтрацией по действию, актору и времени
+type memoryStore struct {
+	mu          sync.Mutex
+	events      []*model.AuditEvent
+	headID      int64
+	headHash    string
+	checkpoints []*model.AuditCheckpoint
+}
+
+func (s *memoryStore) InsertAuditEvent(ctx context.Context, event *model.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, err := event.ChainHash(s.headHash)
+	if err != nil {
+		return err
+	}
+	event.ID = s.headID + 1
+	event.PrevHash = s.headHash
+	event.Hash = hash
+	s.events = append(s.events, event)
+	s.headID, s.headHash = event.ID, event.Hash
+	return nil
+}
+
+func (s *memoryStore) match(filter *model.AuditFilter) []*model.AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []*model.AuditEvent
+	for _, event := range s.events {
+		if filter.Action != "" && event.Action != filter.Action {
+			continue
+		}
+		if filter.Actor != "" && event.ActorID != filter.Actor && event.ActorLogin != filter.Actor {
+			continue
+		}
+		if filter.From != nil && event.Time.Before(*filter.From) || filter.To != nil && !event.Time.Before(*filter.To) {
+			continue
+		}
+		matched = append(matched, event)
+	}
+	return matched
+}
+
+func (s *memoryStore) ListAuditEvents(ctx context.Context, filter *model.AuditFilter, limit, offset int) ([]*model.AuditEvent, error) {
+	matched := s.match(filter)
+	var page []*model.AuditEvent
+	for i := len(matched) - 1 - offset; i >= 0 && len(page) < limit; i-- {
+		page = append(page, matched[i])
+	}
+	return page, nil
+}
+
+func (s *memoryStore) CountAuditEvents(ctx context.Context, filter *model.AuditFilter) (int, error) {
+	return len(s.match(filter)), nil
+}
+
+func (s *memoryStore) ExportAuditEvents(ctx context.Context, filter *model.AuditFilter, fn func(*model.AuditEvent) error) error {
+	for _, event := range s.match(filter) {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) AuditChainHead(ctx context.Context) (int64, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.headID, s.headHash, nil
+}
+
+func (s *memoryStore) LastAuditEventBefore(ctx context.Context, before time.Time) (*model.AuditEvent, error) {
+	matched := s.match(&model.AuditFilter{To: &before})
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	return matched[len(matched)-1], nil
+}
+
+func (s *memoryStore) FirstAuditEventTime(ctx context.Context) (*time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == 0 {
+		return nil, nil
+	}
+	return &s.events[0].Time, nil
+}
+
+func (s *memoryStore) InsertAuditCheckpoint(ctx context.Context, checkpoint *model.AuditCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints = append(s.checkpoints, checkpoint)
+	return nil
+}
+
+func (s *memoryStore) ListAuditCheckpoints(ctx context.Context) ([]*model.AuditCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*model.AuditCheckpoint(nil), s.checkpoints...), nil
+}
+
+func newApp(auditService *service.AuditService) *fiber.App {
+	auditController := controller.NewAuditController(auditService)
+
+	application := fiber.New()
+	application.Use(controller.UnifiedErrorHandler)
+	application.Get("/audit", auditController.ListEvents)
+	application.Get("/audit/export", auditController.ExportEvents)
+	return application
+}
+
+func TestAuditService_Record(t *testing.T) {
+	store := &memoryStore{}
+	auditService := service.NewAuditService(store)
+
+	auditService.Record(&model.AuditEvent{Action: model.AuditLogin, ActorID: "user-1"})
+	require.Len(t, store.events, 1)
+
+	event := store.events[0]
+	assert.Equal(t, model.AuditSuccess, event.Outcome)
+	assert.WithinDuration(t, time.Now(), event.Time, 2*time.Second)
+	assert.Equal(t, time.UTC, event.Time.Location())
+	assert.Zero(t, event.Time.Nanosecond(), "time is stored with second precision")
+
+	// Журнал может быть отключен
+	var disabled *service.AuditService
+	disabled.Record(&model.AuditEvent{Action: model.AuditLogin})
+}
+
+func TestAuditService_ListValidation(t *testing.T) {
+	auditService := service.NewAuditService(&memoryStore{})
+	ctx := context.Background()
+
+	_, _, err := auditService.List(ctx, &model.AuditFilter{}, 0, 0)
+	assert.ErrorIs(t, err, service.ErrInvalidLimit)
+	_, _, err = auditService.List(ctx, &model.AuditFilter{}, 5000, 0)
+	assert.ErrorIs(t, err, service.ErrInvalidLimit)
+	_, _, err = auditService.List(ctx, &model.AuditFilter{}, 10, -1)
+	assert.ErrorIs(t, err, service.ErrInvalidOffset)
+	_, _, err = auditService.List(ctx, &model.AuditFilter{Outcome: "maybe"}, 10, 0)
+	assert.ErrorIs(t, err, service.ErrInvalidAuditFilter)
+
+	now := time.Now()
+	_, _, err = auditService.List(ctx, &model.AuditFilter{From: &now, To: &now}, 10, 0)
+	assert.ErrorIs(t, err, service.ErrInvalidAuditFilter)
+}
+
+func TestAuditController_List(t *testing.T) {
+	store := &memoryStore{}
+	auditService := service.NewAuditService(store)
+	auditService.Record(&model.AuditEvent{Action: model.AuditLogin, ActorID: "user-1", ActorLogin: "testuser01"})
+	auditService.Record(&model.AuditEvent{Action: model.AuditDocumentRead, ActorID: "user-1", DocumentID: "doc-1"})
+	auditService.Record(&model.AuditEvent{Action: model.AuditLoginFailed, ActorLogin: "intruder01", Outcome: model.AuditFailure})
+
+	resp, err := newApp(auditService).Test(httptest.NewRequest("GET", "/audit?actor=user-1&limit=10", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Data model.AuditListResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 2, result.Data.Total)
+	require.Len(t, result.Data.Events, 2)
+	assert.Equal(t, model.AuditDocumentRead, result.Data.Events[0].Action, "newest events first")
+	assert.Equal(t, "doc-1", result.Data.Events[0].DocumentID)
+
+	for _, query := range []string{"?from=yesterday", "?limit=0", "?outcome=unknown"} {
+		resp, err := newApp(auditService).Test(httptest.NewRequest("GET", "/audit"+query, nil))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func TestAuditController_Export(t *testing.T) {
+	store := &memoryStore{}
+	auditService := service.NewAuditService(store)
+	auditService.Record(&model.AuditEvent{Action: model.AuditLogin, ActorID: "user-1"})
+	auditService.Record(&model.AuditEvent{Action: model.AuditLogout, ActorID: "user-1"})
+
+	resp, err := newApp(auditService).Test(httptest.NewRequest("GET", "/audit/export", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), ".jsonl")
+
+	var actions []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var event model.AuditEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		actions = append(actions, event.Action)
+	}
+	assert.Equal(t, []string{model.AuditLogin, model.AuditLogout}, actions, "export keeps insertion order")
+}
@@ -0,0 +1,179 @@
+package audit_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"docs-server/internal/jose"
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKey сохраняет новый ключ Ed25519 в PEM-файлы закрытого и открытого ключа
+func writeKey(t *testing.T) (string, string) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	require.NoError(t, err)
+
+	privateFile := filepath.Join(dir, "checkpoint.pem")
+	publicFile := filepath.Join(dir, "checkpoint.pub.pem")
+	require.NoError(t, os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600))
+	require.NoError(t, os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644))
+	return privateFile, publicFile
+}
+
+// chainedStore журнал из трех записей за два дня
+func chainedStore(t *testing.T) *memoryStore {
+	store := &memoryStore{}
+	day := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i, event := range []*model.AuditEvent{
+		{Time: day, Action: model.AuditLogin, Outcome: model.AuditSuccess, ActorID: "user-1",
+			Details: map[string]interface{}{"method": "password", "size": int64(1024)}},
+		{Time: day.Add(time.Hour), Action: model.AuditDocumentRead, Outcome: model.AuditSuccess, DocumentID: "doc-1"},
+		{Time: day.Add(25 * time.Hour), Action: model.AuditLogout, Outcome: model.AuditSuccess, ActorID: "user-1"},
+	} {
+		require.NoError(t, store.InsertAuditEvent(context.Background(), event), i)
+	}
+	return store
+}
+
+func TestAuditEvent_ChainHash(t *testing.T) {
+	event := &model.AuditEvent{
+		Time:    time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		Action:  model.AuditLogin,
+		Outcome: model.AuditSuccess,
+		Details: map[string]interface{}{"size": int64(1024), "grants": []string{"a", "b"}},
+	}
+	hash, err := event.ChainHash("")
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	// Details после чтения из БД имеют другие типы, но тот же хеш
+	decoded := *event
+	decoded.Details = map[string]interface{}{"size": float64(1024), "grants": []interface{}{"a", "b"}}
+	decodedHash, err := decoded.ChainHash("")
+	require.NoError(t, err)
+	assert.Equal(t, hash, decodedHash)
+
+	chained, err := event.ChainHash(hash)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, chained, "hash depends on previous record")
+}
+
+func TestAuditService_VerifyChain(t *testing.T) {
+	ctx := context.Background()
+
+	report, err := service.NewAuditService(chainedStore(t)).VerifyChain(ctx, nil)
+	require.NoError(t, err)
+	assert.Nil(t, report.Broken)
+	assert.EqualValues(t, 3, report.Events)
+	assert.False(t, report.SignaturesChecked)
+
+	modified := chainedStore(t)
+	modified.events[1].DocumentID = "doc-2"
+	report, err = service.NewAuditService(modified).VerifyChain(ctx, nil)
+	require.NoError(t, err)
+	require.NotNil(t, report.Broken)
+	assert.EqualValues(t, 2, report.Broken.EventID)
+	assert.Contains(t, report.Broken.Reason, "modified")
+
+	removed := chainedStore(t)
+	removed.events = append(removed.events[:1], removed.events[2:]...)
+	report, err = service.NewAuditService(removed).VerifyChain(ctx, nil)
+	require.NoError(t, err)
+	require.NotNil(t, report.Broken)
+	assert.EqualValues(t, 3, report.Broken.EventID, "first record after the gap")
+
+	truncated := chainedStore(t)
+	truncated.events = truncated.events[:2]
+	report, err = service.NewAuditService(truncated).VerifyChain(ctx, nil)
+	require.NoError(t, err)
+	require.NotNil(t, report.Broken)
+	assert.Contains(t, report.Broken.Reason, "removed from the end")
+
+	// Записи до включения цепочки не нарушают ее
+	legacy := chainedStore(t)
+	legacy.events = append([]*model.AuditEvent{{ID: 0, Time: legacy.events[0].Time, Action: model.AuditLogin}}, legacy.events...)
+	report, err = service.NewAuditService(legacy).VerifyChain(ctx, nil)
+	require.NoError(t, err)
+	assert.Nil(t, report.Broken)
+	assert.EqualValues(t, 1, report.Unchained)
+}
+
+func TestAuditService_Checkpoints(t *testing.T) {
+	ctx := context.Background()
+	privateFile, publicFile := writeKey(t)
+	keys, err := jose.LoadKeySet(privateFile, nil, nil)
+	require.NoError(t, err)
+
+	store := chainedStore(t)
+	auditService := service.NewAuditService(store)
+	now := time.Date(2026, 3, 3, 0, 30, 0, 0, time.UTC)
+
+	created, err := auditService.CreateCheckpoints(ctx, keys, now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, created, "one checkpoint per completed day")
+	require.Len(t, store.checkpoints, 2)
+	assert.EqualValues(t, 2, store.checkpoints[0].LastID)
+	assert.Equal(t, 2, store.checkpoints[0].Events)
+	assert.Equal(t, store.events[1].Hash, store.checkpoints[0].LastHash)
+
+	created, err = auditService.CreateCheckpoints(ctx, keys, now)
+	require.NoError(t, err)
+	assert.Zero(t, created, "checkpoints are created once")
+
+	// Проверка только открытым ключом
+	publicKeys, err := jose.LoadVerificationKeySet([]string{publicFile})
+	require.NoError(t, err)
+	report, err := auditService.VerifyChain(ctx, publicKeys)
+	require.NoError(t, err)
+	assert.Nil(t, report.Broken)
+	assert.True(t, report.SignaturesChecked)
+	assert.Equal(t, 2, report.Checkpoints)
+
+	// Цепочка, пересчитанная после изменения записи, не совпадает с подписанной точкой
+	rewritten := &memoryStore{checkpoints: store.checkpoints}
+	for _, event := range store.events {
+		copied := *event
+		if copied.ID == 1 {
+			copied.ActorID = "user-2"
+		}
+		require.NoError(t, rewritten.InsertAuditEvent(ctx, &copied))
+	}
+	report, err = service.NewAuditService(rewritten).VerifyChain(ctx, publicKeys)
+	require.NoError(t, err)
+	require.NotNil(t, report.Broken)
+	assert.Equal(t, "2026-03-01", report.Broken.Checkpoint)
+
+	// Подделанная точка не проходит проверку подписи
+	store.checkpoints[1].LastHash = store.events[0].Hash
+	report, err = auditService.VerifyChain(ctx, publicKeys)
+	require.NoError(t, err)
+	require.NotNil(t, report.Broken)
+	assert.Equal(t, "2026-03-02", report.Broken.Checkpoint)
+	assert.Contains(t, report.Broken.Reason, "signed fields")
+
+	// Подпись другим ключом
+	otherFile, _ := writeKey(t)
+	otherKeys, err := jose.LoadKeySet(otherFile, nil, nil)
+	require.NoError(t, err)
+	checkpoint := *store.checkpoints[0]
+	checkpoint.Signature, err = service.SignAuditCheckpoint(otherKeys, &checkpoint)
+	require.NoError(t, err)
+	assert.Error(t, service.VerifyAuditCheckpoint(publicKeys, &checkpoint))
+}
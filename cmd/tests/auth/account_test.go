@@ -0,0 +1,98 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sendJSON(t *testing.T, method, path, token string, body interface{}) int {
+	jsonBody, _ := testutils.CreateJSONRequest(body)
+	req := httptest.NewRequest(method, path, jsonBody)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAccount_Profile(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+testutils.TestToken)
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Data adminUser `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, testutils.TestLogin, result.Data.Login)
+	assert.NotEmpty(t, result.Data.ID)
+}
+
+func TestAccount_ChangePasswordAndDelete(t *testing.T) {
+	login := fmt.Sprintf("selfserv%d", time.Now().UnixNano())
+	status, _ := adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	})
+	require.Equal(t, http.StatusOK, status)
+
+	current := loginAs(t, login)
+	other := loginAs(t, login)
+
+	// Неверный текущий пароль
+	assert.Equal(t, http.StatusForbidden, sendJSON(t, "POST", "/api/me/password", current, map[string]string{
+		"old_pswd": "Wr0ng!Pass",
+		"new_pswd": "N3wSecur3!",
+	}))
+	// Новый пароль не проходит проверку сложности
+	assert.Equal(t, http.StatusBadRequest, sendJSON(t, "POST", "/api/me/password", current, map[string]string{
+		"old_pswd": testutils.TestPass,
+		"new_pswd": "short",
+	}))
+
+	require.Equal(t, http.StatusOK, sendJSON(t, "POST", "/api/me/password", current, map[string]string{
+		"old_pswd": testutils.TestPass,
+		"new_pswd": "N3wSecur3!",
+	}))
+
+	// Текущая сессия сохраняется, остальные завершаются
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/me", current))
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/me", other))
+
+	// Вход со старым паролем невозможен
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", "/api/auth", "", map[string]string{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	}))
+
+	// Удаление учетной записи требует пароль
+	assert.Equal(t, http.StatusForbidden, sendJSON(t, "DELETE", "/api/me", current, map[string]string{
+		"pswd": testutils.TestPass,
+	}))
+	require.Equal(t, http.StatusOK, sendJSON(t, "DELETE", "/api/me", current, map[string]string{
+		"pswd":        "N3wSecur3!",
+		"transfer_to": testutils.TestLogin,
+	}))
+
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/me", current))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", "/api/auth", "", map[string]string{
+		"login": login,
+		"pswd":  "N3wSecur3!",
+	}))
+}
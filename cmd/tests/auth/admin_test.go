@@ -0,0 +1,170 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type adminUser struct {
+	ID         string     `json:"id"`
+	Login      string     `json:"login"`
+	IsAdmin    bool       `json:"is_admin"`
+	DisabledAt *time.Time `json:"disabled_at"`
+}
+
+func adminRequest(t *testing.T, method, path string, body interface{}) (int, adminUser) {
+	var req *http.Request
+	if body != nil {
+		jsonBody, _ := testutils.CreateJSONRequest(body)
+		req = httptest.NewRequest(method, path, jsonBody)
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	req.Header.Set("Authorization", "Bearer "+testutils.TestToken)
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var result struct {
+		Data adminUser `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result.Data
+}
+
+func loginAs(t *testing.T, login string) string {
+	body, _ := testutils.CreateJSONRequest(map[string]string{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	})
+	req := httptest.NewRequest("POST", "/api/auth", body)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Response tokenPair `json:"response"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result.Response.Token
+}
+
+func TestAdmin_RequiresAdminRole(t *testing.T) {
+	assert.Equal(t, http.StatusForbidden, getStatus(t, "GET", "/api/admin/users", testutils.TestToken2))
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/admin/users", testutils.TestToken))
+}
+
+func TestAdmin_ListPagination(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/admin/users?limit=1&offset=1", nil)
+	req.Header.Set("Authorization", testutils.TestToken)
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Data struct {
+			Users []adminUser `json:"users"`
+			Total int         `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Len(t, result.Data.Users, 1)
+	assert.GreaterOrEqual(t, result.Data.Total, 3)
+}
+
+func TestAdmin_UserLifecycle(t *testing.T) {
+	login := fmt.Sprintf("managed%d", time.Now().UnixNano())
+
+	status, user := adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, login, user.Login)
+	assert.False(t, user.IsAdmin)
+
+	// Повторное создание с тем же логином
+	status, _ = adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	})
+	assert.Equal(t, http.StatusConflict, status)
+
+	// Пользователь загружает документ
+	token := loginAs(t, login)
+	docName := login + ".txt"
+	body, contentType := testutils.CreateMultipartRequest(`{"name": "`+docName+`", "mime": "text/plain"}`, docName, "managed content")
+	req := httptest.NewRequest("POST", "/api/docs", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", token)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Блокировка завершает сессии пользователя
+	status, user = adminRequest(t, "PATCH", "/api/admin/users/"+user.ID, map[string]interface{}{"disabled": true})
+	require.Equal(t, http.StatusOK, status)
+	assert.NotNil(t, user.DisabledAt)
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/docs", token))
+
+	// Удаление с передачей документов администратору
+	status, _ = adminRequest(t, "DELETE", "/api/admin/users/"+user.ID+"?transfer_to="+testutils.TestLogin, nil)
+	require.Equal(t, http.StatusOK, status)
+
+	status, _ = adminRequest(t, "GET", "/api/admin/users/"+user.ID, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	docID, err := testutils.FindDocumentID(testutils.TestApp, testutils.TestToken, "/api/docs?limit=1000", docName)
+	require.NoError(t, err)
+	assert.NotEmpty(t, docID, "transferred document not found")
+}
+
+func TestAdmin_CannotRemoveLastAdmin(t *testing.T) {
+	// Администратор не может снять роль с последнего активного администратора
+	req := httptest.NewRequest("GET", "/api/admin/users?limit=1000", nil)
+	req.Header.Set("Authorization", testutils.TestToken)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var result struct {
+		Data struct {
+			Users []adminUser `json:"users"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+
+	var adminID string
+	admins := 0
+	for _, u := range result.Data.Users {
+		if u.IsAdmin && u.DisabledAt == nil {
+			admins++
+		}
+		if u.Login == testutils.TestLogin {
+			adminID = u.ID
+		}
+	}
+	require.NotEmpty(t, adminID)
+	if admins > 1 {
+		t.Skip("more than one active admin")
+	}
+
+	status, _ := adminRequest(t, "PATCH", "/api/admin/users/"+adminID, map[string]interface{}{"is_admin": false})
+	assert.Equal(t, http.StatusConflict, status)
+}
@@ -0,0 +1,105 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createAPIKey(t *testing.T, token string, readOnly bool) (string, string) {
+	body, _ := testutils.CreateJSONRequest(map[string]interface{}{
+		"name":      "ci",
+		"read_only": readOnly,
+	})
+	req := httptest.NewRequest("POST", "/api/keys", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Response struct {
+			Key    string `json:"key"`
+			APIKey struct {
+				ID       string `json:"id"`
+				ReadOnly bool   `json:"read_only"`
+			} `json:"api_key"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, readOnly, result.Response.APIKey.ReadOnly)
+	return result.Response.APIKey.ID, result.Response.Key
+}
+
+func TestAPIKey_Lifecycle(t *testing.T) {
+	token := testutils.TestToken
+	keyID, key := createAPIKey(t, token, false)
+	assert.NotEmpty(t, key)
+
+	// Ключ принимается вместо JWT
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/docs", key))
+
+	// Ключом нельзя управлять ключами
+	assert.Equal(t, http.StatusForbidden, getStatus(t, "GET", "/api/keys", key))
+
+	// Ключ виден в списке, но без секрета
+	req := httptest.NewRequest("GET", "/api/keys", nil)
+	req.Header.Set("Authorization", token)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var list struct {
+		Data struct {
+			Keys []map[string]interface{} `json:"keys"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	found := false
+	for _, k := range list.Data.Keys {
+		if k["id"] == keyID {
+			found = true
+			assert.NotContains(t, k, "key")
+		}
+	}
+	assert.True(t, found, "API key not found in list")
+
+	// Отозванный ключ не действует
+	assert.Equal(t, http.StatusOK, getStatus(t, "DELETE", "/api/keys/"+keyID, token))
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/docs", key))
+}
+
+func TestAPIKey_ReadOnly(t *testing.T) {
+	_, key := createAPIKey(t, testutils.TestToken, true)
+
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/docs", key))
+	assert.Equal(t, http.StatusForbidden, getStatus(t, "DELETE", "/api/docs/00000000-0000-0000-0000-000000000000", key))
+}
+
+func TestAPIKey_RoleChangeAppliesImmediately(t *testing.T) {
+	login := fmt.Sprintf("keyadmin%d", time.Now().UnixNano())
+	status, user := adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login":    login,
+		"pswd":     testutils.TestPass,
+		"is_admin": true,
+	})
+	require.Equal(t, http.StatusOK, status)
+	defer adminRequest(t, "DELETE", "/api/admin/users/"+user.ID, nil)
+
+	_, key := createAPIKey(t, "Bearer "+loginAs(t, login), false)
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/admin/users", key))
+
+	// Снятая роль действует для ключа сразу, а не после истечения кеша
+	status, _ = adminRequest(t, "PATCH", "/api/admin/users/"+user.ID, map[string]interface{}{"is_admin": false})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusForbidden, getStatus(t, "GET", "/api/admin/users", key))
+}
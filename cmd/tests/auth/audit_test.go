@@ -0,0 +1,94 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"docs-server/internal/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func auditEvents(t *testing.T, query url.Values) []*model.AuditEvent {
+	req := httptest.NewRequest("GET", "/api/admin/audit?"+query.Encode(), nil)
+	req.Header.Set("Authorization", "Bearer "+testutils.TestToken)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Data model.AuditListResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result.Data.Events
+}
+
+func TestAudit_LoginEvents(t *testing.T) {
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", "/api/auth", "", map[string]string{
+		"login": testutils.TestLogin,
+		"pswd":  "Wr0ng!Pass",
+	}))
+	loginAs(t, testutils.TestLogin)
+
+	failed := auditEvents(t, url.Values{"action": {model.AuditLoginFailed}, "actor": {testutils.TestLogin}, "limit": {"1"}})
+	require.Len(t, failed, 1)
+	assert.Equal(t, model.AuditFailure, failed[0].Outcome)
+	assert.NotEmpty(t, failed[0].IP)
+	assert.Equal(t, "password", failed[0].Details["method"])
+
+	succeeded := auditEvents(t, url.Values{"action": {model.AuditLogin}, "actor": {testutils.TestLogin}, "limit": {"1"}})
+	require.Len(t, succeeded, 1)
+	assert.Equal(t, model.AuditSuccess, succeeded[0].Outcome)
+	assert.NotEmpty(t, succeeded[0].ActorID)
+	assert.NotEmpty(t, succeeded[0].Details["session_id"])
+}
+
+func TestAudit_DocumentEvents(t *testing.T) {
+	body, contentType := testutils.CreateMultipartRequest(`{"name": "audited.txt", "mime": "text/plain"}`, "audited.txt", "audited content")
+	req := httptest.NewRequest("POST", "/api/docs", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", testutils.TestToken)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	docID, err := testutils.FindDocumentID(testutils.TestApp, testutils.TestToken, "/api/docs?limit=1000", "audited.txt")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/docs/"+docID, testutils.TestToken))
+	require.Equal(t, http.StatusOK, getStatus(t, "DELETE", "/api/docs/"+docID, testutils.TestToken))
+
+	events := auditEvents(t, url.Values{"document": {docID}})
+	var actions []string
+	for _, event := range events {
+		actions = append(actions, event.Action)
+		assert.NotEmpty(t, event.ActorID)
+	}
+	assert.Equal(t, []string{model.AuditDocumentDelete, model.AuditDocumentDownload, model.AuditDocumentUpload}, actions)
+}
+
+func TestAudit_Export(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/admin/audit/export?action="+model.AuditLogin, nil)
+	req.Header.Set("Authorization", "Bearer "+testutils.TestToken)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+
+	decoder := json.NewDecoder(resp.Body)
+	for decoder.More() {
+		var event model.AuditEvent
+		require.NoError(t, decoder.Decode(&event))
+		assert.Equal(t, model.AuditLogin, event.Action)
+	}
+}
+
+func TestAudit_AdminOnly(t *testing.T) {
+	assert.Equal(t, http.StatusForbidden, getStatus(t, "GET", "/api/admin/audit", testutils.TestToken2))
+}
@@ -0,0 +1,45 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBearer_Schemes(t *testing.T) {
+	token := testutils.TestToken
+
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/docs", "Bearer "+token))
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/docs", "bearer "+token))
+	// Устаревший формат без схемы (allow_raw_token)
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/docs", token))
+
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/docs", "Basic "+token))
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/docs", "Bearer "))
+}
+
+func TestBearer_Challenge(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/docs", nil)
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "Bearer")
+}
+
+func TestLogout_AuthenticatedHeader(t *testing.T) {
+	tokens := login(t, "logout")
+	bearer := "Bearer " + tokens.Token
+
+	assert.Equal(t, http.StatusOK, getStatus(t, "DELETE", "/api/auth", bearer))
+
+	// Токен завершенной сессии больше не действует
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/docs", bearer))
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "DELETE", "/api/auth", bearer))
+}
@@ -0,0 +1,42 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"docs-server/internal/jose"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKS_VerifiesAccessToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "/.well-known/jwks.json", nil)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Cache-Control"), "max-age")
+
+	var set jose.JWKSet
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&set))
+	require.NotEmpty(t, set.Keys)
+	for _, key := range set.Keys {
+		assert.NotEmpty(t, key.Kid)
+		assert.Equal(t, "sig", key.Use)
+	}
+
+	keys, err := set.PublicKeys()
+	require.NoError(t, err)
+
+	// Сторонний сервис проверяет access-токен только по опубликованным ключам
+	token, err := jwt.Parse(testutils.TestToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return keys[kid], nil
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience("docs-server"), jwt.WithIssuer("docs-server"))
+	require.NoError(t, err)
+	assert.True(t, token.Valid)
+}
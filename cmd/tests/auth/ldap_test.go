@@ -0,0 +1,88 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func profile(t *testing.T, token string) adminUser {
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Data adminUser `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result.Data
+}
+
+func TestLDAP_ProvisionWithGroups(t *testing.T) {
+	user := profile(t, loginAs(t, "ldapuser01"))
+	assert.Equal(t, "ldapuser01", user.Login)
+	assert.False(t, user.IsAdmin)
+
+	admin := profile(t, loginAs(t, "ldapadmin01"))
+	assert.True(t, admin.IsAdmin)
+	// Роль снимается, чтобы не влиять на проверки единственного администратора; при входе она синхронизируется снова
+	t.Cleanup(func() {
+		adminRequest(t, "PATCH", "/api/admin/users/"+admin.ID, map[string]interface{}{"is_admin": false})
+	})
+
+	// Повторный вход сопоставляется с созданным пользователем
+	assert.Equal(t, user.ID, profile(t, loginAs(t, "ldapuser01")).ID)
+
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", "/api/auth", "", map[string]string{
+		"login": "ldapuser01",
+		"pswd":  "Wr0ngPass!",
+	}))
+}
+
+func TestLDAP_LocalUsersStillAuthenticate(t *testing.T) {
+	assert.NotEmpty(t, loginAs(t, testutils.TestLogin))
+}
+
+func TestLDAP_DoesNotSignInAsLocalUser(t *testing.T) {
+	// Локальный пользователь с тем же логином, что и в каталоге (мог остаться от прошлого запуска)
+	status, _ := adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login": "ldapshadow01",
+		"pswd":  testutils.TestPass,
+	})
+	require.Contains(t, []int{http.StatusOK, http.StatusConflict}, status)
+
+	// Пароль из каталога не открывает локальную учетную запись
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", "/api/auth", "", map[string]string{
+		"login": "ldapshadow01",
+		"pswd":  testutils.LDAPShadowPass,
+	}))
+
+	// И группы каталога не синхронизируются с ней
+	user := profile(t, loginAs(t, "ldapshadow01"))
+	assert.False(t, user.IsAdmin)
+}
+
+func TestLDAP_DemotionRevokesAccess(t *testing.T) {
+	user := profile(t, loginAs(t, "ldapuser01"))
+	_, key := createAPIKey(t, "Bearer "+loginAs(t, "ldapuser01"), false)
+
+	// Роль, выданная вручную, действует для ключа, пока вход не синхронизирует ее с каталогом
+	status, _ := adminRequest(t, "PATCH", "/api/admin/users/"+user.ID, map[string]interface{}{"is_admin": true})
+	require.Equal(t, http.StatusOK, status)
+	t.Cleanup(func() {
+		adminRequest(t, "PATCH", "/api/admin/users/"+user.ID, map[string]interface{}{"is_admin": false})
+	})
+	require.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/admin/users", key))
+
+	// После входа роль снята по группам каталога, и ключ сразу ее теряет
+	assert.False(t, profile(t, loginAs(t, "ldapuser01")).IsAdmin)
+	assert.Equal(t, http.StatusForbidden, getStatus(t, "GET", "/api/admin/users", key))
+}
@@ -0,0 +1,60 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func authAttempt(t *testing.T, login, password string) *http.Response {
+	jsonBody, _ := testutils.CreateJSONRequest(map[string]string{
+		"login": login,
+		"pswd":  password,
+	})
+	req := httptest.NewRequest("POST", "/api/auth", jsonBody)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestLogin_BackoffAndUnlock(t *testing.T) {
+	login := fmt.Sprintf("lockout%d", time.Now().UnixNano())
+	status, user := adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	})
+	require.Equal(t, http.StatusOK, status)
+
+	// После бесплатных попыток вход откладывается с растущей задержкой
+	var resp *http.Response
+	for i := 0; i < 10; i++ {
+		resp = authAttempt(t, login, "Wr0ngPass!")
+		if resp.StatusCode != http.StatusUnauthorized {
+			break
+		}
+	}
+	require.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Greater(t, retryAfter, 0)
+
+	// Пока действует задержка, не принимается и верный пароль
+	assert.Equal(t, http.StatusTooManyRequests, authAttempt(t, login, testutils.TestPass).StatusCode)
+
+	status, _ = adminRequest(t, "POST", "/api/admin/users/"+user.ID+"/unlock", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusOK, authAttempt(t, login, testutils.TestPass).StatusCode)
+
+	status, _ = adminRequest(t, "POST", "/api/admin/users/00000000-0000-0000-0000-000000000000/unlock", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
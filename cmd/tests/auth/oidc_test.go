@@ -0,0 +1,210 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// oidcFlow ответ провайдера и cookie с привязкой state, выданная браузеру при начале входа
+type oidcFlow struct {
+	code   string
+	state  string
+	cookie *http.Cookie
+}
+
+// oidcAuthorize начинает вход через OIDC и проходит авторизацию у тестового провайдера
+func oidcAuthorize(t *testing.T, login string) oidcFlow {
+	req := httptest.NewRequest("GET", "/api/auth/oidc/login", nil)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	cookie := oidcStateCookie(t, resp)
+
+	location := resp.Header.Get("Location")
+	authURL, err := url.Parse(location)
+	require.NoError(t, err)
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	assert.NotEmpty(t, authURL.Query().Get("nonce"))
+
+	code, state, err := testutils.TestOIDC.Authorize(location, login)
+	require.NoError(t, err)
+	return oidcFlow{code: code, state: state, cookie: cookie}
+}
+
+func oidcStateCookie(t *testing.T, resp *http.Response) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "oidc_state" {
+			assert.True(t, cookie.HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+			return cookie
+		}
+	}
+	require.Fail(t, "oidc_state cookie is not set")
+	return nil
+}
+
+func oidcCallback(t *testing.T, flow oidcFlow) (int, tokenPair) {
+	query := url.Values{"code": {flow.code}, "state": {flow.state}}
+	req := httptest.NewRequest("GET", "/api/auth/oidc/callback?"+query.Encode(), nil)
+	if flow.cookie != nil {
+		req.AddCookie(&http.Cookie{Name: flow.cookie.Name, Value: flow.cookie.Value})
+	}
+	resp, err := testutils.TestApp.Test(req, 15000)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var result struct {
+		Response tokenPair `json:"response"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result.Response
+}
+
+func TestOIDC_ProvisionAndLogin(t *testing.T) {
+	login := fmt.Sprintf("oidcuser%d", time.Now().UnixNano())
+
+	flow := oidcAuthorize(t, login)
+	status, tokens := oidcCallback(t, flow)
+	require.Equal(t, http.StatusOK, status)
+	require.NotEmpty(t, tokens.Token)
+
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.Token)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var profile struct {
+		Data struct {
+			Login string `json:"login"`
+			Email string `json:"email"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&profile))
+	assert.Equal(t, login, profile.Data.Login)
+	assert.Equal(t, login+"@example.com", profile.Data.Email)
+
+	// state одноразовый
+	status, _ = oidcCallback(t, flow)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Повторный вход сопоставляется с уже созданным пользователем
+	status, _ = oidcCallback(t, oidcAuthorize(t, login))
+	assert.Equal(t, http.StatusOK, status)
+
+	// Локальный пароль у такого пользователя не задан
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", "/api/auth", "", map[string]string{
+		"login": login,
+		"pswd":  "",
+	}))
+}
+
+func TestOIDC_RejectsInvalidIDToken(t *testing.T) {
+	login := fmt.Sprintf("oidcbad%d", time.Now().UnixNano())
+
+	cases := map[string]func(jwt.MapClaims){
+		"audience": func(c jwt.MapClaims) { c["aud"] = "another-client" },
+		"nonce":    func(c jwt.MapClaims) { c["nonce"] = "replayed" },
+		"expired":  func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+	}
+	defer testutils.TestOIDC.SetClaimHook(nil)
+
+	for name, hook := range cases {
+		testutils.TestOIDC.SetClaimHook(hook)
+		status, _ := oidcCallback(t, oidcAuthorize(t, login))
+		assert.Equal(t, http.StatusUnauthorized, status, name)
+	}
+
+	status, _ := oidcCallback(t, oidcFlow{code: "code", state: "unknown-state"})
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestOIDC_RejectsCallbackFromAnotherBrowser(t *testing.T) {
+	login := fmt.Sprintf("oidccsrf%d", time.Now().UnixNano())
+
+	// Ответ провайдера, подсунутый браузеру без cookie, не открывает сессию
+	flow := oidcAuthorize(t, login)
+	status, _ := oidcCallback(t, oidcFlow{code: flow.code, state: flow.state})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Cookie другого входа тоже не подходит
+	other := oidcAuthorize(t, login)
+	status, _ = oidcCallback(t, oidcFlow{code: other.code, state: other.state, cookie: flow.cookie})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Отклоненный ответ не расходует state браузера, начавшего вход
+	status, _ = oidcCallback(t, other)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestOIDC_DoesNotTakeOverExistingAccount(t *testing.T) {
+	login := fmt.Sprintf("oidclocal%d", time.Now().UnixNano())
+	status, _ := adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	})
+	require.Equal(t, http.StatusOK, status)
+
+	// Совпадение логина в утверждении провайдера не дает войти под локальной учетной записью
+	status, _ = oidcCallback(t, oidcAuthorize(t, login))
+	assert.Equal(t, http.StatusConflict, status)
+
+	// Привязка из сессии пользователя
+	req := httptest.NewRequest("POST", "/api/me/oidc", nil)
+	req.Header.Set("Authorization", "Bearer "+loginAs(t, login))
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var link struct {
+		Data struct {
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&link))
+
+	code, state, err := testutils.TestOIDC.Authorize(link.Data.URL, login)
+	require.NoError(t, err)
+	status, tokens := oidcCallback(t, oidcFlow{code: code, state: state, cookie: oidcStateCookie(t, resp)})
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, tokens.Token)
+
+	// После привязки пользователь определяется по iss и sub, а не по логину
+	defer testutils.TestOIDC.SetClaimHook(nil)
+	testutils.TestOIDC.SetClaimHook(func(c jwt.MapClaims) { c["preferred_username"] = "admin" })
+	status, tokens = oidcCallback(t, oidcAuthorize(t, login))
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, login, profileLogin(t, tokens.Token))
+
+	// Другая учетная запись провайдера с тем же логином отклоняется
+	testutils.TestOIDC.SetClaimHook(func(c jwt.MapClaims) { c["preferred_username"] = login })
+	status, _ = oidcCallback(t, oidcAuthorize(t, "other"+login))
+	assert.Equal(t, http.StatusConflict, status)
+}
+
+func profileLogin(t *testing.T, token string) string {
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var profile struct {
+		Data struct {
+			Login string `json:"login"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&profile))
+	return profile.Data.Login
+}
@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetTokenFromMessage извлекает токен из письма о сбросе пароля
+func resetTokenFromMessage(t *testing.T, body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if i := strings.Index(line, "token="); i >= 0 {
+			return strings.TrimSpace(line[i+len("token="):])
+		}
+		if i := strings.LastIndex(line, ": "); i >= 0 && strings.HasPrefix(line, "Код") {
+			return strings.TrimSpace(line[i+2:])
+		}
+	}
+	t.Fatalf("reset token not found in message: %q", body)
+	return ""
+}
+
+func TestPasswordReset_ByEmail(t *testing.T) {
+	login := fmt.Sprintf("resetmail%d", time.Now().UnixNano())
+	email := login + "@example.com"
+	status, _ := adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login": login,
+		"pswd":  testutils.TestPass,
+		"email": email,
+	})
+	require.Equal(t, http.StatusOK, status)
+	session := loginAs(t, login)
+
+	// Ответ одинаков для существующего и несуществующего логина
+	assert.Equal(t, http.StatusOK, sendJSON(t, "POST", "/api/auth/password-reset", "", map[string]string{"login": login}))
+	assert.Equal(t, http.StatusOK, sendJSON(t, "POST", "/api/auth/password-reset", "", map[string]string{"login": "nosuchuser404"}))
+
+	// Письмо отправляется в фоне
+	require.Eventually(t, func() bool { return testutils.TestNotifier.Last(email) != nil },
+		5*time.Second, 10*time.Millisecond, "reset message was not sent")
+	msg := testutils.TestNotifier.Last(email)
+	token := resetTokenFromMessage(t, msg.Body)
+
+	require.Equal(t, http.StatusOK, sendJSON(t, "POST", "/api/auth/password-reset/confirm", "", map[string]string{
+		"token":    token,
+		"new_pswd": "R3setPass!",
+	}))
+
+	// Токен одноразовый
+	assert.Equal(t, http.StatusBadRequest, sendJSON(t, "POST", "/api/auth/password-reset/confirm", "", map[string]string{
+		"token":    token,
+		"new_pswd": "An0therPass!",
+	}))
+
+	// Сессии завершены, вход возможен только с новым паролем
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/me", session))
+	assert.Equal(t, http.StatusUnauthorized, sendJSON(t, "POST", "/api/auth", "", map[string]string{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	}))
+	assert.Equal(t, http.StatusOK, sendJSON(t, "POST", "/api/auth", "", map[string]string{
+		"login": login,
+		"pswd":  "R3setPass!",
+	}))
+}
+
+func TestPasswordReset_AdminInitiated(t *testing.T) {
+	login := fmt.Sprintf("resetadm%d", time.Now().UnixNano())
+	status, user := adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	})
+	require.Equal(t, http.StatusOK, status)
+
+	// Только администратор может выдать токен
+	assert.Equal(t, http.StatusForbidden, getStatus(t, "POST", "/api/admin/users/"+user.ID+"/password-reset", testutils.TestToken2))
+
+	req := httptest.NewRequest("POST", "/api/admin/users/"+user.ID+"/password-reset", nil)
+	req.Header.Set("Authorization", testutils.TestToken)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// У пользователя нет почты, токен возвращается администратору
+	var result struct {
+		Response struct {
+			Sent  bool   `json:"sent"`
+			Token string `json:"token"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.False(t, result.Response.Sent)
+	require.NotEmpty(t, result.Response.Token)
+
+	require.Equal(t, http.StatusOK, sendJSON(t, "POST", "/api/auth/password-reset/confirm", "", map[string]string{
+		"token":    result.Response.Token,
+		"new_pswd": "Adm1nReset!",
+	}))
+	assert.Equal(t, http.StatusOK, sendJSON(t, "POST", "/api/auth", "", map[string]string{
+		"login": login,
+		"pswd":  "Adm1nReset!",
+	}))
+}
@@ -2,7 +2,6 @@ package auth_test
 
 import (
 	"docs-server/cmd/tests/testutils"
-	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,10 +9,12 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestRegisterUser_Success(t *testing.T) {
+func TestRegisterUser_Bootstrap(t *testing.T) {
 	app := testutils.TestApp
 
-	t.Run("Successful registration", func(t *testing.T) {
+	t.Run("Admin already exists", func(t *testing.T) {
+		// Первый администратор создан при инициализации тестов,
+		// после этого токен начальной настройки не принимается
 		body := map[string]string{
 			"token": "secure-admin-token-123",
 			"login": "testuser123",
@@ -28,16 +29,7 @@ func TestRegisterUser_Success(t *testing.T) {
 		assert.NoError(t, err)
 		defer resp.Body.Close()
 
-		assert.Equal(t, http.StatusOK, resp.StatusCode)
-
-		var result map[string]interface{}
-		err = json.NewDecoder(resp.Body).Decode(&result)
-		assert.NoError(t, err)
-
-		response, ok := result["response"].(map[string]interface{})
-		assert.True(t, ok)
-		_, ok = response["login"].(string)
-		assert.True(t, ok)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
 	})
 
 	t.Run("Invalid admin token", func(t *testing.T) {
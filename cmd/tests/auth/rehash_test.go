@@ -0,0 +1,52 @@
+package auth_test
+
+import (
+	"context"
+	"docs-server/cmd/tests/testutils"
+	"docs-server/internal/password"
+	"docs-server/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthenticate_RehashesOutdatedPassword(t *testing.T) {
+	legacy := password.Bcrypt{Cost: bcrypt.MinCost}
+	current := password.Argon2id{Memory: 64, Time: 1, Threads: 1}
+
+	bcryptService, _ := newAuthServiceWithHasher(t, password.New(legacy), nil)
+	user, err := bcryptService.CreateUser("rehashuser01", "Rehash123", "", false)
+	require.NoError(t, err)
+
+	userRepo := repository.NewUserRepository(testutils.TestConfig.Database.DSN)
+	t.Cleanup(func() {
+		userRepo.DeleteUserWithDocuments(context.Background(), user.ID, "")
+		userRepo.Close()
+	})
+	stored := func() string {
+		u, err := userRepo.GetUserByID(context.Background(), user.ID)
+		require.NoError(t, err)
+		return u.Password
+	}
+	require.True(t, legacy.Matches(stored()))
+
+	argonService, _ := newAuthServiceWithHasher(t, password.New(current, legacy), nil)
+	_, challenge, err := argonService.Authenticate("rehashuser01", "Rehash123", "rehash", "127.0.0.1")
+	require.NoError(t, err)
+	require.Nil(t, challenge)
+
+	rehashed := stored()
+	assert.True(t, current.Matches(rehashed), "hash must be upgraded after successful login")
+
+	// Хеш с актуальными параметрами не пересчитывается
+	_, _, err = argonService.Authenticate("rehashuser01", "Rehash123", "rehash", "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, rehashed, stored())
+
+	// Неверный пароль не меняет хеш
+	_, _, err = argonService.Authenticate("rehashuser01", "Wrong1234", "rehash", "127.0.0.1")
+	assert.Error(t, err)
+	assert.Equal(t, rehashed, stored())
+}
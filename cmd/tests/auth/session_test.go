@@ -0,0 +1,135 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	SessionID    string `json:"session_id"`
+}
+
+func login(t *testing.T, device string) tokenPair {
+	body, _ := testutils.CreateJSONRequest(map[string]string{
+		"login":  testutils.TestLogin2,
+		"pswd":   testutils.TestPass,
+		"device": device,
+	})
+	req := httptest.NewRequest("POST", "/api/auth", body)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Response tokenPair `json:"response"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	return result.Response
+}
+
+func refresh(t *testing.T, refreshToken string) (int, tokenPair) {
+	body, _ := testutils.CreateJSONRequest(map[string]string{"refresh_token": refreshToken})
+	req := httptest.NewRequest("POST", "/api/auth/refresh", body)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var result struct {
+		Response tokenPair `json:"response"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result.Response
+}
+
+func getStatus(t *testing.T, method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", token)
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSessions_MultipleDevices(t *testing.T) {
+	laptop := login(t, "laptop")
+	phone := login(t, "phone")
+
+	assert.NotEqual(t, laptop.Token, phone.Token)
+	assert.NotEqual(t, laptop.SessionID, phone.SessionID)
+
+	// Обе сессии действуют одновременно
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/docs", laptop.Token))
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/docs", phone.Token))
+
+	// Список сессий содержит обе, текущая помечена
+	req := httptest.NewRequest("GET", "/api/sessions", nil)
+	req.Header.Set("Authorization", laptop.Token)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var list struct {
+		Data struct {
+			Sessions []struct {
+				ID      string `json:"id"`
+				Device  string `json:"device"`
+				Current bool   `json:"current"`
+			} `json:"sessions"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	ids := map[string]bool{}
+	for _, s := range list.Data.Sessions {
+		ids[s.ID] = s.Current
+	}
+	assert.Contains(t, ids, phone.SessionID)
+	assert.True(t, ids[laptop.SessionID])
+
+	// Завершение одной сессии не затрагивает другую
+	assert.Equal(t, http.StatusOK, getStatus(t, "DELETE", "/api/sessions/"+phone.SessionID, laptop.Token))
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/docs", phone.Token))
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/docs", laptop.Token))
+}
+
+func TestRefresh_Rotation(t *testing.T) {
+	pair := login(t, "rotation")
+
+	status, next := refresh(t, pair.RefreshToken)
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEqual(t, pair.RefreshToken, next.RefreshToken)
+	assert.Equal(t, pair.SessionID, next.SessionID)
+
+	// Новый access-токен действует, прежний - нет
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/docs", next.Token))
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/docs", pair.Token))
+}
+
+func TestRefresh_ReuseRevokesSession(t *testing.T) {
+	pair := login(t, "reuse")
+
+	status, next := refresh(t, pair.RefreshToken)
+	assert.Equal(t, http.StatusOK, status)
+
+	// Повторное использование старого refresh-токена отзывает сессию
+	status, _ = refresh(t, pair.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/docs", next.Token))
+	status, _ = refresh(t, next.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
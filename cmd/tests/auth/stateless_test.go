@@ -0,0 +1,141 @@
+package auth_test
+
+import (
+	"context"
+	"docs-server/cmd/tests/testutils"
+	"docs-server/internal/cache"
+	"docs-server/internal/password"
+	"docs-server/internal/repository"
+	"docs-server/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthService сервис аутентификации с настройками тестового приложения.
+// denylist != nil включает проверку токенов без обращения к БД
+func newAuthService(tb testing.TB, denylist *service.TokenDenylist) (*service.AuthService, *cache.MemoryCache) {
+	return newAuthServiceWithHasher(tb, nil, denylist)
+}
+
+// newAuthServiceWithHasher то же, что newAuthService, с заданным хешированием паролей
+func newAuthServiceWithHasher(tb testing.TB, hasher password.Hasher, denylist *service.TokenDenylist) (*service.AuthService, *cache.MemoryCache) {
+	cfg := testutils.TestConfig
+	tokens, err := cfg.NewTokenIssuer()
+	require.NoError(tb, err)
+
+	userRepo := repository.NewUserRepository(cfg.Database.DSN)
+	sessionRepo := repository.NewSessionRepository(cfg.Database.DSN)
+	apiKeyRepo := repository.NewAPIKeyRepository(cfg.Database.DSN)
+	tb.Cleanup(func() {
+		userRepo.Close()
+		sessionRepo.Close()
+		apiKeyRepo.Close()
+	})
+
+	tokenCache := cache.NewMemoryCache()
+	authService := service.NewAuthService(userRepo, sessionRepo, apiKeyRepo, cfg.Auth.AdminToken, hasher, nil, tokens, denylist,
+		tokenCache, service.PasswordReset{}, nil, service.MFAPolicy{ChallengeTTL: time.Minute}, service.OIDCLogin{}, nil)
+	return authService, tokenCache
+}
+
+func statelessLogin(tb testing.TB, authService *service.AuthService) string {
+	pair, challenge, err := authService.Authenticate(testutils.TestLogin, testutils.TestPass, "stateless", "127.0.0.1")
+	require.NoError(tb, err)
+	require.Nil(tb, challenge)
+	return pair.AccessToken
+}
+
+func TestStateless_ValidateFromClaims(t *testing.T) {
+	authService, _ := newAuthService(t, service.NewTokenDenylist(nil, time.Minute))
+	token := statelessLogin(t, authService)
+
+	user, session, err := authService.ValidateToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, testutils.TestLogin, user.Login)
+	assert.NotEmpty(t, user.ID)
+	assert.NotEmpty(t, session.ID)
+	assert.NotEmpty(t, session.AccessTokenID)
+
+	require.NoError(t, authService.Logout(session))
+	_, _, err = authService.ValidateToken(token)
+	assert.ErrorIs(t, err, service.ErrSessionRevoked)
+}
+
+func TestStateless_RefreshRevokesPreviousToken(t *testing.T) {
+	authService, _ := newAuthService(t, service.NewTokenDenylist(nil, time.Minute))
+	pair, _, err := authService.Authenticate(testutils.TestLogin, testutils.TestPass, "stateless", "127.0.0.1")
+	require.NoError(t, err)
+
+	refreshed, err := authService.Refresh(pair.RefreshToken, "127.0.0.1")
+	require.NoError(t, err)
+
+	_, _, err = authService.ValidateToken(pair.AccessToken)
+	assert.ErrorIs(t, err, service.ErrSessionRevoked)
+
+	_, session, err := authService.ValidateToken(refreshed.AccessToken)
+	require.NoError(t, err)
+	require.NoError(t, authService.Logout(session))
+}
+
+func TestStateless_RevokeOtherSessions(t *testing.T) {
+	authService, _ := newAuthService(t, service.NewTokenDenylist(nil, time.Minute))
+	current := statelessLogin(t, authService)
+	other := statelessLogin(t, authService)
+
+	user, session, err := authService.ValidateToken(current)
+	require.NoError(t, err)
+	require.NoError(t, authService.RevokeOtherSessions(context.Background(), user.ID, session.ID))
+
+	_, _, err = authService.ValidateToken(other)
+	assert.ErrorIs(t, err, service.ErrSessionRevoked)
+	_, _, err = authService.ValidateToken(current)
+	assert.NoError(t, err)
+
+	require.NoError(t, authService.Logout(session))
+}
+
+// BenchmarkValidateToken сравнивает проверку access-токена по сессии в БД и по списку отозванных
+func BenchmarkValidateToken(b *testing.B) {
+	b.Run("session/cached", func(b *testing.B) {
+		authService, _ := newAuthService(b, nil)
+		token := statelessLogin(b, authService)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := authService.ValidateToken(token); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("session/uncached", func(b *testing.B) {
+		authService, tokenCache := newAuthService(b, nil)
+		token := statelessLogin(b, authService)
+		_, session, err := authService.ValidateToken(token)
+		require.NoError(b, err)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			// Промах кеша: сессия и пользователь читаются из БД
+			tokenCache.Delete("session_" + session.ID)
+			if _, _, err := authService.ValidateToken(token); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("stateless", func(b *testing.B) {
+		authService, _ := newAuthService(b, service.NewTokenDenylist(nil, time.Minute))
+		token := statelessLogin(b, authService)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := authService.ValidateToken(token); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package auth_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"docs-server/internal/totp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postJSON отправляет POST-запрос и декодирует ответ в out
+func postJSON(t *testing.T, path, token string, body, out interface{}) int {
+	jsonBody, _ := testutils.CreateJSONRequest(body)
+	req := httptest.NewRequest("POST", path, jsonBody)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp.StatusCode
+}
+
+type mfaResponse struct {
+	Response struct {
+		Token              string   `json:"token"`
+		MFAToken           string   `json:"mfa_token"`
+		EnrollmentRequired bool     `json:"enrollment_required"`
+		RecoveryCodes      []string `json:"recovery_codes"`
+	} `json:"response"`
+}
+
+func TestTOTP_EnrollAndLogin(t *testing.T) {
+	login := fmt.Sprintf("totp%d", time.Now().UnixNano())
+	status, _ := adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	})
+	require.Equal(t, http.StatusOK, status)
+	session := loginAs(t, login)
+
+	var enrollment struct {
+		Data struct {
+			Secret string `json:"secret"`
+			URI    string `json:"otpauth_uri"`
+		} `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, postJSON(t, "/api/me/totp", session, nil, &enrollment))
+	secret := enrollment.Data.Secret
+	require.NotEmpty(t, secret)
+	assert.Contains(t, enrollment.Data.URI, "otpauth://totp/")
+
+	assert.Equal(t, http.StatusBadRequest, postJSON(t, "/api/me/totp/confirm", session, map[string]string{"code": "000000"}, nil))
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	var confirmed struct {
+		Data struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		} `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, postJSON(t, "/api/me/totp/confirm", session, map[string]string{"code": code}, &confirmed))
+	require.Len(t, confirmed.Data.RecoveryCodes, 10)
+
+	// Пароль больше не дает доступа, выдается токен второго шага
+	var challenge mfaResponse
+	require.Equal(t, http.StatusOK, postJSON(t, "/api/auth", "", map[string]string{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	}, &challenge))
+	assert.Empty(t, challenge.Response.Token)
+	require.NotEmpty(t, challenge.Response.MFAToken)
+	assert.False(t, challenge.Response.EnrollmentRequired)
+	assert.Equal(t, http.StatusUnauthorized, getStatus(t, "GET", "/api/me", challenge.Response.MFAToken))
+
+	// Код, уже использованный при подключении, повторно не принимается
+	assert.Equal(t, http.StatusUnauthorized, postJSON(t, "/api/auth/mfa", "", map[string]string{
+		"mfa_token": challenge.Response.MFAToken,
+		"code":      code,
+	}, nil))
+
+	next, err := totp.Code(secret, totp.Step(time.Now())+1)
+	require.NoError(t, err)
+	var tokens mfaResponse
+	require.Equal(t, http.StatusOK, postJSON(t, "/api/auth/mfa", "", map[string]string{
+		"mfa_token": challenge.Response.MFAToken,
+		"code":      next,
+	}, &tokens))
+	require.NotEmpty(t, tokens.Response.Token)
+	assert.Equal(t, http.StatusOK, getStatus(t, "GET", "/api/me", tokens.Response.Token))
+
+	// Код восстановления одноразовый
+	recovery := confirmed.Data.RecoveryCodes[0]
+	assert.Equal(t, http.StatusOK, postJSON(t, "/api/auth/mfa", "", map[string]string{
+		"mfa_token": challenge.Response.MFAToken,
+		"code":      recovery,
+	}, nil))
+	assert.Equal(t, http.StatusUnauthorized, postJSON(t, "/api/auth/mfa", "", map[string]string{
+		"mfa_token": challenge.Response.MFAToken,
+		"code":      recovery,
+	}, nil))
+
+	// После отключения вход снова только по паролю
+	assert.Equal(t, http.StatusForbidden, sendJSON(t, "DELETE", "/api/me/totp", tokens.Response.Token, map[string]string{"pswd": "Wr0ngPass!"}))
+	require.Equal(t, http.StatusOK, sendJSON(t, "DELETE", "/api/me/totp", tokens.Response.Token, map[string]string{"pswd": testutils.TestPass}))
+	assert.NotEmpty(t, loginAs(t, login))
+}
+
+func TestTOTP_AdminReset(t *testing.T) {
+	login := fmt.Sprintf("totpreset%d", time.Now().UnixNano())
+	status, user := adminRequest(t, "POST", "/api/admin/users", map[string]interface{}{
+		"login": login,
+		"pswd":  testutils.TestPass,
+	})
+	require.Equal(t, http.StatusOK, status)
+	session := loginAs(t, login)
+
+	var enrollment struct {
+		Data struct {
+			Secret string `json:"secret"`
+		} `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, postJSON(t, "/api/me/totp", session, nil, &enrollment))
+	code, err := totp.Code(enrollment.Data.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, postJSON(t, "/api/me/totp/confirm", session, map[string]string{"code": code}, nil))
+	assert.Equal(t, http.StatusConflict, postJSON(t, "/api/me/totp", session, nil, nil))
+
+	status, _ = adminRequest(t, "DELETE", "/api/admin/users/"+user.ID+"/totp", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, loginAs(t, login))
+}
@@ -0,0 +1,39 @@
+package config_test
+
+import (
+	"docs-server/internal/app"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// Сервер читает ./config.yml из рабочей директории, поэтому тест загружает его так же
+func TestShippedConfig(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir("../../docs-server"))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	raw, err := os.ReadFile("config.yml")
+	require.NoError(t, err)
+	var shipped struct {
+		Auth struct {
+			JWTSecret string `yaml:"jwt_secret"`
+		} `yaml:"auth"`
+	}
+	require.NoError(t, yaml.Unmarshal(raw, &shipped))
+
+	cfg, err := app.NewConfig()
+	require.NoError(t, err)
+	assert.Equal(t, shipped.Auth.JWTSecret, cfg.Auth.JWTSecret, "config.yml is loaded")
+
+	secret, err := cfg.GetJWTSecret()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(secret), 32)
+
+	_, err = cfg.NewTokenIssuer()
+	assert.NoError(t, err)
+}
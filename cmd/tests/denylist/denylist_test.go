@@ -0,0 +1,92 @@
+package denylist_test
+
+import (
+	"context"
+	"docs-server/internal/service"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore общее хранилище отзывов, разделяемое несколькими списками
+type memoryStore struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{tokens: make(map[string]time.Time)}
+}
+
+func (s *memoryStore) AddRevokedToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[tokenID] = expiresAt
+	return nil
+}
+
+func (s *memoryStore) ListRevokedTokens(ctx context.Context) (map[string]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := make(map[string]time.Time, len(s.tokens))
+	for id, expiresAt := range s.tokens {
+		if expiresAt.After(time.Now()) {
+			tokens[id] = expiresAt
+		}
+	}
+	return tokens, nil
+}
+
+func TestDenylist_RevokeAndExpire(t *testing.T) {
+	ctx := context.Background()
+	denylist := service.NewTokenDenylist(nil, time.Minute)
+
+	require.NoError(t, denylist.Revoke(ctx, "token-1", time.Now().Add(time.Hour)))
+	require.NoError(t, denylist.Revoke(ctx, "token-2", time.Now().Add(50*time.Millisecond)))
+	assert.True(t, denylist.Revoked("token-1"))
+	assert.True(t, denylist.Revoked("token-2"))
+	assert.False(t, denylist.Revoked("token-3"))
+
+	// Уже истекший токен не нужно хранить: он не пройдет проверку срока
+	require.NoError(t, denylist.Revoke(ctx, "expired", time.Now().Add(-time.Second)))
+	assert.False(t, denylist.Revoked("expired"))
+
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, denylist.Sync(ctx))
+	assert.True(t, denylist.Revoked("token-1"))
+	assert.False(t, denylist.Revoked("token-2"))
+	assert.Equal(t, 1, denylist.Len())
+}
+
+func TestDenylist_SharedStore(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	first := service.NewTokenDenylist(store, time.Minute)
+	second := service.NewTokenDenylist(store, time.Minute)
+
+	require.NoError(t, first.Revoke(ctx, "token-1", time.Now().Add(time.Hour)))
+	assert.True(t, first.Revoked("token-1"))
+	assert.False(t, second.Revoked("token-1"), "other instance sees revocation only after sync")
+
+	require.NoError(t, second.Sync(ctx))
+	assert.True(t, second.Revoked("token-1"))
+}
+
+func TestDenylist_Run(t *testing.T) {
+	store := newMemoryStore()
+	require.NoError(t, store.AddRevokedToken(context.Background(), "token-1", time.Now().Add(time.Hour)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	denylist := service.NewTokenDenylist(store, 10*time.Millisecond)
+	go denylist.Run(ctx)
+
+	assert.Eventually(t, func() bool { return denylist.Revoked("token-1") }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, store.AddRevokedToken(context.Background(), "token-2", time.Now().Add(time.Hour)))
+	assert.Eventually(t, func() bool { return denylist.Revoked("token-2") }, time.Second, 5*time.Millisecond)
+}
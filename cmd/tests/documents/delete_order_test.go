@@ -0,0 +1,209 @@
+package documents_test
+
+import (
+	"context"
+	"docs-server/cmd/tests/testutils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uploadTestFile загружает файл и возвращает ID документа и путь к файлу на диске
+func uploadTestFile(t *testing.T, name, grant string) (string, string) {
+	app := testutils.TestApp
+	token := testutils.TestToken
+
+	meta := `{"name": "` + name + `", "mime": "text/plain", "grant": [` + grant + `]}`
+	body, contentType := testutils.CreateMultipartRequest(meta, name, "content of "+name)
+	req := httptest.NewRequest("POST", "/api/docs", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", token)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	docID, err := testutils.FindDocumentID(app, token, "/api/docs", name)
+	require.NoError(t, err)
+	require.NotEmpty(t, docID, "Document not found in list")
+
+	doc, err := testutils.TestDocRepo.GetDocumentByID(context.Background(), docID)
+	require.NoError(t, err)
+	require.NotEmpty(t, doc.FilePath)
+
+	return docID, doc.FilePath
+}
+
+// deleteAndPurge перемещает документ в корзину и удаляет окончательно
+func deleteAndPurge(t *testing.T, docID string) int {
+	app := testutils.TestApp
+	token := testutils.TestToken
+
+	req := httptest.NewRequest("DELETE", "/api/docs/"+docID, nil)
+	req.Header.Set("Authorization", token)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest("DELETE", "/api/trash/"+docID, nil)
+	req.Header.Set("Authorization", token)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestPurgeDocument_WithGrants(t *testing.T) {
+	docID, filePath := uploadTestFile(t, "purge_grants.txt", `"testuser1"`)
+
+	assert.Equal(t, http.StatusOK, deleteAndPurge(t, docID))
+
+	_, err := os.Stat(filePath)
+	assert.True(t, os.IsNotExist(err), "file should be removed after purge")
+}
+
+func TestPurgeDocument_FileRemovalFails(t *testing.T) {
+	docID, filePath := uploadTestFile(t, "purge_blob_fails.txt", "")
+
+	// Непустой каталог на месте файла не дает удалить его
+	require.NoError(t, os.Remove(filePath))
+	require.NoError(t, os.MkdirAll(filepath.Join(filePath, "locked"), 0755))
+	defer os.RemoveAll(filePath)
+
+	// Запись удаляется, несмотря на ошибку удаления файла
+	assert.Equal(t, http.StatusOK, deleteAndPurge(t, docID))
+	doc, err := testutils.TestDocRepo.GetDocumentByID(context.Background(), docID)
+	assert.NoError(t, err)
+	assert.Nil(t, doc)
+	_, err = os.Stat(filePath)
+	assert.NoError(t, err, "file should stay in pending deletes queue")
+
+	// Повторная попытка после устранения причины удаляет файл
+	require.NoError(t, os.Remove(filepath.Join(filePath, "locked")))
+	_, err = testutils.TestDocService.ProcessPendingDeletes(context.Background(), time.Now().Add(2*time.Hour))
+	assert.NoError(t, err)
+	_, err = os.Stat(filePath)
+	assert.True(t, os.IsNotExist(err), "file should be removed on retry")
+}
+
+func TestDeleteDocument_TransactionFails(t *testing.T) {
+	docID, filePath := uploadTestFile(t, "delete_tx_fails.txt", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := testutils.TestDocRepo.DeleteDocument(ctx, docID)
+	assert.Error(t, err)
+
+	// Ни запись, ни файл не затронуты
+	doc, err := testutils.TestDocRepo.GetDocumentByID(context.Background(), docID)
+	assert.NoError(t, err)
+	assert.NotNil(t, doc)
+	_, err = os.Stat(filePath)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, deleteAndPurge(t, docID))
+}
+
+func TestDeleteDocument_CrashAfterCommit(t *testing.T) {
+	docID, filePath := uploadTestFile(t, "delete_crash.txt", "")
+
+	// Коммит выполнен, но файл не удален (процесс завершился до удаления)
+	pending, err := testutils.TestDocRepo.DeleteDocument(context.Background(), docID)
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	assert.Equal(t, filePath, pending.FilePath)
+	_, err = os.Stat(filePath)
+	assert.NoError(t, err)
+
+	// Фоновая обработка очереди удаляет файл
+	_, err = testutils.TestDocService.ProcessPendingDeletes(context.Background(), time.Now())
+	assert.NoError(t, err)
+	_, err = os.Stat(filePath)
+	assert.True(t, os.IsNotExist(err), "file should be removed by pending deletes worker")
+}
+
+// adminStatus выполняет запрос администратора и возвращает код ответа и поле data
+func adminStatus(t *testing.T, method, path, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", testutils.TestToken)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var result struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result.Data
+}
+
+func TestDeleteUser_IsAtomic(t *testing.T) {
+	ctx := context.Background()
+	login := fmt.Sprintf("deleted%d", time.Now().UnixNano())
+	status, user := adminStatus(t, "POST", "/api/admin/users",
+		`{"login": "`+login+`", "pswd": "`+testutils.TestPass+`"}`)
+	require.Equal(t, http.StatusOK, status)
+	userID := user["id"].(string)
+
+	body, _ := testutils.CreateJSONRequest(map[string]string{"login": login, "pswd": testutils.TestPass})
+	req := httptest.NewRequest("POST", "/api/auth", body)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	var auth struct {
+		Response struct {
+			Token string `json:"token"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&auth))
+	resp.Body.Close()
+	token := "Bearer " + auth.Response.Token
+
+	require.Equal(t, http.StatusOK, uploadAs(t, token, "owned-plain.txt", "plain"))
+	require.Equal(t, http.StatusOK, uploadAs(t, token, "owned-held.txt", "held"))
+	plainID, err := testutils.FindDocumentID(testutils.TestApp, token, "/api/docs", "owned-plain.txt")
+	require.NoError(t, err)
+	heldID, err := testutils.FindDocumentID(testutils.TestApp, token, "/api/docs", "owned-held.txt")
+	require.NoError(t, err)
+
+	status, _ = adminStatus(t, "PATCH", "/api/docs/"+heldID, `{"legal_hold": true}`)
+	require.Equal(t, http.StatusOK, status)
+
+	// Удаление отклонено целиком: ни учетная запись, ни документы, ни сессии не затронуты
+	status, _ = adminStatus(t, "DELETE", "/api/admin/users/"+userID, "")
+	assert.Equal(t, http.StatusConflict, status)
+	status, _ = adminStatus(t, "GET", "/api/admin/users/"+userID, "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, http.StatusOK, uploadAs(t, token, "owned-after-conflict.txt", "still signed in"))
+	for _, id := range []string{plainID, heldID} {
+		doc, err := testutils.TestDocRepo.GetDocumentByID(ctx, id)
+		require.NoError(t, err)
+		assert.NotNil(t, doc, id)
+	}
+
+	// После снятия удержания удаление можно повторить
+	status, _ = adminStatus(t, "PATCH", "/api/docs/"+heldID, `{"legal_hold": false}`)
+	require.Equal(t, http.StatusOK, status)
+	status, _ = adminStatus(t, "DELETE", "/api/admin/users/"+userID, "")
+	require.Equal(t, http.StatusOK, status)
+	for _, id := range []string{plainID, heldID} {
+		doc, err := testutils.TestDocRepo.GetDocumentByID(ctx, id)
+		require.NoError(t, err)
+		assert.Nil(t, doc, id)
+	}
+	// После удаления токен пользователя больше не действует
+	assert.Equal(t, http.StatusUnauthorized, uploadAs(t, token, "owned-after-delete.txt", "revoked"))
+}
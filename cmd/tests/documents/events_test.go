@@ -0,0 +1,58 @@
+package documents_test
+
+import (
+	"context"
+	"docs-server/cmd/tests/testutils"
+	"docs-server/internal/model"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// relayEvents публикует накопленные в outbox события, как это делает OutboxRelay
+func relayEvents(t *testing.T) {
+	t.Helper()
+	for {
+		published, err := testutils.TestOutbox.RelayDue(context.Background(), time.Now())
+		require.NoError(t, err)
+		if published < 100 {
+			return
+		}
+	}
+}
+
+func TestEventStream_DocumentLifecycle(t *testing.T) {
+	stream := testutils.TestEventStream
+	ctx := context.Background()
+
+	start, _, err := stream.Resume(ctx, 0, false)
+	require.NoError(t, err)
+
+	docID, _ := uploadTestFile(t, "events-test.txt", "")
+	doc, err := testutils.TestDocRepo.GetDocumentByID(ctx, docID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, deleteAndPurge(t, docID))
+	relayEvents(t)
+
+	// Владелец видит создание и удаление документа
+	events, _, err := stream.Read(ctx, doc.Owner, start)
+	require.NoError(t, err)
+	var types []string
+	for _, event := range events {
+		if event.DocumentID == docID {
+			types = append(types, event.Type)
+		}
+	}
+	assert.Equal(t, []string{model.EventDocumentCreated, model.EventDocumentDeleted}, types)
+
+	// Посторонний пользователь событий закрытого документа не получает
+	events, next, err := stream.Read(ctx, "00000000-0000-0000-0000-000000000000", start)
+	require.NoError(t, err)
+	for _, event := range events {
+		assert.NotEqual(t, docID, event.DocumentID)
+	}
+	assert.Greater(t, next, start)
+}
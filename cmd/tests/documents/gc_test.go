@@ -0,0 +1,106 @@
+package documents_test
+
+import (
+	"context"
+	"docs-server/cmd/tests/testutils"
+	"docs-server/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectGarbage_OrphanFile(t *testing.T) {
+	docService := testutils.TestDocService
+	uploadDir := testutils.TestConfig.Storage.UploadDir
+
+	assert.NoError(t, os.MkdirAll(uploadDir, 0755))
+	orphan := filepath.Join(uploadDir, "gc-orphan-test.bin")
+	assert.NoError(t, os.WriteFile(orphan, []byte("orphan"), 0644))
+	defer os.Remove(orphan)
+
+	old := time.Now().Add(-2 * time.Hour)
+	assert.NoError(t, os.Chtimes(orphan, old, old))
+
+	// 1. Пробный прогон только сообщает о файле
+	report, err := docService.CollectGarbage(context.Background(), time.Hour, false)
+	assert.NoError(t, err)
+	assert.Contains(t, report.OrphanFiles, orphan)
+	assert.Equal(t, 0, report.RemovedFiles)
+	_, err = os.Stat(orphan)
+	assert.NoError(t, err)
+
+	// 2. Свежие файлы не трогаем
+	report, err = docService.CollectGarbage(context.Background(), 3*time.Hour, false)
+	assert.NoError(t, err)
+	assert.NotContains(t, report.OrphanFiles, orphan)
+
+	// 3. Исправление удаляет файл
+	report, err = docService.CollectGarbage(context.Background(), time.Hour, true)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, report.RemovedFiles, 1)
+	_, err = os.Stat(orphan)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCollectGarbage_KeepsHeldDocuments(t *testing.T) {
+	ctx := context.Background()
+	start, _, err := testutils.TestEventStream.Resume(ctx, 0, false)
+	require.NoError(t, err)
+
+	heldID, heldPath := uploadTestFile(t, "gc-held.txt", "")
+	danglingID, danglingPath := uploadTestFile(t, "gc-dangling.txt", "")
+
+	req := httptest.NewRequest("PATCH", "/api/docs/"+heldID, strings.NewReader(`{"legal_hold": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", testutils.TestToken)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Файлы пропали с диска, записи остались
+	require.NoError(t, os.Remove(heldPath))
+	require.NoError(t, os.Remove(danglingPath))
+
+	report, err := testutils.TestDocService.CollectGarbage(ctx, time.Hour, true)
+	require.NoError(t, err)
+	assert.Contains(t, report.DanglingDocuments, heldID)
+	assert.Contains(t, report.HeldDocuments, heldID)
+	assert.NotContains(t, report.HeldDocuments, danglingID)
+
+	held, err := testutils.TestDocRepo.GetDocumentByID(ctx, heldID)
+	require.NoError(t, err)
+	require.NotNil(t, held, "document under legal hold must survive gc")
+	dangling, err := testutils.TestDocRepo.GetDocumentByID(ctx, danglingID)
+	require.NoError(t, err)
+	assert.Nil(t, dangling)
+
+	// Событие удаления содержит полный документ
+	relayEvents(t)
+	events, _, err := testutils.TestEventStream.Read(ctx, held.Owner, start)
+	require.NoError(t, err)
+	var deleted *model.DocumentEvent
+	for _, event := range events {
+		if event.DocumentID == danglingID && event.Type == model.EventDocumentDeleted {
+			deleted = event
+		}
+	}
+	require.NotNil(t, deleted)
+	assert.Equal(t, "gc-dangling.txt", deleted.Document.Name)
+
+	// Снимаем удержание и удаляем документ
+	req = httptest.NewRequest("PATCH", "/api/docs/"+heldID, strings.NewReader(`{"legal_hold": false}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", testutils.TestToken)
+	resp, err = testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, deleteAndPurge(t, heldID))
+}
@@ -0,0 +1,120 @@
+package documents_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadDocument_ExpiresInPast(t *testing.T) {
+	app := testutils.TestApp
+	token := testutils.TestToken
+
+	meta := `{
+        "name": "expired.txt",
+        "mime": "text/plain",
+        "expires_at": "` + time.Now().Add(-time.Hour).UTC().Format(time.RFC3339) + `"
+    }`
+
+	body, contentType := testutils.CreateMultipartRequest(meta, "expired.txt", "expired content")
+	req := httptest.NewRequest("POST", "/api/docs", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", token)
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestLegalHoldBlocksDelete(t *testing.T) {
+	app := testutils.TestApp
+	token := testutils.TestToken
+
+	meta := `{
+        "name": "legal_hold.txt",
+        "mime": "text/plain",
+        "expires_at": "` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"
+    }`
+
+	body, contentType := testutils.CreateMultipartRequest(meta, "legal_hold.txt", "held content")
+	req := httptest.NewRequest("POST", "/api/docs", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", token)
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	docID, err := testutils.FindDocumentID(app, token, "/api/docs", "legal_hold.txt")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, docID, "Document not found in list")
+
+	patch := func(body string) int {
+		req := httptest.NewRequest("PATCH", "/api/docs/"+docID, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	del := func() int {
+		req := httptest.NewRequest("DELETE", "/api/docs/"+docID, nil)
+		req.Header.Set("Authorization", token)
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, patch(`{"legal_hold": true, "expires_at": null}`))
+	assert.Equal(t, http.StatusConflict, del())
+
+	assert.Equal(t, http.StatusOK, patch(`{"legal_hold": false}`))
+	assert.Equal(t, http.StatusOK, del())
+}
+
+func TestLegalHoldAdminOnly(t *testing.T) {
+	app := testutils.TestApp
+	ownerToken, adminToken := testutils.TestToken2, testutils.TestToken
+
+	require.Equal(t, http.StatusOK, uploadAs(t, ownerToken, "legal_hold_owner.txt", "held content"))
+	docID, err := testutils.FindDocumentID(app, ownerToken, "/api/docs", "legal_hold_owner.txt")
+	require.NoError(t, err)
+	require.NotEmpty(t, docID)
+
+	request := func(method, token, body string) int {
+		req := httptest.NewRequest(method, "/api/docs/"+docID, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", token)
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Владелец не может ни поставить, ни снять удержание
+	assert.Equal(t, http.StatusForbidden, request("PATCH", ownerToken, `{"legal_hold": true}`))
+	assert.Equal(t, http.StatusOK, request("PATCH", adminToken, `{"legal_hold": true}`))
+	assert.Equal(t, http.StatusForbidden, request("PATCH", ownerToken, `{"legal_hold": false}`))
+	assert.Equal(t, http.StatusConflict, request("DELETE", ownerToken, ""))
+
+	// Срок хранения владелец по-прежнему меняет, администратор в чужом документе - нет
+	expiresAt := `{"expires_at": "` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`
+	assert.Equal(t, http.StatusOK, request("PATCH", ownerToken, expiresAt))
+	assert.Equal(t, http.StatusForbidden, request("PATCH", adminToken, expiresAt))
+
+	assert.Equal(t, http.StatusOK, request("PATCH", adminToken, `{"legal_hold": false}`))
+	assert.Equal(t, http.StatusOK, request("DELETE", ownerToken, ""))
+}
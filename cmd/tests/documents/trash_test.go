@@ -0,0 +1,99 @@
+package documents_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashWorkflow(t *testing.T) {
+	app := testutils.TestApp
+	token := testutils.TestToken
+
+	meta := `{
+        "name": "trash_test.txt",
+        "public": false,
+        "mime": "text/plain"
+    }`
+
+	body, contentType := testutils.CreateMultipartRequest(meta, "trash_test.txt", "trash content")
+	req := httptest.NewRequest("POST", "/api/docs", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", token)
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	docID, err := testutils.FindDocumentID(app, token, "/api/docs", "trash_test.txt")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, docID, "Document not found in list")
+
+	// 1. Удаление перемещает документ в корзину
+	t.Run("Delete moves document to trash", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/docs/"+docID, nil)
+		req.Header.Set("Authorization", token)
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		trashedID, err := testutils.FindDocumentID(app, token, "/api/trash", "trash_test.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, docID, trashedID)
+	})
+
+	// 2. Документ в корзине недоступен
+	t.Run("Trashed document is hidden", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/docs/"+docID, nil)
+		req.Header.Set("Authorization", token)
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+	})
+
+	// 3. Восстановление возвращает документ в список
+	t.Run("Restore document", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/trash/"+docID+"/restore", nil)
+		req.Header.Set("Authorization", token)
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		restoredID, err := testutils.FindDocumentID(app, token, "/api/docs", "trash_test.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, docID, restoredID)
+	})
+
+	// 4. Окончательное удаление из корзины
+	t.Run("Purge document", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/api/docs/"+docID, nil)
+		req.Header.Set("Authorization", token)
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		req = httptest.NewRequest("DELETE", "/api/trash/"+docID, nil)
+		req.Header.Set("Authorization", token)
+
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		trashedID, err := testutils.FindDocumentID(app, token, "/api/trash", "trash_test.txt")
+		assert.NoError(t, err)
+		assert.Empty(t, trashedID)
+	})
+}
@@ -0,0 +1,154 @@
+package documents_test
+
+import (
+	"docs-server/cmd/tests/testutils"
+	"docs-server/internal/model"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUsage_Success(t *testing.T) {
+	app := testutils.TestApp
+	token := testutils.TestToken
+
+	req := httptest.NewRequest("GET", "/api/me/usage", nil)
+	req.Header.Set("Authorization", token)
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Data struct {
+			Bytes          int64 `json:"bytes"`
+			Documents      int64 `json:"documents"`
+			QuotaBytes     int64 `json:"quota_bytes"`
+			QuotaDocuments int64 `json:"quota_documents"`
+		} `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, result.Data.Bytes, int64(0))
+	assert.GreaterOrEqual(t, result.Data.Documents, int64(0))
+}
+
+func TestGetUsage_Unauthorized(t *testing.T) {
+	app := testutils.TestApp
+
+	req := httptest.NewRequest("GET", "/api/me/usage", nil)
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+// getUsage возвращает использование хранилища пользователем с токеном token
+func getUsage(t *testing.T, token string) (string, model.Usage) {
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	req.Header.Set("Authorization", token)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	var me struct {
+		Data model.UserResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&me))
+	resp.Body.Close()
+
+	req = httptest.NewRequest("GET", "/api/me/usage", nil)
+	req.Header.Set("Authorization", token)
+	resp, err = testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var usage struct {
+		Data model.Usage `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&usage))
+	return me.Data.ID, usage.Data
+}
+
+// setQuota задает персональные квоты пользователя от имени администратора
+func setQuota(t *testing.T, userID string, quota map[string]int64) {
+	body, _ := testutils.CreateJSONRequest(quota)
+	req := httptest.NewRequest("PATCH", "/api/admin/users/"+userID, body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", testutils.TestToken)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func uploadAs(t *testing.T, token, name, content string) int {
+	body, contentType := testutils.CreateMultipartRequest(`{"name": "`+name+`", "mime": "text/plain"}`, name, content)
+	req := httptest.NewRequest("POST", "/api/docs", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", token)
+	resp, err := testutils.TestApp.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestUpload_FileLargerThanQuota(t *testing.T) {
+	userID, _ := getUsage(t, testutils.TestToken2)
+	setQuota(t, userID, map[string]int64{"quota_bytes": 10})
+	defer setQuota(t, userID, map[string]int64{"quota_bytes": -1})
+
+	_, usage := getUsage(t, testutils.TestToken2)
+	assert.Equal(t, int64(10), usage.QuotaBytes, "admin sets personal quota")
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, uploadAs(t, testutils.TestToken2, "quota-large.txt", "more than ten bytes"))
+}
+
+func TestUpload_QuotaExceeded(t *testing.T) {
+	userID, usage := getUsage(t, testutils.TestToken2)
+	setQuota(t, userID, map[string]int64{"quota_documents": usage.Documents})
+	defer setQuota(t, userID, map[string]int64{"quota_documents": -1})
+
+	assert.Equal(t, http.StatusInsufficientStorage, uploadAs(t, testutils.TestToken2, "quota-count.txt", "content"))
+
+	_, after := getUsage(t, testutils.TestToken2)
+	assert.Equal(t, usage.Documents, after.Documents)
+}
+
+func TestUpload_ConcurrentUploadsRespectQuota(t *testing.T) {
+	userID, usage := getUsage(t, testutils.TestToken2)
+	setQuota(t, userID, map[string]int64{"quota_documents": usage.Documents + 1})
+	defer setQuota(t, userID, map[string]int64{"quota_documents": -1})
+
+	statuses := make(chan int, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses <- uploadAs(t, testutils.TestToken2, fmt.Sprintf("quota-race-%d.txt", i), "content")
+		}(i)
+	}
+	wg.Wait()
+	close(statuses)
+
+	accepted := 0
+	for status := range statuses {
+		if status == http.StatusOK {
+			accepted++
+		} else {
+			assert.Equal(t, http.StatusInsufficientStorage, status)
+		}
+	}
+	assert.Equal(t, 1, accepted, "only one upload fits into the quota")
+
+	_, after := getUsage(t, testutils.TestToken2)
+	assert.Equal(t, usage.Documents+1, after.Documents)
+}
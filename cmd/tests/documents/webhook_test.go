@@ -0,0 +1,125 @@
+package documents_test
+
+import (
+	"context"
+	"docs-server/cmd/tests/testutils"
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_DocumentLifecycle(t *testing.T) {
+	app := testutils.TestApp
+	token := testutils.TestToken
+
+	var mu sync.Mutex
+	var events []model.DocumentEvent
+	var secret string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if service.VerifyWebhookSignature(secret, r.Header.Get("X-Webhook-Signature"), body, time.Minute, time.Now()) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var event model.DocumentEvent
+		json.Unmarshal(body, &event)
+		events = append(events, event)
+	}))
+	defer receiver.Close()
+
+	// Регистрация webhook
+	req := httptest.NewRequest("POST", "/api/webhooks", strings.NewReader(
+		`{"url": "`+receiver.URL+`", "events": ["document.created", "document.deleted"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var created struct {
+		Response struct {
+			Secret  string        `json:"secret"`
+			Webhook model.Webhook `json:"webhook"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	hookID := created.Response.Webhook.ID
+	mu.Lock()
+	secret = created.Response.Secret
+	mu.Unlock()
+	defer func() {
+		req := httptest.NewRequest("DELETE", "/api/webhooks/"+hookID, nil)
+		req.Header.Set("Authorization", token)
+		if resp, err := app.Test(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	// Загрузка и удаление документа
+	docID, _ := uploadTestFile(t, "webhook-test.txt", "")
+	req = httptest.NewRequest("DELETE", "/api/docs/"+docID, nil)
+	req.Header.Set("Authorization", token)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	relayEvents(t)
+	_, err = testutils.TestWebhookService.DeliverDue(context.Background(), time.Now())
+	require.NoError(t, err)
+
+	mu.Lock()
+	var types []string
+	for _, event := range events {
+		if event.DocumentID == docID {
+			types = append(types, event.Type)
+		}
+	}
+	mu.Unlock()
+	assert.Equal(t, []string{model.EventDocumentCreated, model.EventDocumentDeleted}, types)
+
+	// Журнал доставок и повторная отправка
+	req = httptest.NewRequest("GET", "/api/webhooks/"+hookID+"/deliveries", nil)
+	req.Header.Set("Authorization", token)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var log struct {
+		Data struct {
+			Deliveries []model.WebhookDelivery `json:"deliveries"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&log))
+	require.NotEmpty(t, log.Data.Deliveries)
+	assert.Equal(t, model.DeliveryDelivered, log.Data.Deliveries[0].Status)
+
+	req = httptest.NewRequest("POST", "/api/webhooks/"+hookID+"/deliveries/"+log.Data.Deliveries[0].ID+"/redeliver", nil)
+	req.Header.Set("Authorization", token)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Чужой webhook недоступен
+	req = httptest.NewRequest("GET", "/api/webhooks/"+hookID+"/deliveries", nil)
+	req.Header.Set("Authorization", testutils.TestToken2)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,283 @@
+package events_test
+
+import (
+	"bufio"
+	"context"
+	"docs-server/internal/controller"
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func event(id, owner string, public bool, grant ...string) *model.DocumentEvent {
+	return &model.DocumentEvent{
+		ID:         id,
+		Type:       model.EventDocumentCreated,
+		Time:       time.Now().UTC(),
+		DocumentID: "doc-" + id,
+		Owner:      owner,
+		Document:   &model.Document{ID: "doc-" + id, Public: public, Grant: grant},
+	}
+}
+
+func TestReadFiltersByAccess(t *testing.T) {
+	stream := service.NewEventStream(nil, 100, time.Second)
+	cursor, reset, err := stream.Resume(context.Background(), 0, false)
+	require.NoError(t, err)
+	assert.False(t, reset)
+
+	stream.Publish(context.Background(), event("own", "alice", false))
+	stream.Publish(context.Background(), event("granted", "bob", false, "alice"))
+	stream.Publish(context.Background(), event("public", "bob", true))
+	stream.Publish(context.Background(), event("private", "bob", false))
+
+	events, next, err := stream.Read(context.Background(), "alice", cursor)
+	require.NoError(t, err)
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	assert.Equal(t, []string{"own", "granted", "public"}, ids)
+	assert.Equal(t, cursor+4, next, "cursor must skip invisible events too")
+
+	events, after, err := stream.Read(context.Background(), "alice", next)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, next, after)
+}
+
+func TestResume(t *testing.T) {
+	stream := service.NewEventStream(nil, 3, time.Second)
+	start, _, err := stream.Resume(context.Background(), 0, false)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		stream.Publish(context.Background(), event(strconv.Itoa(i), "alice", false))
+	}
+	last := start + 5
+
+	t.Run("retained id continues after it", func(t *testing.T) {
+		cursor, reset, err := stream.Resume(context.Background(), last-2, true)
+		require.NoError(t, err)
+		assert.False(t, reset)
+
+		events, _, err := stream.Read(context.Background(), "alice", cursor)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		assert.Equal(t, "3", events[0].ID)
+		assert.Equal(t, "4", events[1].ID)
+	})
+
+	t.Run("trimmed id resets", func(t *testing.T) {
+		cursor, reset, err := stream.Resume(context.Background(), last-4, true)
+		require.NoError(t, err)
+		assert.True(t, reset)
+		assert.Equal(t, last, cursor)
+	})
+
+	t.Run("lagging reader loses events", func(t *testing.T) {
+		_, cursor, err := stream.Read(context.Background(), "alice", start)
+		assert.ErrorIs(t, err, service.ErrEventsLost)
+		assert.Equal(t, last, cursor)
+	})
+
+	t.Run("id from another process resets", func(t *testing.T) {
+		_, reset, err := stream.Resume(context.Background(), last+100, true)
+		require.NoError(t, err)
+		assert.True(t, reset)
+	})
+}
+
+// gapStore журнал, в котором номер 2 еще не виден
+type gapStore struct {
+	events []*model.DocumentEvent
+}
+
+func (s *gapStore) AppendEvent(ctx context.Context, event *model.DocumentEvent) (int64, error) {
+	return 0, nil
+}
+
+func (s *gapStore) ListEventsAfter(ctx context.Context, seq int64, limit int) ([]*model.DocumentEvent, error) {
+	var events []*model.DocumentEvent
+	for _, e := range s.events {
+		if e.Seq > seq {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (s *gapStore) EventLogBounds(ctx context.Context) (int64, int64, error) {
+	return 1, 3, nil
+}
+
+func (s *gapStore) TrimEvents(ctx context.Context, keep int) error {
+	return nil
+}
+
+func TestReadWaitsForGap(t *testing.T) {
+	first := event("1", "alice", false)
+	first.Seq = 1
+	// Изменение давнее (событие задержалось в outbox), но в журнал записано только что
+	third := event("3", "alice", false)
+	third.Seq = 3
+	third.Time = time.Now().Add(-time.Hour)
+	third.AppendedAt = time.Now()
+	stream := service.NewEventStream(&gapStore{events: []*model.DocumentEvent{first, third}}, 100, time.Second)
+
+	events, cursor, err := stream.Read(context.Background(), "alice", 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(1), cursor, "fresh event after a gap must wait")
+
+	third.AppendedAt = time.Now().Add(-time.Minute)
+	events, cursor, err = stream.Read(context.Background(), "alice", cursor)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, int64(3), cursor, "old gap is skipped")
+}
+
+// sseMessage сообщение потока text/event-stream
+type sseMessage struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+func readMessage(t *testing.T, r *bufio.Reader) sseMessage {
+	t.Helper()
+	var msg sseMessage
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if msg.Event != "" || msg.Data != "" {
+				return msg
+			}
+		case strings.HasPrefix(line, "id: "):
+			msg.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			msg.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			msg.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func startServer(t *testing.T, stream *service.EventStream) string {
+	return startServerWithAuth(t, stream, nil)
+}
+
+// startServerWithAuth запускает поток, повторно авторизующий запрос через reauthorize
+func startServerWithAuth(t *testing.T, stream *service.EventStream, reauthorize controller.Reauthorize) string {
+	t.Helper()
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/api/events", func(ctx *fiber.Ctx) error {
+		ctx.Locals("user", &model.User{ID: ctx.Get("X-User")})
+		if reauthorize != nil {
+			ctx.Locals("reauthorize", reauthorize)
+		}
+		return ctx.Next()
+	}, controller.NewEventsController(stream, 50*time.Millisecond).Stream)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { app.ShutdownWithTimeout(time.Second) })
+	return "http://" + ln.Addr().String() + "/api/events"
+}
+
+func openStream(t *testing.T, url, user, lastEventID string) *bufio.Reader {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("X-User", user)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	return bufio.NewReader(resp.Body)
+}
+
+func TestStreamEndpoint(t *testing.T) {
+	stream := service.NewEventStream(nil, 100, time.Second)
+	url := startServer(t, stream)
+
+	body := openStream(t, url, "alice", "")
+	time.Sleep(100 * time.Millisecond) // Поток должен подписаться до событий
+
+	stream.Publish(context.Background(), event("private", "bob", false))
+	stream.Publish(context.Background(), event("own", "alice", false))
+
+	msg := readMessage(t, body)
+	assert.Equal(t, model.EventDocumentCreated, msg.Event)
+	var received model.DocumentEvent
+	require.NoError(t, json.Unmarshal([]byte(msg.Data), &received))
+	assert.Equal(t, "own", received.ID, "events of other users' private documents are not sent")
+	ownID := msg.ID
+
+	stream.Publish(context.Background(), event("later", "alice", false))
+	require.NoError(t, json.Unmarshal([]byte(readMessage(t, body).Data), &received))
+	assert.Equal(t, "later", received.ID)
+
+	// Переподключение с Last-Event-ID повторяет события после него
+	resumed := openStream(t, url, "alice", ownID)
+	require.NoError(t, json.Unmarshal([]byte(readMessage(t, resumed).Data), &received))
+	assert.Equal(t, "later", received.ID)
+
+	// Слишком старый идентификатор - сигнал перечитать данные
+	stale := openStream(t, url, "alice", "1")
+	assert.Equal(t, "reset", readMessage(t, stale).Event)
+}
+
+func TestStreamClosesAfterRevocation(t *testing.T) {
+	stream := service.NewEventStream(nil, 100, time.Second)
+	var revoked atomic.Bool
+	url := startServerWithAuth(t, stream, func() error {
+		if revoked.Load() {
+			return service.ErrSessionRevoked
+		}
+		return nil
+	})
+
+	body := openStream(t, url, "alice", "")
+	time.Sleep(100 * time.Millisecond)
+	stream.Publish(context.Background(), event("public", "bob", true))
+	assert.Equal(t, model.EventDocumentCreated, readMessage(t, body).Event)
+
+	// После отзыва сессии поток завершается, события публичных документов больше не приходят
+	revoked.Store(true)
+	assert.Equal(t, "revoked", readMessage(t, body).Event)
+	stream.Publish(context.Background(), event("after", "bob", true))
+	_, err := body.ReadString('\n')
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestStreamRejectsInvalidLastEventID(t *testing.T) {
+	url := startServer(t, service.NewEventStream(nil, 100, time.Second))
+
+	req, err := http.NewRequest(http.MethodGet, url+"?last_event_id=abc", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
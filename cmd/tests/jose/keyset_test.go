@@ -0,0 +1,231 @@
+package jose_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"docs-server/internal/jose"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePEM сохраняет ключ в PEM-файл во временном каталоге теста
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+	return path
+}
+
+func writePrivateKey(t *testing.T, name string, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return writePEM(t, name, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, name string, key interface{}) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return writePEM(t, name, "PUBLIC KEY", der)
+}
+
+func newClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   "user",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+}
+
+func parse(set *jose.KeySet, token string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(token, &jwt.RegisteredClaims{}, set.Keyfunc, jwt.WithValidMethods(set.Methods()))
+}
+
+func TestKeySet_SignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		key interface{}
+		alg string
+	}{
+		"RS256": {rsaKey, "RS256"},
+		"ES256": {ecKey, "ES256"},
+		"EdDSA": {edKey, "EdDSA"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			set, err := jose.LoadKeySet(writePrivateKey(t, "signing.pem", tc.key), nil, nil)
+			require.NoError(t, err)
+
+			signed, err := set.Sign(newClaims())
+			require.NoError(t, err)
+
+			token, err := parse(set, signed)
+			require.NoError(t, err)
+			assert.Equal(t, tc.alg, token.Method.Alg())
+
+			jwks := set.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, token.Header["kid"], jwks.Keys[0].Kid)
+			assert.Equal(t, tc.alg, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	oldSet, err := jose.LoadKeySet(writePrivateKey(t, "old.pem", oldKey), nil, nil)
+	require.NoError(t, err)
+	oldToken, err := oldSet.Sign(newClaims())
+	require.NoError(t, err)
+
+	// Новый ключ подписывает, прежний (только открытая часть) продолжает проверять
+	newKeyFile := writePrivateKey(t, "new.pem", newKey)
+	rotated, err := jose.LoadKeySet(newKeyFile,
+		[]string{writePublicKey(t, "old.pub", &oldKey.PublicKey)}, nil)
+	require.NoError(t, err)
+
+	_, err = parse(rotated, oldToken)
+	assert.NoError(t, err)
+
+	newToken, err := rotated.Sign(newClaims())
+	require.NoError(t, err)
+	token, err := parse(rotated, newToken)
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", token.Method.Alg())
+
+	assert.Len(t, rotated.JWKS().Keys, 2)
+
+	// После удаления прежнего ключа из набора его токены отклоняются
+	newOnly, err := jose.LoadKeySet(newKeyFile, nil, nil)
+	require.NoError(t, err)
+	_, err = parse(newOnly, oldToken)
+	assert.Error(t, err)
+}
+
+func TestKeySet_LegacyHMAC(t *testing.T) {
+	secret := []byte("legacy-secret-legacy-secret-0123")
+	legacy, err := jose.NewHMACKeySet(secret)
+	require.NoError(t, err)
+	legacyToken, err := legacy.Sign(newClaims())
+	require.NoError(t, err)
+
+	token, err := parse(legacy, legacyToken)
+	require.NoError(t, err)
+	assert.NotContains(t, token.Header, "kid")
+	assert.Empty(t, legacy.JWKS().Keys, "symmetric keys must not be published")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signingKey := writePrivateKey(t, "signing.pem", rsaKey)
+
+	withLegacy, err := jose.LoadKeySet(signingKey, nil, secret)
+	require.NoError(t, err)
+	_, err = parse(withLegacy, legacyToken)
+	assert.NoError(t, err)
+	assert.Len(t, withLegacy.JWKS().Keys, 1)
+
+	withoutLegacy, err := jose.LoadKeySet(signingKey, nil, nil)
+	require.NoError(t, err)
+	_, err = parse(withoutLegacy, legacyToken)
+	assert.Error(t, err)
+
+	// Токен с неизвестным kid
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherSet, err := jose.LoadKeySet(writePrivateKey(t, "other.pem", other), nil, nil)
+	require.NoError(t, err)
+	otherToken, err := otherSet.Sign(newClaims())
+	require.NoError(t, err)
+	_, err = parse(withoutLegacy, otherToken)
+	assert.ErrorIs(t, err, jose.ErrUnknownKey)
+}
+
+func TestKeySet_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	set, err := jose.LoadKeySet(writePrivateKey(t, "signing.pem", rsaKey), nil, nil)
+	require.NoError(t, err)
+	kid := set.JWKS().Keys[0].Kid
+
+	// Токен HS256, подписанный открытым ключом как секретом, с kid RSA-ключа
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims())
+	forged.Header["kid"] = kid
+	signed, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	require.NoError(t, err)
+
+	_, err = parse(set, signed)
+	assert.Error(t, err)
+
+	// Неподписанный токен
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, newClaims())
+	unsigned.Header["kid"] = kid
+	signed, err = unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	_, err = parse(set, signed)
+	assert.Error(t, err)
+}
+
+func TestLoadKeySet_InvalidKeys(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = jose.LoadKeySet(writePrivateKey(t, "weak.pem", weak), nil, nil)
+	assert.Error(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, err = jose.LoadKeySet(writePublicKey(t, "public.pem", &ecKey.PublicKey), nil, nil)
+	assert.Error(t, err, "signing key must be private")
+
+	_, err = jose.LoadKeySet(filepath.Join(t.TempDir(), "missing.pem"), nil, nil)
+	assert.Error(t, err)
+
+	_, err = jose.NewHMACKeySet(nil)
+	assert.Error(t, err)
+}
+
+func TestJWK_ThumbprintRFC7638(t *testing.T) {
+	// Пример из RFC 7638, раздел 3.1
+	jwk := jose.JWK{
+		Kty: "RSA",
+		N: "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMs" +
+			"tn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91" +
+			"CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E: "AQAB",
+	}
+	thumbprint, err := jwk.Thumbprint()
+	require.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint)
+
+	key, err := jwk.PublicKey()
+	require.NoError(t, err)
+	roundTrip, err := jose.NewJWK("", "RS256", key)
+	require.NoError(t, err)
+	assert.Equal(t, jwk.N, roundTrip.N)
+	assert.Equal(t, jwk.E, roundTrip.E)
+
+	_, err = base64.RawURLEncoding.DecodeString(thumbprint)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,169 @@
+package jose_test
+
+import (
+	"docs-server/internal/app"
+	"docs-server/internal/jose"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClaims struct {
+	UserID string `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+func (c *testClaims) Registered() *jwt.RegisteredClaims {
+	return &c.RegisteredClaims
+}
+
+// fakeClock управляемый источник времени
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newIssuer(t *testing.T, clock *fakeClock, issuer string) *jose.TokenIssuer {
+	keys, err := jose.NewHMACKeySet([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+
+	tokens, err := jose.NewTokenIssuer(keys, jose.TokenConfig{
+		Issuer:     issuer,
+		Audience:   "docs-server",
+		TTL:        15 * time.Minute,
+		RefreshTTL: 24 * time.Hour,
+		Leeway:     30 * time.Second,
+		Clock:      clock.Now,
+	})
+	require.NoError(t, err)
+	return tokens
+}
+
+func TestTokenIssuer_IssueAndVerify(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	tokens := newIssuer(t, clock, "docs-server")
+
+	signed, expiresAt, err := tokens.Issue(&testClaims{UserID: "user-1"}, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, clock.now.Add(15*time.Minute), expiresAt)
+
+	var claims testClaims
+	require.NoError(t, tokens.Verify(signed, &claims, ""))
+	assert.Equal(t, "user-1", claims.UserID)
+	assert.Equal(t, "docs-server", claims.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{"docs-server"}, claims.Audience)
+	assert.NotEmpty(t, claims.ID)
+	assert.Equal(t, 24*time.Hour, tokens.RefreshTTL())
+
+	// Каждый токен получает свой jti
+	other, _, err := tokens.Issue(&testClaims{UserID: "user-1"}, "", 0)
+	require.NoError(t, err)
+	var otherClaims testClaims
+	require.NoError(t, tokens.Verify(other, &otherClaims, ""))
+	assert.NotEqual(t, claims.ID, otherClaims.ID)
+}
+
+func TestTokenIssuer_Audience(t *testing.T) {
+	tokens := newIssuer(t, &fakeClock{now: time.Now()}, "docs-server")
+
+	mfaToken, _, err := tokens.Issue(&testClaims{UserID: "user-1"}, "mfa", time.Minute)
+	require.NoError(t, err)
+
+	// Токен второго шага входа не принимается как access-токен и наоборот
+	err = tokens.Verify(mfaToken, &testClaims{}, "")
+	assert.ErrorIs(t, err, jose.ErrInvalidToken)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+	assert.NoError(t, tokens.Verify(mfaToken, &testClaims{}, "mfa"))
+
+	accessToken, _, err := tokens.Issue(&testClaims{UserID: "user-1"}, "", 0)
+	require.NoError(t, err)
+	assert.ErrorIs(t, tokens.Verify(accessToken, &testClaims{}, "mfa"), jwt.ErrTokenInvalidAudience)
+}
+
+func TestTokenIssuer_Issuer(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	staging := newIssuer(t, clock, "docs-server-staging")
+	production := newIssuer(t, clock, "docs-server")
+
+	signed, _, err := staging.Issue(&testClaims{UserID: "user-1"}, "", 0)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, production.Verify(signed, &testClaims{}, ""), jwt.ErrTokenInvalidIssuer)
+}
+
+func TestTokenIssuer_Leeway(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	tokens := newIssuer(t, clock, "docs-server")
+
+	signed, expiresAt, err := tokens.Issue(&testClaims{}, "", time.Minute)
+	require.NoError(t, err)
+
+	// Срок истек, но в пределах допустимого расхождения часов
+	clock.now = expiresAt.Add(20 * time.Second)
+	assert.NoError(t, tokens.Verify(signed, &testClaims{}, ""))
+	assert.False(t, tokens.Expired(expiresAt))
+
+	clock.now = expiresAt.Add(time.Minute)
+	err = tokens.Verify(signed, &testClaims{}, "")
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	assert.True(t, tokens.Expired(expiresAt))
+
+	// Токен, выпущенный сервером с убежавшими вперед часами
+	clock.now = time.Now().Add(5 * time.Minute)
+	future, _, err := tokens.Issue(&testClaims{}, "", 0)
+	require.NoError(t, err)
+	clock.now = time.Now()
+	assert.ErrorIs(t, tokens.Verify(future, &testClaims{}, ""), jwt.ErrTokenUsedBeforeIssued)
+}
+
+func TestNewTokenIssuer_InvalidConfig(t *testing.T) {
+	keys, err := jose.NewHMACKeySet([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	valid := jose.TokenConfig{Issuer: "docs-server", Audience: "docs-server", TTL: time.Minute, RefreshTTL: time.Hour}
+
+	_, err = jose.NewTokenIssuer(keys, valid)
+	assert.NoError(t, err)
+
+	_, err = jose.NewTokenIssuer(nil, valid)
+	assert.Error(t, err)
+
+	cfg := valid
+	cfg.Audience = ""
+	_, err = jose.NewTokenIssuer(keys, cfg)
+	assert.Error(t, err)
+
+	cfg = valid
+	cfg.TTL = 0
+	_, err = jose.NewTokenIssuer(keys, cfg)
+	assert.Error(t, err)
+
+	cfg = valid
+	cfg.Leeway = -time.Second
+	_, err = jose.NewTokenIssuer(keys, cfg)
+	assert.Error(t, err)
+}
+
+func TestConfig_GetJWTSecret(t *testing.T) {
+	raw := []byte("0123456789abcdef0123456789abcdef")
+	cfg := &app.Config{}
+
+	cfg.Auth.JWTSecret = base64.StdEncoding.EncodeToString(raw)
+	secret, err := cfg.GetJWTSecret()
+	require.NoError(t, err)
+	assert.Equal(t, raw, secret, "secret must be decoded, not used as raw base64 text")
+
+	cfg.Auth.JWTSecret = "not base64!"
+	_, err = cfg.GetJWTSecret()
+	assert.Error(t, err)
+
+	cfg.Auth.JWTSecret = base64.StdEncoding.EncodeToString([]byte("short"))
+	_, err = cfg.GetJWTSecret()
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package ldap_test
+
+import (
+	"docs-server/cmd/tests/testutils/fakeldap"
+	"docs-server/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startDirectory(t *testing.T) *fakeldap.Server {
+	srv, err := fakeldap.Start("cn=reader,dc=corp,dc=example", "reader-secret",
+		fakeldap.Entry{
+			DN:       "uid=alice,ou=people,dc=corp,dc=example",
+			Password: "alice-secret",
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"alice"},
+				"mail":        {"alice@corp.example"},
+				"memberOf":    {"cn=DocsAdmins,ou=groups,dc=corp,dc=example", "cn=Finance,ou=groups,dc=corp,dc=example"},
+			},
+		},
+		fakeldap.Entry{
+			DN:       "uid=bob,ou=people,dc=corp,dc=example",
+			Password: "bob-secret",
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"bob"},
+			},
+		},
+	)
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newAuthenticator(srv *fakeldap.Server, bindPassword string) *service.LDAPAuthenticator {
+	return service.NewLDAPAuthenticator(service.LDAPConfig{
+		URL:            srv.URL(),
+		BindDN:         "cn=reader,dc=corp,dc=example",
+		BindPassword:   bindPassword,
+		BaseDN:         "dc=corp,dc=example",
+		UserFilter:     "(&(objectClass=person)(uid=%s))",
+		LoginAttribute: "uid",
+		EmailAttribute: "mail",
+		GroupAttribute: "memberOf",
+		Timeout:        5 * time.Second,
+	}, nil, nil)
+}
+
+func TestLookup_BindAndSearch(t *testing.T) {
+	auth := newAuthenticator(startDirectory(t), "reader-secret")
+
+	identity, err := auth.Lookup("alice", "alice-secret")
+	require.NoError(t, err)
+	require.NotNil(t, identity)
+	assert.Equal(t, "uid=alice,ou=people,dc=corp,dc=example", identity.DN)
+	assert.Equal(t, "alice", identity.Login)
+	assert.Equal(t, "alice@corp.example", identity.Email)
+	assert.Len(t, identity.Groups, 2)
+}
+
+func TestLookup_Rejects(t *testing.T) {
+	auth := newAuthenticator(startDirectory(t), "reader-secret")
+
+	cases := map[string][2]string{
+		"wrong password":   {"alice", "bob-secret"},
+		"empty password":   {"alice", ""},
+		"unknown user":     {"carol", "alice-secret"},
+		"filter injection": {"*", "alice-secret"},
+	}
+	for name, c := range cases {
+		identity, err := auth.Lookup(c[0], c[1])
+		assert.NoError(t, err, name)
+		assert.Nil(t, identity, name)
+	}
+}
+
+func TestLookup_ServiceBindFailure(t *testing.T) {
+	auth := newAuthenticator(startDirectory(t), "wrong")
+
+	_, err := auth.Lookup("alice", "alice-secret")
+	assert.Error(t, err)
+}
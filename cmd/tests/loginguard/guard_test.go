@@ -0,0 +1,84 @@
+package loginguard_test
+
+import (
+	"context"
+	"docs-server/internal/cache"
+	"docs-server/internal/service"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGuard(policy service.LoginPolicy) *service.LoginGuard {
+	return service.NewLoginGuard(service.NewCacheAttemptStore(cache.NewMemoryCache()), policy)
+}
+
+func TestLoginGuard_ParallelAttemptsDoNotExceedLimit(t *testing.T) {
+	guard := newGuard(service.LoginPolicy{MaxAttempts: 5, LockoutDuration: time.Minute, Window: time.Hour})
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		allowed  int
+		rejected int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := guard.Attempt(context.Background(), "alice", "10.0.0.1")
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				allowed++
+			} else if errors.Is(err, service.ErrTooManyAttempts) {
+				rejected++
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, allowed, "only MaxAttempts attempts pass before the lockout")
+	assert.Equal(t, 45, rejected)
+
+	var lockout *service.LockoutError
+	require.ErrorAs(t, guard.Attempt(context.Background(), "alice", "10.0.0.2"), &lockout)
+	assert.Greater(t, lockout.RetryAfter, time.Duration(0))
+}
+
+func TestLoginGuard_ReleasedAttemptIsNotCounted(t *testing.T) {
+	guard := newGuard(service.LoginPolicy{MaxAttempts: 2, MaxAttemptsPerIP: 2, LockoutDuration: time.Minute, Window: time.Hour})
+	ctx := context.Background()
+
+	// Удачные попытки возвращаются и не приближают блокировку ни логина, ни IP
+	for i := 0; i < 5; i++ {
+		require.NoError(t, guard.Attempt(ctx, "alice", "10.0.0.1"))
+		require.NoError(t, guard.Release(ctx, "alice", "10.0.0.1"))
+	}
+
+	require.NoError(t, guard.Attempt(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, guard.Attempt(ctx, "alice", "10.0.0.1"))
+	assert.ErrorIs(t, guard.Attempt(ctx, "alice", "10.0.0.1"), service.ErrTooManyAttempts)
+
+	// Блокировка логина снимается администратором, блокировка IP остается
+	require.NoError(t, guard.Unlock(ctx, "alice"))
+	assert.ErrorIs(t, guard.Attempt(ctx, "alice", "10.0.0.1"), service.ErrTooManyAttempts)
+	assert.NoError(t, guard.Attempt(ctx, "alice", "10.0.0.2"))
+}
+
+func TestLoginGuard_DelayAppliesToParallelAttempts(t *testing.T) {
+	guard := newGuard(service.LoginPolicy{FreeAttempts: 1, BaseDelay: time.Minute, Window: time.Hour})
+	ctx := context.Background()
+
+	require.NoError(t, guard.Attempt(ctx, "alice", "10.0.0.1"))
+	require.NoError(t, guard.Attempt(ctx, "alice", "10.0.0.1"))
+
+	// Вторая неудача уже учтена, поэтому следующая попытка ждет задержку
+	var lockout *service.LockoutError
+	require.ErrorAs(t, guard.Attempt(ctx, "alice", "10.0.0.1"), &lockout)
+	assert.Greater(t, lockout.RetryAfter, 50*time.Second)
+}
@@ -0,0 +1,142 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"docs-server/internal/notify"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTP минимальный SMTP-сервер, принимающий одно письмо
+type fakeSMTP struct {
+	listener net.Listener
+	from     string
+	rcpt     []string
+	data     string
+	done     chan struct{}
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &fakeSMTP{listener: listener, done: make(chan struct{})}
+	go srv.serve()
+	t.Cleanup(func() { listener.Close() })
+	return srv
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = strings.Trim(strings.Fields(line[len("MAIL FROM:"):])[0], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.rcpt = append(s.rcpt, strings.Trim(strings.Fields(line[len("RCPT TO:"):])[0], "<>"))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.data = data.String()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPNotifier_Send(t *testing.T) {
+	srv := startFakeSMTP(t)
+	notifier := notify.NewSMTPNotifier("127.0.0.1", srv.port(), "", "", "docs@example.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := notifier.Send(ctx, &notify.Message{
+		To:      "user@example.com",
+		Subject: "Сброс пароля",
+		Body:    "line one\nline two",
+	})
+	require.NoError(t, err)
+	<-srv.done
+
+	assert.Equal(t, "docs@example.com", srv.from)
+	assert.Equal(t, []string{"user@example.com"}, srv.rcpt)
+	assert.Contains(t, srv.data, "To: user@example.com\r\n")
+	assert.Contains(t, srv.data, "Subject: =?UTF-8?q?")
+	assert.Contains(t, srv.data, "Content-Type: text/plain; charset=UTF-8\r\n")
+	assert.Contains(t, srv.data, "\r\n\r\nline one\r\nline two")
+}
+
+func TestSMTPNotifier_HeaderInjection(t *testing.T) {
+	srv := startFakeSMTP(t)
+	notifier := notify.NewSMTPNotifier("127.0.0.1", srv.port(), "", "", "docs@example.com")
+
+	err := notifier.Send(context.Background(), &notify.Message{
+		To:      "user@example.com",
+		Subject: "Hello\r\nBcc: attacker@example.com",
+		Body:    "body",
+	})
+	require.NoError(t, err)
+	<-srv.done
+
+	assert.NotContains(t, srv.data, "\r\nBcc:")
+	assert.Equal(t, []string{"user@example.com"}, srv.rcpt)
+}
+
+func TestSMTPNotifier_ConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	notifier := notify.NewSMTPNotifier("127.0.0.1", port, "", "", "docs@example.com")
+	err = notifier.Send(context.Background(), &notify.Message{To: "user@example.com", Subject: "s", Body: "b"})
+	assert.Error(t, err)
+}
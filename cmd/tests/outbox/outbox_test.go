@@ -0,0 +1,292 @@
+package outbox_test
+
+import (
+	"context"
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+	"docs-server/internal/sink"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore сообщения outbox в памяти
+type memoryStore struct {
+	mu       sync.Mutex
+	messages []*model.OutboxMessage
+}
+
+func (s *memoryStore) add(messages []*model.OutboxMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, message := range messages {
+		message.ID = int64(len(s.messages) + 1)
+		s.messages = append(s.messages, message)
+	}
+}
+
+func (s *memoryStore) ClaimOutboxMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []*model.OutboxMessage
+	for _, message := range s.messages {
+		if len(claimed) < limit && message.Status == model.OutboxPending && !message.NextAttemptAt.After(now) {
+			message.NextAttemptAt = now.Add(lease)
+			claimed = append(claimed, message)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) UpdateOutboxMessage(ctx context.Context, message *model.OutboxMessage) error {
+	return nil
+}
+
+func (s *memoryStore) DeletePublishedOutboxMessages(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var kept []*model.OutboxMessage
+	for _, message := range s.messages {
+		if message.Status != model.OutboxPublished || !message.PublishedAt.Before(before) {
+			kept = append(kept, message)
+		}
+	}
+	deleted := int64(len(s.messages) - len(kept))
+	s.messages = kept
+	return deleted, nil
+}
+
+// flakySink приемник, отклоняющий первые failures публикаций
+type flakySink struct {
+	failures  int
+	published []string
+}
+
+func (s *flakySink) Publish(ctx context.Context, event *model.DocumentEvent) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	s.published = append(s.published, event.ID)
+	return nil
+}
+
+func event(id string) *model.DocumentEvent {
+	return &model.DocumentEvent{
+		ID:         id,
+		Type:       model.EventDocumentCreated,
+		Time:       time.Now().UTC(),
+		DocumentID: "doc-1",
+		Owner:      "user-1",
+		Document:   &model.Document{ID: "doc-1", Name: "report.pdf"},
+	}
+}
+
+func TestOutbox_RetryIsIndependentPerSink(t *testing.T) {
+	store := &memoryStore{}
+	healthy, flaky := &flakySink{}, &flakySink{failures: 1}
+	outbox := service.NewOutbox(store, map[string]sink.Sink{"healthy": healthy, "flaky": flaky},
+		service.OutboxPolicy{Timeout: time.Second, MaxAttempts: 3, Retention: time.Hour})
+
+	messages, err := outbox.Messages(event("event-1"))
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	store.add(messages)
+
+	now := time.Now()
+	published, err := outbox.RelayDue(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{"event-1"}, healthy.published)
+	assert.Empty(t, flaky.published)
+
+	// Повтор откладывается и затрагивает только отказавший приемник
+	published, err = outbox.RelayDue(context.Background(), now)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+
+	published, err = outbox.RelayDue(context.Background(), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, []string{"event-1"}, healthy.published)
+	assert.Equal(t, []string{"event-1"}, flaky.published)
+
+	for _, message := range store.messages {
+		assert.Equal(t, model.OutboxPublished, message.Status, message.Sink)
+		assert.Empty(t, message.LastError)
+	}
+
+	// Опубликованные сообщения удаляются по истечении срока хранения
+	deleted, err := outbox.Cleanup(context.Background(), now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+}
+
+func TestOutbox_FailsAfterMaxAttempts(t *testing.T) {
+	store := &memoryStore{}
+	outbox := service.NewOutbox(store, map[string]sink.Sink{"flaky": &flakySink{failures: 10}},
+		service.OutboxPolicy{Timeout: time.Second, MaxAttempts: 2, Retention: time.Hour})
+
+	messages, err := outbox.Messages(event("event-1"))
+	require.NoError(t, err)
+	store.add(messages)
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := outbox.RelayDue(context.Background(), now.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+	}
+
+	message := store.messages[0]
+	assert.Equal(t, model.OutboxFailed, message.Status)
+	assert.Equal(t, 2, message.Attempts)
+	assert.Equal(t, "sink unavailable", message.LastError)
+}
+
+func TestOutbox_UnknownSink(t *testing.T) {
+	store := &memoryStore{}
+	outbox := service.NewOutbox(store, nil, service.OutboxPolicy{Timeout: time.Second, MaxAttempts: 5})
+	store.add([]*model.OutboxMessage{{
+		Sink:          "removed",
+		EventID:       "event-1",
+		EventType:     model.EventDocumentCreated,
+		Payload:       json.RawMessage(`{"id":"event-1"}`),
+		Status:        model.OutboxPending,
+		NextAttemptAt: time.Now(),
+	}})
+
+	published, err := outbox.RelayDue(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, published)
+	assert.Equal(t, model.OutboxPending, store.messages[0].Status, "message waits for the sink to return")
+	assert.Contains(t, store.messages[0].LastError, "not configured")
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	fileSink := sink.NewFileSink(path)
+
+	require.NoError(t, fileSink.Publish(context.Background(), event("event-1")))
+	require.NoError(t, fileSink.Publish(context.Background(), event("event-2")))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var received model.DocumentEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &received))
+	assert.Equal(t, "event-2", received.ID)
+	assert.Equal(t, "report.pdf", received.Document.Name)
+}
+
+// startNATS запускает встроенный сервер NATS с JetStream и потоком DOCS для тем docs.events.>
+func startNATS(t *testing.T) string {
+	t.Helper()
+	srv, err := server.NewServer(&server.Options{
+		Host:          "127.0.0.1",
+		Port:          -1,
+		JetStream:     true,
+		StoreDir:      t.TempDir(),
+		Authorization: "secret-token",
+		NoLog:         true,
+		NoSigs:        true,
+	})
+	require.NoError(t, err)
+	go srv.Start()
+	t.Cleanup(srv.Shutdown)
+	require.True(t, srv.ReadyForConnections(5*time.Second))
+
+	conn, err := nats.Connect(srv.ClientURL(), nats.Token("secret-token"))
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+	js, err := conn.JetStream()
+	require.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{Name: "DOCS", Subjects: []string{"docs.events.>"}, Duplicates: time.Hour})
+	require.NoError(t, err)
+
+	return srv.ClientURL()
+}
+
+func streamMessages(t *testing.T, url string) (uint64, *nats.RawStreamMsg) {
+	t.Helper()
+	conn, err := nats.Connect(url, nats.Token("secret-token"))
+	require.NoError(t, err)
+	defer conn.Close()
+	js, err := conn.JetStream()
+	require.NoError(t, err)
+
+	info, err := js.StreamInfo("DOCS")
+	require.NoError(t, err)
+	if info.State.Msgs == 0 {
+		return 0, nil
+	}
+	first, err := js.GetMsg("DOCS", info.State.FirstSeq)
+	require.NoError(t, err)
+	return info.State.Msgs, first
+}
+
+func TestNATSSink(t *testing.T) {
+	url := startNATS(t)
+	natsSink := sink.NewNATSSink(sink.NATSConfig{
+		URL:     url,
+		Subject: "docs.events",
+		Token:   "secret-token",
+		Timeout: 5 * time.Second,
+	})
+	defer natsSink.Close()
+
+	require.NoError(t, natsSink.Publish(context.Background(), event("event-1")))
+	require.NoError(t, natsSink.Publish(context.Background(), event("event-2")))
+	// Повторная публикация после сбоя relay подтверждается, но JetStream ее отбрасывает по Nats-Msg-Id
+	require.NoError(t, natsSink.Publish(context.Background(), event("event-1")))
+
+	count, message := streamMessages(t, url)
+	assert.Equal(t, uint64(2), count)
+	require.NotNil(t, message)
+	assert.Equal(t, "docs.events."+model.EventDocumentCreated, message.Subject)
+	assert.Equal(t, "event-1", message.Header.Get(nats.MsgIdHdr))
+	var received model.DocumentEvent
+	require.NoError(t, json.Unmarshal(message.Data, &received))
+	assert.Equal(t, "event-1", received.ID)
+}
+
+func TestNATSSink_RequiresStreamAck(t *testing.T) {
+	url := startNATS(t)
+
+	// Тема вне потока: core NATS принимает сообщение, но без PubAck оно не считается опубликованным
+	natsSink := sink.NewNATSSink(sink.NATSConfig{URL: url, Subject: "other.events", Token: "secret-token", Timeout: time.Second})
+	defer natsSink.Close()
+	assert.Error(t, natsSink.Publish(context.Background(), event("event-1")))
+
+	count, _ := streamMessages(t, url)
+	assert.Zero(t, count)
+}
+
+func TestNATSSink_AuthFailure(t *testing.T) {
+	url := startNATS(t)
+	natsSink := sink.NewNATSSink(sink.NATSConfig{URL: url, Subject: "docs.events", Token: "wrong", Timeout: time.Second})
+	defer natsSink.Close()
+	assert.Error(t, natsSink.Publish(context.Background(), event("event-1")))
+}
+
+func TestNATSSink_Unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	natsSink := sink.NewNATSSink(sink.NATSConfig{URL: "nats://" + addr, Subject: "docs.events", Timeout: time.Second})
+	assert.Error(t, natsSink.Publish(context.Background(), event("event-1")))
+}
@@ -0,0 +1,123 @@
+package password_test
+
+import (
+	"docs-server/internal/app"
+	"docs-server/internal/password"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Параметры меньше рекомендуемых, чтобы тесты шли быстро
+var (
+	fastArgon2id = password.Argon2id{Memory: 64, Time: 1, Threads: 1}
+	fastBcrypt   = password.Bcrypt{Cost: bcrypt.MinCost}
+)
+
+func TestArgon2id_HashAndVerify(t *testing.T) {
+	hashed, err := fastArgon2id.Hash("Secret123")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=64,t=1,p=1$"), hashed)
+
+	assert.True(t, fastArgon2id.Verify(hashed, "Secret123"))
+	assert.False(t, fastArgon2id.Verify(hashed, "Secret124"))
+	assert.False(t, fastArgon2id.NeedsRehash(hashed))
+
+	// Соль случайная: хеши одного пароля различаются
+	again, err := fastArgon2id.Hash("Secret123")
+	require.NoError(t, err)
+	assert.NotEqual(t, hashed, again)
+}
+
+func TestArgon2id_VerifyUsesEncodedParams(t *testing.T) {
+	hashed, err := fastArgon2id.Hash("Secret123")
+	require.NoError(t, err)
+
+	stronger := password.Argon2id{Memory: 128, Time: 2, Threads: 1}
+	assert.True(t, stronger.Verify(hashed, "Secret123"))
+	assert.True(t, stronger.NeedsRehash(hashed))
+}
+
+func TestArgon2id_MalformedHash(t *testing.T) {
+	for _, hashed := range []string{
+		"",
+		"!",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+	} {
+		assert.False(t, fastArgon2id.Verify(hashed, ""), hashed)
+	}
+}
+
+func TestBcrypt_HashAndVerify(t *testing.T) {
+	hashed, err := fastBcrypt.Hash("Secret123")
+	require.NoError(t, err)
+
+	assert.True(t, fastBcrypt.Verify(hashed, "Secret123"))
+	assert.False(t, fastBcrypt.Verify(hashed, "Secret124"))
+	assert.False(t, fastBcrypt.NeedsRehash(hashed))
+	assert.True(t, password.Bcrypt{Cost: bcrypt.MinCost + 1}.NeedsRehash(hashed))
+}
+
+func TestPolicy_MigratesFromBcrypt(t *testing.T) {
+	legacy, err := fastBcrypt.Hash("Secret123")
+	require.NoError(t, err)
+
+	hasher := password.New(fastArgon2id, fastBcrypt)
+	assert.True(t, hasher.Verify(legacy, "Secret123"))
+	assert.True(t, hasher.NeedsRehash(legacy))
+
+	hashed, err := hasher.Hash("Secret123")
+	require.NoError(t, err)
+	assert.True(t, fastArgon2id.Matches(hashed))
+	assert.True(t, hasher.Verify(hashed, "Secret123"))
+	assert.False(t, hasher.NeedsRehash(hashed))
+}
+
+func TestPolicy_UnknownHash(t *testing.T) {
+	hasher := password.New(fastArgon2id)
+
+	legacy, err := fastBcrypt.Hash("Secret123")
+	require.NoError(t, err)
+	assert.False(t, hasher.Verify(legacy, "Secret123"), "bcrypt is not accepted without legacy algorithm")
+
+	// Пользователи без локального пароля
+	assert.False(t, hasher.Verify("!", "!"))
+	assert.False(t, hasher.NeedsRehash("!"))
+}
+
+func TestConfig_NewPasswordHasher(t *testing.T) {
+	cfg, err := app.NewConfig()
+	require.NoError(t, err)
+
+	cfg.PasswordHashing.Argon2id.Memory = 64
+	cfg.PasswordHashing.Argon2id.Time = 1
+	cfg.PasswordHashing.BcryptCost = bcrypt.MinCost
+
+	cfg.PasswordHashing.Algorithm = "bcrypt"
+	hasher, err := cfg.NewPasswordHasher()
+	require.NoError(t, err)
+	hashed, err := hasher.Hash("Secret123")
+	require.NoError(t, err)
+	assert.True(t, fastBcrypt.Matches(hashed))
+
+	cfg.PasswordHashing.Algorithm = "argon2id"
+	hasher, err = cfg.NewPasswordHasher()
+	require.NoError(t, err)
+	assert.True(t, hasher.Verify(hashed, "Secret123"))
+	assert.True(t, hasher.NeedsRehash(hashed))
+
+	cfg.PasswordHashing.Algorithm = "scrypt"
+	_, err = cfg.NewPasswordHasher()
+	assert.Error(t, err)
+
+	cfg.PasswordHashing.Algorithm = "bcrypt"
+	cfg.PasswordHashing.BcryptCost = 100
+	_, err = cfg.NewPasswordHasher()
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package policy_test
+
+import (
+	"crypto/sha1"
+	"docs-server/internal/app"
+	"docs-server/internal/controller"
+	"docs-server/internal/service"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func assertRule(t *testing.T, err error, field, rule string) {
+	t.Helper()
+
+	var validationErr *service.ValidationError
+	require.True(t, errors.As(err, &validationErr), "expected validation error, got %v", err)
+	assert.Equal(t, field, validationErr.Field)
+	assert.Equal(t, rule, validationErr.Rule)
+}
+
+func TestDefaultPolicy_Login(t *testing.T) {
+	policy := service.DefaultCredentialPolicy()
+
+	assert.NoError(t, policy.ValidateLogin("testuser01"))
+	assertRule(t, policy.ValidateLogin("short"), "login", "min_length")
+	assertRule(t, policy.ValidateLogin(strings.Repeat("a", 51)), "login", "max_length")
+	assertRule(t, policy.ValidateLogin("test.user01"), "login", "charset")
+	assertRule(t, policy.ValidateLogin("пользователь"), "login", "charset")
+	assertRule(t, policy.ValidateLogin("user@example.com"), "login", "charset")
+}
+
+func TestPolicy_LoginCharset(t *testing.T) {
+	policy := service.DefaultCredentialPolicy()
+	policy.LoginCharset = "unicode"
+	policy.LoginSymbols = "._-"
+
+	assert.NoError(t, policy.ValidateLogin("пользователь"))
+	assert.NoError(t, policy.ValidateLogin("test.user-01"))
+	assertRule(t, policy.ValidateLogin("test user01"), "login", "charset")
+}
+
+func TestPolicy_EmailLogins(t *testing.T) {
+	policy := service.DefaultCredentialPolicy()
+	policy.EmailLogins = true
+
+	assert.NoError(t, policy.ValidateLogin("user@example.com"))
+	assert.NoError(t, policy.ValidateLogin("testuser01"))
+	assertRule(t, policy.ValidateLogin("user@@example.com"), "login", "charset")
+	assertRule(t, policy.ValidateLogin("Name <user@example.com>"), "login", "charset")
+}
+
+func TestDefaultPolicy_Password(t *testing.T) {
+	policy := service.DefaultCredentialPolicy()
+
+	assert.NoError(t, policy.ValidatePassword("Secur3!Pass"))
+	assertRule(t, policy.ValidatePassword("S3!a"), "password", "min_length")
+	assertRule(t, policy.ValidatePassword("secur3!pass"), "password", "uppercase")
+	assertRule(t, policy.ValidatePassword("SECUR3!PASS"), "password", "lowercase")
+	assertRule(t, policy.ValidatePassword("Secure!Pass"), "password", "digit")
+	assertRule(t, policy.ValidatePassword("Secur3Pass"), "password", "special")
+}
+
+func TestPolicy_PasswordClasses(t *testing.T) {
+	policy := &service.CredentialPolicy{PasswordMinLength: 12, PasswordMaxLength: 16}
+
+	assert.NoError(t, policy.ValidatePassword("correct horse"))
+	assertRule(t, policy.ValidatePassword("correct"), "password", "min_length")
+	assertRule(t, policy.ValidatePassword("correct horse battery"), "password", "max_length")
+}
+
+func TestPolicy_Blocklist(t *testing.T) {
+	digest := sha1.Sum([]byte("Winter2024!"))
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join([]string{
+		"# пароли из утечек",
+		"P@ssw0rd123",
+		strings.ToUpper(hex.EncodeToString(digest[:])) + ":42",
+		"",
+	}, "\n")), 0o600))
+
+	policy := service.DefaultCredentialPolicy()
+	require.NoError(t, policy.LoadBlocklist(path))
+	assert.Equal(t, 2, policy.BlocklistSize())
+
+	assertRule(t, policy.ValidatePassword("P@ssw0rd123"), "password", "breached")
+	assertRule(t, policy.ValidatePassword("p@SSW0RD123"), "password", "breached")
+	assertRule(t, policy.ValidatePassword("Winter2024!"), "password", "breached")
+	assert.NoError(t, policy.ValidatePassword("Summer2024!"))
+
+	assert.Error(t, policy.LoadBlocklist(filepath.Join(t.TempDir(), "missing.txt")))
+}
+
+func TestConfig_NewCredentialPolicy(t *testing.T) {
+	cfg, err := app.NewConfig()
+	require.NoError(t, err)
+
+	policy, err := cfg.NewCredentialPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, service.DefaultCredentialPolicy(), policy)
+
+	cfg.CredentialPolicy.Login.Charset = "latin1"
+	_, err = cfg.NewCredentialPolicy()
+	assert.Error(t, err)
+
+	cfg.CredentialPolicy.Login.Charset = "ascii"
+	cfg.CredentialPolicy.Login.MaxLength = 300
+	_, err = cfg.NewCredentialPolicy()
+	assert.Error(t, err)
+
+	cfg.CredentialPolicy.Login.MaxLength = 50
+	cfg.CredentialPolicy.Password.BlocklistFile = filepath.Join(t.TempDir(), "missing.txt")
+	_, err = cfg.NewCredentialPolicy()
+	assert.Error(t, err)
+}
+
+func TestErrorHandler_ValidationError(t *testing.T) {
+	application := fiber.New()
+	application.Use(controller.UnifiedErrorHandler)
+	application.Post("/users", func(c *fiber.Ctx) error {
+		return service.DefaultCredentialPolicy().ValidatePassword("short")
+	})
+
+	resp, err := application.Test(httptest.NewRequest("POST", "/users", nil))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var result struct {
+		Data struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Field   string `json:"field"`
+			Rule    string `json:"rule"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, http.StatusBadRequest, result.Data.Code)
+	assert.Equal(t, "password", result.Data.Field)
+	assert.Equal(t, "min_length", result.Data.Rule)
+	assert.NotEmpty(t, result.Data.Message)
+}
@@ -0,0 +1,220 @@
+// Package fakeldap минимальный LDAP-сервер для тестов: простая привязка и поиск
+// с фильтрами and/or/not, равенства и присутствия атрибута
+package fakeldap
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+const (
+	appBindRequest    = 0
+	appBindResponse   = 1
+	appUnbindRequest  = 2
+	appSearchRequest  = 3
+	appSearchEntry    = 4
+	appSearchDone     = 5
+	resultSuccess     = 0
+	resultInvalidCred = 49
+	resultUnwilling   = 53
+)
+
+// Entry запись каталога. Password - пароль для привязки от имени записи
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// Server LDAP-сервер на случайном локальном порту
+type Server struct {
+	BindDN       string
+	BindPassword string
+
+	listener net.Listener
+	entries  []Entry
+	wg       sync.WaitGroup
+}
+
+func Start(bindDN, bindPassword string, entries ...Entry) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		BindDN:       bindDN,
+		BindPassword: bindPassword,
+		listener:     listener,
+		entries:      entries,
+	}
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// URL адрес сервера для ldap.DialURL
+func (s *Server) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case appBindRequest:
+			s.write(conn, messageID, s.bind(op))
+		case appSearchRequest:
+			for _, entry := range s.search(op) {
+				s.write(conn, messageID, entry)
+			}
+			s.write(conn, messageID, result(appSearchDone, resultSuccess, ""))
+		case appUnbindRequest:
+			return
+		default:
+			s.write(conn, messageID, result(appBindResponse, resultUnwilling, "operation not supported"))
+		}
+	}
+}
+
+func (s *Server) bind(op *ber.Packet) *ber.Packet {
+	if len(op.Children) < 3 {
+		return result(appBindResponse, resultUnwilling, "malformed bind")
+	}
+	name := op.Children[1].Data.String()
+	password := op.Children[2].Data.String()
+
+	if password == "" {
+		// Анонимная привязка (RFC 4513, 5.1.2)
+		return result(appBindResponse, resultSuccess, "")
+	}
+	if name == s.BindDN && password == s.BindPassword {
+		return result(appBindResponse, resultSuccess, "")
+	}
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, name) && entry.Password == password {
+			return result(appBindResponse, resultSuccess, "")
+		}
+	}
+	return result(appBindResponse, resultInvalidCred, "invalid credentials")
+}
+
+func (s *Server) search(op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return nil
+	}
+	baseDN := strings.ToLower(op.Children[0].Data.String())
+	filter := op.Children[6]
+
+	var found []*ber.Packet
+	for _, entry := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.DN), baseDN) || !matches(filter, entry) {
+			continue
+		}
+
+		attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+		for name, values := range entry.Attributes {
+			attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+			attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+			for _, value := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+			}
+			attribute.AppendChild(set)
+			attributes.AppendChild(attribute)
+		}
+
+		response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, appSearchEntry, nil, "search entry")
+		response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "dn"))
+		response.AppendChild(attributes)
+		found = append(found, response)
+	}
+	return found
+}
+
+// matches вычисляет фильтр поиска для записи
+func matches(filter *ber.Packet, entry Entry) bool {
+	switch filter.Tag {
+	case 0: // and
+		for _, child := range filter.Children {
+			if !matches(child, entry) {
+				return false
+			}
+		}
+		return true
+	case 1: // or
+		for _, child := range filter.Children {
+			if matches(child, entry) {
+				return true
+			}
+		}
+		return false
+	case 2: // not
+		return len(filter.Children) == 1 && !matches(filter.Children[0], entry)
+	case 3: // equalityMatch
+		if len(filter.Children) != 2 {
+			return false
+		}
+		for _, value := range attribute(entry, filter.Children[0].Data.String()) {
+			if strings.EqualFold(value, filter.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	case 7: // present
+		return len(attribute(entry, filter.Data.String())) > 0
+	}
+	return false
+}
+
+func attribute(entry Entry, name string) []string {
+	for key, values := range entry.Attributes {
+		if strings.EqualFold(key, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+func result(tag ber.Tag, code int64, message string) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "result")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "diagnosticMessage"))
+	return response
+}
+
+func (s *Server) write(conn net.Conn, messageID int64, op *ber.Packet) {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP message")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "messageID"))
+	envelope.AppendChild(op)
+	conn.Write(envelope.Bytes())
+}
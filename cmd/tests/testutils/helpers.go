@@ -2,15 +2,21 @@ package testutils
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"docs-server/cmd/tests/testutils/fakeldap"
 	"docs-server/internal/app"
 	"docs-server/internal/cache"
 	"docs-server/internal/controller"
 	"docs-server/internal/repository"
 	"docs-server/internal/service"
 	"encoding/json"
+	"encoding/pem"
 	"log"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/gofiber/fiber/v2"
@@ -19,12 +25,22 @@ import (
 )
 
 var (
-	TestApp    *fiber.App
-	TestToken  string
-	TestToken2 string
-	TestLogin  = "testuser" // Фиксированный логин для тестов
-	TestLogin2 = "testuser1"
-	TestPass   = "Secur3P@ss"
+	TestApp            *fiber.App
+	TestConfig         *app.Config
+	TestDocService     *service.DocumentService
+	TestDocRepo        *repository.DocumentRepository
+	TestWebhookService *service.WebhookService
+	TestEventStream    *service.EventStream
+	TestOutbox         *service.Outbox
+	TestNotifier       = &RecordingNotifier{}
+	TestOIDC           = NewMockOIDCProvider()
+	TestLDAP           = startTestLDAP()
+	TestToken          string
+	TestToken2         string
+	TestLogin          = "testuser" // Фиксированный логин для тестов
+	TestLogin2         = "testuser1"
+	TestPass           = "Secur3P@ss"
+	LDAPShadowPass     = "Shad0wDir!" // Пароль ldapshadow01 в каталоге, отличается от локального
 
 	initOnce sync.Once
 )
@@ -35,12 +51,68 @@ func init() {
 		TestApp = createTestApp()
 		// Регистрация и аутентификация тестового пользователя
 		registerTestUser(TestApp)
-		registerTestListUser(TestApp)
 		TestToken = getTestToken(TestLogin, TestApp)
+		registerTestListUser(TestApp, TestToken)
 		TestToken2 = getTestToken(TestLogin2, TestApp)
 	})
 }
 
+// startTestLDAP каталог с пользователями ldapuser01 и ldapadmin01 (член группы DocsAdmins), пароль TestPass,
+// и ldapshadow01 (член DocsAdmins, пароль LDAPShadowPass) с логином локального пользователя
+func startTestLDAP() *fakeldap.Server {
+	srv, err := fakeldap.Start("cn=reader,dc=corp,dc=example", "reader-secret",
+		fakeldap.Entry{
+			DN:       "uid=ldapuser01,ou=people,dc=corp,dc=example",
+			Password: TestPass,
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"ldapuser01"},
+				"mail":        {"ldapuser01@corp.example"},
+			},
+		},
+		fakeldap.Entry{
+			DN:       "uid=ldapadmin01,ou=people,dc=corp,dc=example",
+			Password: TestPass,
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"ldapadmin01"},
+				"memberOf":    {"cn=DocsAdmins,ou=groups,dc=corp,dc=example"},
+			},
+		},
+		fakeldap.Entry{
+			DN:       "uid=ldapshadow01,ou=people,dc=corp,dc=example",
+			Password: LDAPShadowPass,
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {"ldapshadow01"},
+				"memberOf":    {"cn=DocsAdmins,ou=groups,dc=corp,dc=example"},
+			},
+		},
+	)
+	if err != nil {
+		log.Fatalf("Failed to start test LDAP server: %v", err)
+	}
+	return srv
+}
+
+// writeTestSigningKey создает RSA-ключ подписи access-токенов во временном файле
+func writeTestSigningKey() string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatalf("Failed to generate signing key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		log.Fatalf("Failed to encode signing key: %v", err)
+	}
+
+	path := filepath.Join(os.TempDir(), "docs-server-test-jwt.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		log.Fatalf("Failed to write signing key: %v", err)
+	}
+	return path
+}
+
 func Cleanup() {
 	os.RemoveAll("./test_uploads")
 }
@@ -50,14 +122,82 @@ func createTestApp() *fiber.App {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	cfg.OIDC.Issuer = TestOIDC.URL()
+	cfg.OIDC.ClientID = TestOIDC.ClientID
+	cfg.OIDC.ClientSecret = TestOIDC.ClientSecret
+	cfg.OIDC.RedirectURL = "http://localhost/api/auth/oidc/callback"
+	cfg.Auth.Backends = []string{"local", "ldap"}
+	cfg.LDAP.URL = TestLDAP.URL()
+	cfg.LDAP.BindDN = TestLDAP.BindDN
+	cfg.LDAP.BindPassword = TestLDAP.BindPassword
+	cfg.LDAP.BaseDN = "dc=corp,dc=example"
+	cfg.LDAP.AdminGroups = []string{"DocsAdmins"}
+	cfg.JWT.SigningKey = writeTestSigningKey()
+	cfg.Webhooks.AllowPrivate = true // Получатели в тестах слушают на loopback
 
 	userRepo := repository.NewUserRepository(cfg.Database.DSN)
 	docRepo := repository.NewDocumentRepository(cfg.Database.DSN)
+	sessionRepo := repository.NewSessionRepository(cfg.Database.DSN)
+	apiKeyRepo := repository.NewAPIKeyRepository(cfg.Database.DSN)
+	auditRepo := repository.NewAuditRepository(cfg.Database.DSN)
+	webhookRepo := repository.NewWebhookRepository(cfg.Database.DSN)
 	cache := cache.NewMemoryCache()
 
-	authService := service.NewAuthService(userRepo, cfg.Auth.AdminToken, []byte(cfg.Auth.JWTSecret), cache)
-	docService := service.NewDocumentService(docRepo, userRepo, cache, cfg.Storage.UploadDir)
-	userService := service.NewUserService(userRepo)
+	hasher, err := cfg.NewPasswordHasher()
+	if err != nil {
+		log.Fatalf("Failed to configure password hashing: %v", err)
+	}
+	policy, err := cfg.NewCredentialPolicy()
+	if err != nil {
+		log.Fatalf("Failed to configure credential policy: %v", err)
+	}
+	authenticators, err := cfg.NewAuthenticators(userRepo, hasher, policy)
+	if err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
+	}
+	tokens, err := cfg.NewTokenIssuer()
+	if err != nil {
+		log.Fatalf("Failed to configure JWT: %v", err)
+	}
+	denylist := cfg.NewTokenDenylist()
+	authService := service.NewAuthService(userRepo, sessionRepo, apiKeyRepo, cfg.Auth.AdminToken, hasher, policy, tokens, denylist, cache, service.PasswordReset{
+		Notifier: TestNotifier,
+		TTL:      cfg.PasswordReset.TTL,
+		URL:      cfg.PasswordReset.URL,
+	}, cfg.NewLoginGuard(cache), service.MFAPolicy{
+		Required:     cfg.MFA.Required,
+		Issuer:       cfg.MFA.Issuer,
+		ChallengeTTL: cfg.MFA.ChallengeTTL,
+	}, cfg.NewOIDCLogin(), authenticators)
+	webhookService := service.NewWebhookService(webhookRepo, service.WebhookPolicy{
+		Timeout:      cfg.Webhooks.Timeout,
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		AllowPrivate: cfg.Webhooks.AllowPrivate,
+	})
+	eventStream := cfg.NewEventStream()
+	outbox, err := cfg.NewOutbox(webhookService, eventStream)
+	if err != nil {
+		log.Fatalf("Failed to configure outbox: %v", err)
+	}
+	docService := service.NewDocumentService(docRepo, userRepo, cache, cfg.Storage.UploadDir, service.Quota{
+		MaxBytes:     cfg.Quota.MaxBytes,
+		MaxDocuments: cfg.Quota.MaxDocuments,
+	}, service.RetentionPolicy{
+		Default: cfg.Retention.Default,
+		ByMime:  cfg.Retention.ByMime,
+		ByUser:  cfg.Retention.ByUser,
+	}, outbox)
+	userService := service.NewUserService(userRepo, authService, docService)
+	auditService := service.NewAuditService(auditRepo)
+
+	// Публикация и доставка выполняются тестами через TestOutbox.RelayDue и TestWebhookService.DeliverDue
+
+	TestConfig = cfg
+	TestWebhookService = webhookService
+	TestOutbox = outbox
+	TestEventStream = eventStream
+	TestDocService = docService
+	TestDocRepo = docRepo
 
 	application := fiber.New(fiber.Config{
 		ErrorHandler: app.ErrorHandler,
@@ -65,27 +205,96 @@ func createTestApp() *fiber.App {
 
 	application.Use(recover.New())
 	application.Use(logger.New())
+	application.Use(controller.UnifiedErrorHandler)
+
+	authController := controller.NewAuthController(authService, auditService)
+	docsController := controller.NewDocsController(docService, userService, auditService)
+	adminController := controller.NewAdminController(userService, authService)
+	accountController := controller.NewAccountController(userService, authService)
+	auditController := controller.NewAuditController(auditService)
+	webhookController := controller.NewWebhookController(webhookService, false)
+	adminWebhookController := controller.NewWebhookController(webhookService, true)
+	eventsController := controller.NewEventsController(eventStream, cfg.Events.Heartbeat)
 
-	authController := controller.NewAuthController(authService)
-	docsController := controller.NewDocsController(docService, userService)
+	application.Get("/.well-known/jwks.json", authController.JWKS)
 
 	api := application.Group("/api")
+	requireAuth := controller.AuthMiddleware(authService, cfg.Auth.AllowRawToken)
 
 	api.Post("/register", authController.Register)
 	api.Post("/auth", authController.Authenticate)
-	api.Delete("/auth/:token", authController.Logout)
+	api.Post("/auth/refresh", authController.Refresh)
+	api.Post("/auth/mfa", authController.CompleteMFA)
+	api.Post("/auth/mfa/enroll", authController.BeginMFAEnrollment)
+	api.Get("/auth/oidc/login", authController.OIDCLogin)
+	api.Get("/auth/oidc/callback", authController.OIDCCallback)
+	api.Post("/auth/password-reset", authController.RequestPasswordReset)
+	api.Post("/auth/password-reset/confirm", authController.ResetPassword)
+	api.Delete("/auth", requireAuth, controller.SessionOnly, authController.Logout)
 
-	docs := api.Group("/docs", controller.AuthMiddleware(authService))
+	docs := api.Group("/docs", requireAuth)
 	docs.Post("/", docsController.UploadDocument)
 	docs.Get("/", docsController.GetDocumentsList)
 	docs.Get("/:id", docsController.GetDocument)
+	docs.Patch("/:id", docsController.UpdateDocument)
 	docs.Delete("/:id", docsController.DeleteDocument)
 
+	me := api.Group("/me", requireAuth)
+	me.Get("/", accountController.GetProfile)
+	me.Get("/usage", docsController.GetUsage)
+	me.Post("/password", controller.SessionOnly, accountController.ChangePassword)
+	me.Delete("/", controller.SessionOnly, accountController.DeleteAccount)
+	me.Post("/totp", controller.SessionOnly, accountController.EnrollTOTP)
+	me.Post("/totp/confirm", controller.SessionOnly, accountController.ConfirmTOTP)
+	me.Delete("/totp", controller.SessionOnly, accountController.DisableTOTP)
+	me.Post("/oidc", controller.SessionOnly, accountController.LinkOIDC)
+
+	trash := api.Group("/trash", requireAuth)
+	trash.Get("/", docsController.GetTrash)
+	trash.Post("/:id/restore", docsController.RestoreDocument)
+	trash.Delete("/:id", docsController.PurgeDocument)
+
+	sessions := api.Group("/sessions", requireAuth)
+	sessions.Get("/", authController.ListSessions)
+	sessions.Delete("/:id", authController.RevokeSession)
+
+	keys := api.Group("/keys", requireAuth, controller.SessionOnly)
+	keys.Post("/", authController.CreateAPIKey)
+	keys.Get("/", authController.ListAPIKeys)
+	keys.Delete("/:id", authController.RevokeAPIKey)
+
+	hooks := api.Group("/webhooks", requireAuth)
+	hooks.Post("/", webhookController.CreateWebhook)
+	hooks.Get("/", webhookController.ListWebhooks)
+	hooks.Delete("/:id", webhookController.DeleteWebhook)
+	hooks.Get("/:id/deliveries", webhookController.ListDeliveries)
+	hooks.Post("/:id/deliveries/:delivery/redeliver", webhookController.RedeliverDelivery)
+
+	api.Get("/events", requireAuth, eventsController.Stream)
+
+	admin := api.Group("/admin", requireAuth, controller.AdminOnly)
+	admin.Get("/users", adminController.ListUsers)
+	admin.Post("/users", adminController.CreateUser)
+	admin.Get("/users/:id", adminController.GetUser)
+	admin.Patch("/users/:id", adminController.UpdateUser)
+	admin.Delete("/users/:id", adminController.DeleteUser)
+	admin.Post("/users/:id/password-reset", adminController.ResetPassword)
+	admin.Post("/users/:id/unlock", adminController.UnlockUser)
+	admin.Delete("/users/:id/totp", adminController.ResetTOTP)
+	admin.Get("/audit", auditController.ListEvents)
+	admin.Get("/audit/export", auditController.ExportEvents)
+
+	admin.Post("/webhooks", adminWebhookController.CreateWebhook)
+	admin.Get("/webhooks", adminWebhookController.ListWebhooks)
+	admin.Delete("/webhooks/:id", adminWebhookController.DeleteWebhook)
+	admin.Get("/webhooks/:id/deliveries", adminWebhookController.ListDeliveries)
+	admin.Post("/webhooks/:id/deliveries/:delivery/redeliver", adminWebhookController.RedeliverDelivery)
+
 	return application
 }
 
 func registerTestUser(app *fiber.App) {
-	// Регистрация тестового пользователя (если еще не существует)
+	// Регистрация тестового пользователя первым администратором (если еще не существует)
 	regBody := map[string]string{
 		"token": "secure-admin-token-123",
 		"login": TestLogin,
@@ -97,30 +306,19 @@ func registerTestUser(app *fiber.App) {
 	req.Header.Set("Content-Type", "application/json")
 	app.Test(req)
 }
-func registerTestListUser(app *fiber.App) {
-	// Регистрация тестового пользователя (если еще не существует)
-	regBody1 := map[string]string{
-		"token": "secure-admin-token-123",
-		"login": "testuser1",
-		"pswd":  TestPass,
-	}
-	// Регистрация тестового пользователя (если еще не существует)
-	regBody2 := map[string]string{
-		"token": "secure-admin-token-123",
-		"login": "testuser2",
-		"pswd":  TestPass,
-	}
-	jsonBody, _ := json.Marshal(regBody1)
-
-	req := httptest.NewRequest("POST", "/api/register", bytes.NewBuffer(jsonBody))
-	req.Header.Set("Content-Type", "application/json")
-	app.Test(req)
-
-	jsonBody, _ = json.Marshal(regBody2)
+func registerTestListUser(app *fiber.App, adminToken string) {
+	// Создание тестовых пользователей администратором (если еще не существуют)
+	for _, login := range []string{TestLogin2, "testuser2"} {
+		jsonBody, _ := json.Marshal(map[string]string{
+			"login": login,
+			"pswd":  TestPass,
+		})
 
-	req = httptest.NewRequest("POST", "/api/register", bytes.NewBuffer(jsonBody))
-	req.Header.Set("Content-Type", "application/json")
-	app.Test(req)
+		req := httptest.NewRequest("POST", "/api/admin/users", bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", adminToken)
+		app.Test(req)
+	}
 }
 func getTestToken(login string, app *fiber.App) string {
 	// Аутентификация
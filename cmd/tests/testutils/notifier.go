@@ -0,0 +1,32 @@
+package testutils
+
+import (
+	"context"
+	"docs-server/internal/notify"
+	"sync"
+)
+
+// RecordingNotifier запоминает отправленные сообщения вместо доставки
+type RecordingNotifier struct {
+	mu       sync.Mutex
+	messages []*notify.Message
+}
+
+func (n *RecordingNotifier) Send(ctx context.Context, msg *notify.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+// Last возвращает последнее сообщение, отправленное на адрес to
+func (n *RecordingNotifier) Last(to string) *notify.Message {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for i := len(n.messages) - 1; i >= 0; i-- {
+		if n.messages[i].To == to {
+			return n.messages[i]
+		}
+	}
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+
+	"github.com/gofiber/fiber/v2"
 )
 
 func CreateMultipartRequest(meta string, filename string, content string) (*bytes.Buffer, string) {
@@ -52,3 +54,35 @@ func GetDocumentIDFromResponse(resp *http.Response) (string, error) {
 
 	return id, nil
 }
+
+// FindDocumentID ищет ID документа по имени в списке, возвращаемом по path (например /api/docs или /api/trash)
+func FindDocumentID(app *fiber.App, token, path, name string) (string, error) {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", token)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var list struct {
+		Data struct {
+			Docs []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"docs"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return "", err
+	}
+
+	for _, doc := range list.Data.Docs {
+		if doc.Name == name {
+			return doc.ID, nil
+		}
+	}
+
+	return "", nil
+}
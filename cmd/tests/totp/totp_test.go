@@ -0,0 +1,65 @@
+package totp_test
+
+import (
+	"docs-server/internal/totp"
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Секрет из тестовых векторов RFC 6238 (приложение B, SHA1)
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// Ожидаемые значения - последние 6 цифр 8-значных кодов из RFC
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := totp.Code(rfcSecret, totp.Step(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidate_Skew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := totp.Step(now)
+
+	next, err := totp.Code(rfcSecret, step+1)
+	require.NoError(t, err)
+	matched, ok := totp.Validate(rfcSecret, next, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, step+1, matched)
+
+	far, err := totp.Code(rfcSecret, step+2)
+	require.NoError(t, err)
+	_, ok = totp.Validate(rfcSecret, far, now, 1)
+	assert.False(t, ok)
+
+	_, ok = totp.Validate(rfcSecret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	uri, err := url.Parse(totp.URI("docs-server", "alice", secret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/docs-server:alice", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "docs-server", uri.Query().Get("issuer"))
+}
@@ -0,0 +1,323 @@
+package webhooks_test
+
+import (
+	"context"
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore webhook и доставки в памяти
+type memoryStore struct {
+	mu         sync.Mutex
+	hooks      map[string]*model.Webhook
+	deliveries []*model.WebhookDelivery
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{hooks: make(map[string]*model.Webhook)}
+}
+
+func (s *memoryStore) CreateWebhook(ctx context.Context, hook *model.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks[hook.ID] = hook
+	return nil
+}
+
+func (s *memoryStore) GetWebhook(ctx context.Context, id string) (*model.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hooks[id], nil
+}
+
+func (s *memoryStore) ListWebhooks(ctx context.Context, ownerID string, global bool) ([]*model.Webhook, error) {
+	hooks, _ := s.ListAllWebhooks(ctx)
+	var matched []*model.Webhook
+	for _, hook := range hooks {
+		if hook.Global == global && (global || hook.OwnerID == ownerID) {
+			matched = append(matched, hook)
+		}
+	}
+	return matched, nil
+}
+
+func (s *memoryStore) ListAllWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var hooks []*model.Webhook
+	for _, hook := range s.hooks {
+		hooks = append(hooks, hook)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+	return hooks, nil
+}
+
+func (s *memoryStore) DeleteWebhook(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.hooks, id)
+	return nil
+}
+
+func (s *memoryStore) CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	return nil
+}
+
+func (s *memoryStore) GetDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, delivery := range s.deliveries {
+		if delivery.ID == id {
+			return delivery, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched []*model.WebhookDelivery
+	for i := len(s.deliveries) - 1; i >= 0 && len(matched) < limit; i-- {
+		if s.deliveries[i].WebhookID == webhookID {
+			matched = append(matched, s.deliveries[i])
+		}
+	}
+	return matched, nil
+}
+
+func (s *memoryStore) DeliveryExists(ctx context.Context, webhookID, eventID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID && delivery.EventID == eventID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryStore) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []*model.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if len(claimed) < limit && delivery.Status == model.DeliveryPending && !delivery.NextAttemptAt.After(now) {
+			delivery.NextAttemptAt = now.Add(lease)
+			claimed = append(claimed, delivery)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return nil
+}
+
+// receiver получатель webhook, отвечающий кодом status
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, status int) *receiver {
+	r := &receiver{status: status}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		status := r.status
+		r.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func event(eventType, owner string, grant ...string) *model.DocumentEvent {
+	return &model.DocumentEvent{
+		ID:         "event-" + eventType + "-" + owner,
+		Type:       eventType,
+		Time:       time.Now().UTC(),
+		DocumentID: "doc-1",
+		Owner:      owner,
+		Document:   &model.Document{ID: "doc-1", Name: "report.pdf", Grant: grant},
+	}
+}
+
+func newService(store *memoryStore) *service.WebhookService {
+	return service.NewWebhookService(store, service.WebhookPolicy{
+		Timeout:      5 * time.Second,
+		MaxAttempts:  3,
+		AllowPrivate: true,
+	})
+}
+
+func TestWebhook_SignedDelivery(t *testing.T) {
+	store := newMemoryStore()
+	webhookService := newService(store)
+	target := newReceiver(t, http.StatusOK)
+
+	hook, secret, err := webhookService.CreateWebhook("user-1", false, target.URL, []string{model.EventDocumentCreated})
+	require.NoError(t, err)
+	require.NotEmpty(t, secret)
+
+	require.NoError(t, webhookService.Publish(context.Background(), event(model.EventDocumentCreated, "user-1")))
+	require.NoError(t, webhookService.Publish(context.Background(), event(model.EventDocumentDeleted, "user-1"))) // Не подписан
+	require.Len(t, store.deliveries, 1)
+
+	// Повторная публикация того же события из outbox не создает новую доставку
+	require.NoError(t, webhookService.Publish(context.Background(), event(model.EventDocumentCreated, "user-1")))
+	require.Len(t, store.deliveries, 1)
+
+	delivered, err := webhookService.DeliverDue(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	require.Len(t, target.requests, 1)
+
+	req, body := target.requests[0], target.bodies[0]
+	assert.Equal(t, model.EventDocumentCreated, req.Header.Get("X-Webhook-Event"))
+	assert.Equal(t, store.deliveries[0].ID, req.Header.Get("X-Webhook-ID"))
+	assert.NoError(t, service.VerifyWebhookSignature(secret, req.Header.Get("X-Webhook-Signature"), body, time.Minute, time.Now()))
+	assert.ErrorIs(t, service.VerifyWebhookSignature("other-secret", req.Header.Get("X-Webhook-Signature"), body, time.Minute, time.Now()),
+		service.ErrInvalidSignature)
+	assert.ErrorIs(t, service.VerifyWebhookSignature(secret, req.Header.Get("X-Webhook-Signature"), body, time.Minute, time.Now().Add(time.Hour)),
+		service.ErrInvalidSignature, "stale signature")
+
+	var payload model.DocumentEvent
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "doc-1", payload.DocumentID)
+	assert.Equal(t, "report.pdf", payload.Document.Name)
+
+	deliveries, err := webhookService.ListDeliveries("user-1", false, hook.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, model.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseCode)
+}
+
+func TestWebhook_Access(t *testing.T) {
+	store := newMemoryStore()
+	webhookService := newService(store)
+	target := newReceiver(t, http.StatusOK)
+
+	userHook, _, err := webhookService.CreateWebhook("user-2", false, target.URL, nil)
+	require.NoError(t, err)
+	globalHook, _, err := webhookService.CreateWebhook("admin-1", true, target.URL, nil)
+	require.NoError(t, err)
+
+	// Чужой закрытый документ видит только глобальный webhook
+	require.NoError(t, webhookService.Publish(context.Background(), event(model.EventDocumentCreated, "user-1")))
+	require.Len(t, store.deliveries, 1)
+	assert.Equal(t, globalHook.ID, store.deliveries[0].WebhookID)
+
+	// Документ, к которому user-2 получил доступ
+	require.NoError(t, webhookService.Publish(context.Background(), event(model.EventGrantChanged, "user-1", "user-2")))
+	require.Len(t, store.deliveries, 3)
+
+	// Webhook другого пользователя и глобальный webhook недоступны через пользовательские методы
+	_, err = webhookService.ListDeliveries("user-3", false, userHook.ID, 10)
+	assert.ErrorIs(t, err, service.ErrWebhookNotFound)
+	assert.ErrorIs(t, webhookService.DeleteWebhook("admin-1", false, globalHook.ID), service.ErrWebhookNotFound)
+	assert.NoError(t, webhookService.DeleteWebhook("admin-2", true, globalHook.ID), "any admin manages global webhooks")
+}
+
+func TestWebhook_RetryAndRedeliver(t *testing.T) {
+	store := newMemoryStore()
+	webhookService := newService(store)
+	target := newReceiver(t, http.StatusInternalServerError)
+
+	hook, _, err := webhookService.CreateWebhook("user-1", false, target.URL, nil)
+	require.NoError(t, err)
+	require.NoError(t, webhookService.Publish(context.Background(), event(model.EventDocumentUpdated, "user-1")))
+	delivery := store.deliveries[0]
+
+	now := time.Now()
+	_, err = webhookService.DeliverDue(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, model.DeliveryPending, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, delivery.ResponseCode)
+	assert.Contains(t, delivery.LastError, "500")
+	assert.WithinDuration(t, now.Add(30*time.Second), delivery.NextAttemptAt, time.Second)
+
+	// До наступления срока повтор не выполняется
+	_, err = webhookService.DeliverDue(context.Background(), now.Add(10*time.Second))
+	require.NoError(t, err)
+	assert.Len(t, target.requests, 1)
+
+	// Задержка удваивается, после max_attempts доставка переводится в failed
+	now = delivery.NextAttemptAt
+	_, err = webhookService.DeliverDue(context.Background(), now)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(time.Minute), delivery.NextAttemptAt, time.Second)
+	_, err = webhookService.DeliverDue(context.Background(), delivery.NextAttemptAt)
+	require.NoError(t, err)
+	assert.Equal(t, model.DeliveryFailed, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+
+	// Повторная отправка создает новую доставку того же события
+	target.mu.Lock()
+	target.status = http.StatusNoContent
+	target.mu.Unlock()
+	redelivery, err := webhookService.Redeliver("user-1", false, hook.ID, delivery.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, delivery.ID, redelivery.ID)
+	assert.Equal(t, delivery.EventID, redelivery.EventID)
+
+	delivered, err := webhookService.DeliverDue(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, model.DeliveryFailed, delivery.Status, "original delivery is kept in the log")
+	assert.Equal(t, string(target.bodies[0]), string(target.bodies[len(target.bodies)-1]))
+
+	_, err = webhookService.Redeliver("user-1", false, hook.ID, "unknown")
+	assert.ErrorIs(t, err, service.ErrDeliveryNotFound)
+}
+
+func TestWebhook_Validation(t *testing.T) {
+	webhookService := service.NewWebhookService(newMemoryStore(), service.WebhookPolicy{
+		Timeout:     time.Second,
+		MaxAttempts: 1,
+	})
+	target := newReceiver(t, http.StatusOK)
+
+	for _, url := range []string{"", "ftp://example.com/hook", "/relative", "http://127.0.0.1/hook",
+		"http://localhost:8080/hook", "http://10.1.2.3/hook", "http://[::1]/hook", "http://169.254.169.254/latest"} {
+		_, _, err := webhookService.CreateWebhook("user-1", false, url, nil)
+		assert.ErrorIs(t, err, service.ErrInvalidWebhookURL, url)
+	}
+
+	_, _, err := webhookService.CreateWebhook("user-1", false, "https://example.com/hook", []string{"document.renamed"})
+	assert.ErrorIs(t, err, service.ErrInvalidWebhookEvent)
+
+	// Имя, разрешающееся в адрес локальной сети, отклоняется при подключении
+	store := newMemoryStore()
+	strict := service.NewWebhookService(store, service.WebhookPolicy{Timeout: time.Second, MaxAttempts: 1})
+	require.NoError(t, store.CreateWebhook(context.Background(), &model.Webhook{ID: "hook-1", OwnerID: "user-1", URL: target.URL}))
+	require.NoError(t, strict.Publish(context.Background(), event(model.EventDocumentCreated, "user-1")))
+	_, err = strict.DeliverDue(context.Background(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, store.deliveries, 1)
+	assert.Equal(t, model.DeliveryFailed, store.deliveries[0].Status)
+	assert.Contains(t, store.deliveries[0].LastError, "private address")
+	assert.Empty(t, target.requests)
+}
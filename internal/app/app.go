@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"docs-server/internal/cache"
 	"docs-server/internal/controller"
 	"docs-server/internal/repository"
@@ -23,38 +24,215 @@ func NewApp(fiberApp *fiber.App, cfg *Config) (*App, error) {
 	// Инициализация репозиториев
 	userRepo := repository.NewUserRepository(cfg.Database.DSN)
 	docRepo := repository.NewDocumentRepository(cfg.Database.DSN)
+	sessionRepo := repository.NewSessionRepository(cfg.Database.DSN)
+	apiKeyRepo := repository.NewAPIKeyRepository(cfg.Database.DSN)
+	auditRepo := repository.NewAuditRepository(cfg.Database.DSN)
+	webhookRepo := repository.NewWebhookRepository(cfg.Database.DSN)
 
 	// Инициализация кеша
 	cache := cache.NewMemoryCache()
 	// Инициализация сервисов
-	authService := service.NewAuthService(userRepo, cfg.Auth.AdminToken, []byte(cfg.Auth.JWTSecret), cache)
-	userService := service.NewUserService(userRepo)
-	docService := service.NewDocumentService(docRepo, userRepo, cache, cfg.Storage.UploadDir)
+	hasher, err := cfg.NewPasswordHasher()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := cfg.NewCredentialPolicy()
+	if err != nil {
+		return nil, err
+	}
+	authenticators, err := cfg.NewAuthenticators(userRepo, hasher, policy)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := cfg.NewTokenIssuer()
+	if err != nil {
+		return nil, err
+	}
+	denylist := cfg.NewTokenDenylist()
+	authService := service.NewAuthService(userRepo, sessionRepo, apiKeyRepo, cfg.Auth.AdminToken, hasher, policy, tokens, denylist, cache, service.PasswordReset{
+		Notifier: cfg.NewNotifier(),
+		TTL:      cfg.PasswordReset.TTL,
+		URL:      cfg.PasswordReset.URL,
+	}, cfg.NewLoginGuard(cache), service.MFAPolicy{
+		Required:     cfg.MFA.Required,
+		Issuer:       cfg.MFA.Issuer,
+		ChallengeTTL: cfg.MFA.ChallengeTTL,
+	}, cfg.NewOIDCLogin(), authenticators)
+	webhookService := service.NewWebhookService(webhookRepo, service.WebhookPolicy{
+		Timeout:      cfg.Webhooks.Timeout,
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		AllowPrivate: cfg.Webhooks.AllowPrivate,
+	})
+	eventStream := cfg.NewEventStream()
+	outbox, err := cfg.NewOutbox(webhookService, eventStream)
+	if err != nil {
+		return nil, err
+	}
+	docService := service.NewDocumentService(docRepo, userRepo, cache, cfg.Storage.UploadDir, service.Quota{
+		MaxBytes:     cfg.Quota.MaxBytes,
+		MaxDocuments: cfg.Quota.MaxDocuments,
+	}, service.RetentionPolicy{
+		Default: cfg.Retention.Default,
+		ByMime:  cfg.Retention.ByMime,
+		ByUser:  cfg.Retention.ByUser,
+	}, outbox)
+	userService := service.NewUserService(userRepo, authService, docService)
+	auditService := service.NewAuditService(auditRepo)
+	checkpointKeys, err := cfg.NewAuditCheckpointKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	// Фоновое удаление файлов из очереди pending_deletes
+	if cfg.Storage.DeleteRetryInterval > 0 {
+		pendingDeleteWorker := service.NewPendingDeleteWorker(docService, cfg.Storage.DeleteRetryInterval)
+		go pendingDeleteWorker.Run(context.Background())
+	}
+
+	// Фоновая очистка корзины
+	if cfg.Storage.TrashPurgeInterval > 0 {
+		trashPurger := service.NewTrashPurger(docService, cfg.Storage.TrashRetention, cfg.Storage.TrashPurgeInterval)
+		go trashPurger.Run(context.Background())
+	}
+
+	// Фоновое удаление документов с истекшим сроком хранения
+	if cfg.Retention.CheckInterval > 0 {
+		expiryScheduler := service.NewExpiryScheduler(docService, cfg.Retention.CheckInterval)
+		go expiryScheduler.Run(context.Background())
+	}
+
+	// Синхронизация списка отозванных токенов между экземплярами
+	if denylist != nil {
+		go denylist.Run(context.Background())
+	}
+
+	// Фоновая сверка каталога загрузок с БД
+	if cfg.GC.Interval > 0 {
+		garbageCollector := service.NewGarbageCollector(docService, cfg.GC.Interval, cfg.GC.GracePeriod, cfg.GC.Fix)
+		go garbageCollector.Run(context.Background())
+	}
+
+	// Публикация событий документов из outbox
+	outboxRelay := service.NewOutboxRelay(outbox, cfg.Outbox.RelayInterval)
+	go outboxRelay.Run(context.Background())
+
+	// Доставка событий документов на webhook
+	if cfg.Webhooks.Enabled {
+		webhookDispatcher := service.NewWebhookDispatcher(webhookService, cfg.Webhooks.RetryInterval)
+		go webhookDispatcher.Run(context.Background())
+	}
+
+	// Журнал событий для потока /api/events
+	go eventStream.Run(context.Background())
+
+	// Подпись контрольных точек журнала аудита
+	if cfg.Audit.CheckpointInterval > 0 && checkpointKeys != nil {
+		auditCheckpointer := service.NewAuditCheckpointer(auditService, checkpointKeys, cfg.Audit.CheckpointInterval)
+		go auditCheckpointer.Run(context.Background())
+	}
 
 	// Инициализация контроллеров
-	authController := controller.NewAuthController(authService)
-	docsController := controller.NewDocsController(docService, userService)
+	authController := controller.NewAuthController(authService, auditService)
+	docsController := controller.NewDocsController(docService, userService, auditService)
+	adminController := controller.NewAdminController(userService, authService)
+	accountController := controller.NewAccountController(userService, authService)
+	auditController := controller.NewAuditController(auditService)
+	webhookController := controller.NewWebhookController(webhookService, false)
+	adminWebhookController := controller.NewWebhookController(webhookService, true)
+	eventsController := controller.NewEventsController(eventStream, cfg.Events.Heartbeat)
 
 	// Настройка маршрутов
-	app.setupRoutes(authController, docsController)
+	app.setupRoutes(authController, docsController, adminController, accountController, auditController, webhookController, adminWebhookController, eventsController)
 
 	return app, nil
 }
 
-func (a *App) setupRoutes(authCtrl *controller.AuthController, docsCtrl *controller.DocsController) {
+func (a *App) setupRoutes(authCtrl *controller.AuthController, docsCtrl *controller.DocsController, adminCtrl *controller.AdminController, accountCtrl *controller.AccountController, auditCtrl *controller.AuditController, webhookCtrl, adminWebhookCtrl *controller.WebhookController, eventsCtrl *controller.EventsController) {
+	a.Get("/.well-known/jwks.json", authCtrl.JWKS)
+
 	api := a.Group("/api")
+	requireAuth := controller.AuthMiddleware(authCtrl.GetAuthService(), a.cfg.Auth.AllowRawToken)
 
 	// Маршруты для авторизации
 	api.Post("/register", authCtrl.Register)
 	api.Post("/auth", authCtrl.Authenticate)
-	api.Delete("/auth/:token", authCtrl.Logout)
+	api.Post("/auth/refresh", authCtrl.Refresh)
+	api.Post("/auth/mfa", authCtrl.CompleteMFA)
+	api.Post("/auth/mfa/enroll", authCtrl.BeginMFAEnrollment)
+	api.Get("/auth/oidc/login", authCtrl.OIDCLogin)
+	api.Get("/auth/oidc/callback", authCtrl.OIDCCallback)
+	api.Post("/auth/password-reset", authCtrl.RequestPasswordReset)
+	api.Post("/auth/password-reset/confirm", authCtrl.ResetPassword)
+	api.Delete("/auth", requireAuth, controller.SessionOnly, authCtrl.Logout)
 
 	// Маршруты для документы
-	docs := api.Group("/docs", controller.AuthMiddleware(authCtrl.GetAuthService()))
+	docs := api.Group("/docs", requireAuth)
 	docs.Post("/", docsCtrl.UploadDocument)
 	docs.Get("/", docsCtrl.GetDocumentsList)
 	docs.Get("/:id", docsCtrl.GetDocument)
+	docs.Patch("/:id", docsCtrl.UpdateDocument)
 	docs.Delete("/:id", docsCtrl.DeleteDocument)
+
+	// Учетная запись текущего пользователя
+	me := api.Group("/me", requireAuth)
+	me.Get("/", accountCtrl.GetProfile)
+	me.Get("/usage", docsCtrl.GetUsage)
+	me.Post("/password", controller.SessionOnly, accountCtrl.ChangePassword)
+	me.Delete("/", controller.SessionOnly, accountCtrl.DeleteAccount)
+	me.Post("/totp", controller.SessionOnly, accountCtrl.EnrollTOTP)
+	me.Post("/totp/confirm", controller.SessionOnly, accountCtrl.ConfirmTOTP)
+	me.Delete("/totp", controller.SessionOnly, accountCtrl.DisableTOTP)
+	me.Post("/oidc", controller.SessionOnly, accountCtrl.LinkOIDC)
+
+	// Корзина
+	trash := api.Group("/trash", requireAuth)
+	trash.Get("/", docsCtrl.GetTrash)
+	trash.Post("/:id/restore", docsCtrl.RestoreDocument)
+	trash.Delete("/:id", docsCtrl.PurgeDocument)
+
+	// Сессии
+	sessions := api.Group("/sessions", requireAuth)
+	sessions.Get("/", authCtrl.ListSessions)
+	sessions.Delete("/:id", authCtrl.RevokeSession)
+
+	// API-ключи
+	keys := api.Group("/keys", requireAuth, controller.SessionOnly)
+	keys.Post("/", authCtrl.CreateAPIKey)
+	keys.Get("/", authCtrl.ListAPIKeys)
+	keys.Delete("/:id", authCtrl.RevokeAPIKey)
+
+	// Webhook
+	hooks := api.Group("/webhooks", requireAuth)
+	hooks.Post("/", webhookCtrl.CreateWebhook)
+	hooks.Get("/", webhookCtrl.ListWebhooks)
+	hooks.Delete("/:id", webhookCtrl.DeleteWebhook)
+	hooks.Get("/:id/deliveries", webhookCtrl.ListDeliveries)
+	hooks.Post("/:id/deliveries/:delivery/redeliver", webhookCtrl.RedeliverDelivery)
+
+	// Поток событий документов
+	api.Get("/events", requireAuth, eventsCtrl.Stream)
+
+	// Администрирование пользователей
+	admin := api.Group("/admin", requireAuth, controller.AdminOnly)
+	admin.Get("/users", adminCtrl.ListUsers)
+	admin.Post("/users", adminCtrl.CreateUser)
+	admin.Get("/users/:id", adminCtrl.GetUser)
+	admin.Patch("/users/:id", adminCtrl.UpdateUser)
+	admin.Delete("/users/:id", adminCtrl.DeleteUser)
+	admin.Post("/users/:id/password-reset", adminCtrl.ResetPassword)
+	admin.Post("/users/:id/unlock", adminCtrl.UnlockUser)
+	admin.Delete("/users/:id/totp", adminCtrl.ResetTOTP)
+
+	// Журнал аудита
+	admin.Get("/audit", auditCtrl.ListEvents)
+	admin.Get("/audit/export", auditCtrl.ExportEvents)
+
+	// Глобальные webhook
+	admin.Post("/webhooks", adminWebhookCtrl.CreateWebhook)
+	admin.Get("/webhooks", adminWebhookCtrl.ListWebhooks)
+	admin.Delete("/webhooks/:id", adminWebhookCtrl.DeleteWebhook)
+	admin.Get("/webhooks/:id/deliveries", adminWebhookCtrl.ListDeliveries)
+	admin.Post("/webhooks/:id/deliveries/:delivery/redeliver", adminWebhookCtrl.RedeliverDelivery)
 }
 
 func ErrorHandler(ctx *fiber.Ctx, err error) error {
@@ -2,9 +2,19 @@ package app
 
 import (
 	"crypto/rand"
+	"docs-server/internal/cache"
+	"docs-server/internal/jose"
+	"docs-server/internal/notify"
+	"docs-server/internal/oidc"
+	"docs-server/internal/password"
+	"docs-server/internal/repository"
+	"docs-server/internal/service"
+	"docs-server/internal/sink"
 	"encoding/base64"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -18,12 +28,158 @@ type Config struct {
 		DSN string `yaml:"dsn"` // Формат: "user:password@tcp(host:port)/dbname"
 	} `yaml:"database"`
 	Auth struct {
-		AdminToken string `yaml:"admin_token"`
-		JWTSecret  string `yaml:"jwt_secret"` // base64-encoded 32-byte secret
+		AdminToken    string   `yaml:"admin_token"`
+		JWTSecret     string   `yaml:"jwt_secret"`      // base64-encoded 32-byte secret
+		AllowRawToken bool     `yaml:"allow_raw_token"` // Принимать токен в Authorization без схемы Bearer
+		Backends      []string `yaml:"backends"`        // Источники проверки пароля по порядку: local, ldap
 	} `yaml:"auth"`
+	PasswordHashing struct {
+		Algorithm string `yaml:"algorithm"` // argon2id или bcrypt. Хеши другого алгоритма пересчитываются при входе
+		Argon2id  struct {
+			Memory  uint32 `yaml:"memory"` // Память в КиБ
+			Time    uint32 `yaml:"time"`   // Количество итераций
+			Threads uint8  `yaml:"threads"`
+		} `yaml:"argon2id"`
+		BcryptCost int `yaml:"bcrypt_cost"`
+	} `yaml:"password_hashing"`
+	CredentialPolicy struct {
+		Login struct {
+			MinLength int    `yaml:"min_length"`
+			MaxLength int    `yaml:"max_length"` // Не более 255 (размер колонки users.login)
+			Charset   string `yaml:"charset"`    // ascii - латинские буквы и цифры, unicode - буквы и цифры любого алфавита
+			Symbols   string `yaml:"symbols"`    // Дополнительно разрешенные символы, например "._-"
+			Email     bool   `yaml:"email"`      // Разрешить логины в виде адреса электронной почты
+		} `yaml:"login"`
+		Password struct {
+			MinLength      int    `yaml:"min_length"`
+			MaxLength      int    `yaml:"max_length"` // 0 - без ограничения
+			RequireUpper   bool   `yaml:"require_upper"`
+			RequireLower   bool   `yaml:"require_lower"`
+			RequireDigit   bool   `yaml:"require_digit"`
+			RequireSpecial bool   `yaml:"require_special"`
+			BlocklistFile  string `yaml:"blocklist_file"` // Скомпрометированные пароли: по одному на строку или SHA-1 в hex
+		} `yaml:"password"`
+	} `yaml:"credential_policy"`
+	JWT struct {
+		SigningKey       string        `yaml:"signing_key"`       // PEM-файл закрытого ключа RSA, ECDSA или Ed25519. Пустой - HS256 с jwt_secret
+		VerificationKeys []string      `yaml:"verification_keys"` // PEM-файлы прежних ключей, токены которых еще принимаются
+		Issuer           string        `yaml:"issuer"`            // Значение iss, проверяется при разборе токена
+		Audience         string        `yaml:"audience"`          // Значение aud access-токенов
+		AccessTTL        time.Duration `yaml:"access_ttl"`
+		RefreshTTL       time.Duration `yaml:"refresh_ttl"`
+		Leeway           time.Duration `yaml:"leeway"`         // Допустимое расхождение часов при проверке сроков
+		Stateless        bool          `yaml:"stateless"`      // Проверять access-токен без обращения к БД, по списку отозванных
+		DenylistStore    string        `yaml:"denylist_store"` // memory - только в этом экземпляре, database - общий в таблице revoked_tokens
+		DenylistSync     time.Duration `yaml:"denylist_sync"`  // Периодичность загрузки отзывов других экземпляров
+	} `yaml:"jwt"`
 	Storage struct {
-		UploadDir string `yaml:"upload_dir"`
+		UploadDir           string        `yaml:"upload_dir"`
+		TrashRetention      time.Duration `yaml:"trash_retention"`       // Срок хранения документов в корзине
+		TrashPurgeInterval  time.Duration `yaml:"trash_purge_interval"`  // Периодичность очистки корзины
+		DeleteRetryInterval time.Duration `yaml:"delete_retry_interval"` // Периодичность повторного удаления файлов
 	} `yaml:"storage"`
+	Quota struct {
+		MaxBytes     int64 `yaml:"max_bytes"`     // Квота по умолчанию в байтах, 0 - без ограничений
+		MaxDocuments int64 `yaml:"max_documents"` // Квота по умолчанию на количество документов, 0 - без ограничений
+	} `yaml:"quota"`
+	Retention struct {
+		Default       time.Duration            `yaml:"default"`        // Срок хранения по умолчанию, 0 - бессрочно
+		ByMime        map[string]time.Duration `yaml:"by_mime"`        // Сроки хранения по MIME-типу ("image/*" допускается)
+		ByUser        map[string]time.Duration `yaml:"by_user"`        // Сроки хранения по логину пользователя
+		CheckInterval time.Duration            `yaml:"check_interval"` // Периодичность удаления истекших документов
+	} `yaml:"retention"`
+	SMTP struct {
+		Host     string `yaml:"host"` // Пустой - отправка писем отключена
+		Port     int    `yaml:"port"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		From     string `yaml:"from"`
+	} `yaml:"smtp"`
+	PasswordReset struct {
+		TTL time.Duration `yaml:"ttl"` // Срок действия токена сброса пароля
+		URL string        `yaml:"url"` // Адрес страницы сброса, к которому дописывается токен
+	} `yaml:"password_reset"`
+	MFA struct {
+		Required     bool          `yaml:"required"`      // Второй фактор обязателен для всех пользователей
+		Issuer       string        `yaml:"issuer"`        // Название сервиса в приложении-аутентификаторе
+		ChallengeTTL time.Duration `yaml:"challenge_ttl"` // Срок на ввод кода после пароля
+	} `yaml:"mfa"`
+	OIDC struct {
+		Issuer        string   `yaml:"issuer"` // Пустой - вход через OpenID Connect отключен
+		ClientID      string   `yaml:"client_id"`
+		ClientSecret  string   `yaml:"client_secret"`
+		RedirectURL   string   `yaml:"redirect_url"` // Адрес /api/auth/oidc/callback этого сервиса
+		Scopes        []string `yaml:"scopes"`
+		LoginClaim    string   `yaml:"login_claim"`    // Утверждение ID-токена, сопоставляемое с логином
+		AutoProvision bool     `yaml:"auto_provision"` // Создавать пользователя при первом входе
+	} `yaml:"oidc"`
+	LDAP struct {
+		URL                string        `yaml:"url"` // ldap://host:389 или ldaps://host:636
+		StartTLS           bool          `yaml:"start_tls"`
+		InsecureSkipVerify bool          `yaml:"insecure_skip_verify"`
+		BindDN             string        `yaml:"bind_dn"` // Служебная учетная запись для поиска пользователя
+		BindPassword       string        `yaml:"bind_password"`
+		BaseDN             string        `yaml:"base_dn"`
+		UserFilter         string        `yaml:"user_filter"` // %s заменяется логином
+		LoginAttribute     string        `yaml:"login_attribute"`
+		EmailAttribute     string        `yaml:"email_attribute"`
+		GroupAttribute     string        `yaml:"group_attribute"`
+		AdminGroups        []string      `yaml:"admin_groups"`   // Члены групп получают роль администратора
+		AllowedGroups      []string      `yaml:"allowed_groups"` // Пустой - входить могут все пользователи каталога
+		AutoProvision      bool          `yaml:"auto_provision"`
+		Timeout            time.Duration `yaml:"timeout"`
+	} `yaml:"ldap"`
+	LoginProtection struct {
+		Enabled          bool          `yaml:"enabled"`
+		MaxAttempts      int           `yaml:"max_attempts"`        // Неудачных попыток по логину до блокировки, 0 - без блокировки
+		MaxAttemptsPerIP int           `yaml:"max_attempts_per_ip"` // Неудачных попыток с одного IP до блокировки, 0 - без блокировки
+		FreeAttempts     int           `yaml:"free_attempts"`       // Попыток без задержки
+		BaseDelay        time.Duration `yaml:"base_delay"`          // Начальная задержка, удваивается с каждой неудачей
+		MaxDelay         time.Duration `yaml:"max_delay"`
+		LockoutDuration  time.Duration `yaml:"lockout_duration"`
+		Window           time.Duration `yaml:"window"` // Счетчик сбрасывается после этого срока без неудач
+		Store            string        `yaml:"store"`  // memory - в кеше приложения, database - в таблице login_attempts
+	} `yaml:"login_protection"`
+	GC struct {
+		Interval    time.Duration `yaml:"interval"`     // Периодичность сверки файлов и БД, 0 - отключить
+		GracePeriod time.Duration `yaml:"grace_period"` // Файлы моложе этого срока не считаются потерянными
+		Fix         bool          `yaml:"fix"`          // Удалять найденные расхождения, иначе только отчет
+	} `yaml:"gc"`
+	Audit struct {
+		CheckpointKey              string        `yaml:"checkpoint_key"`               // PEM-файл закрытого ключа подписи контрольных точек. Пустой - точки не создаются
+		CheckpointVerificationKeys []string      `yaml:"checkpoint_verification_keys"` // PEM-файлы прежних ключей для проверки старых точек
+		CheckpointInterval         time.Duration `yaml:"checkpoint_interval"`          // Периодичность создания контрольных точек
+	} `yaml:"audit"`
+	Webhooks struct {
+		Enabled       bool          `yaml:"enabled"`        // Отправлять события документов на зарегистрированные webhook
+		Timeout       time.Duration `yaml:"timeout"`        // Время ожидания ответа получателя
+		MaxAttempts   int           `yaml:"max_attempts"`   // Попыток доставки до перевода в failed
+		RetryInterval time.Duration `yaml:"retry_interval"` // Периодичность проверки отложенных повторных попыток
+		AllowPrivate  bool          `yaml:"allow_private"`  // Разрешить адреса локальной сети и loopback
+	} `yaml:"webhooks"`
+	Events struct {
+		Store        string        `yaml:"store"`         // memory - журнал в памяти экземпляра (только для одного экземпляра), database - общий в таблице document_events
+		Capacity     int           `yaml:"capacity"`      // Сколько последних событий хранится для продолжения по Last-Event-ID
+		PollInterval time.Duration `yaml:"poll_interval"` // Периодичность проверки событий других экземпляров (database)
+		Heartbeat    time.Duration `yaml:"heartbeat"`     // Периодичность комментария-пинга, чтобы прокси не закрывали поток
+	} `yaml:"events"`
+	Outbox struct {
+		Sinks         []string      `yaml:"sinks"`          // Приемники событий документов: webhook, stream, nats, file
+		Timeout       time.Duration `yaml:"timeout"`        // Время на публикацию одного события
+		MaxAttempts   int           `yaml:"max_attempts"`   // Попыток публикации до перевода в failed
+		RelayInterval time.Duration `yaml:"relay_interval"` // Периодичность проверки неопубликованных событий
+		Retention     time.Duration `yaml:"retention"`      // Срок хранения опубликованных событий
+		NATS          struct {
+			URL      string `yaml:"url"`     // nats://host:4222 или tls://host:4222
+			Subject  string `yaml:"subject"` // Префикс темы, к нему дописывается тип события
+			Token    string `yaml:"token"`
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+		} `yaml:"nats"`
+		File struct {
+			Path string `yaml:"path"` // Журнал событий в формате JSON Lines
+		} `yaml:"file"`
+	} `yaml:"outbox"`
 }
 
 // NewConfig загружает конфигурацию из файла или использует значения по умолчанию
@@ -46,19 +202,90 @@ func NewConfig() (*Config, error) {
 			DSN: "root:password@tcp(localhost:3306)/documents_db",
 		},
 		Auth: struct {
-			AdminToken string `yaml:"admin_token"`
-			JWTSecret  string `yaml:"jwt_secret"`
+			AdminToken    string   `yaml:"admin_token"`
+			JWTSecret     string   `yaml:"jwt_secret"`
+			AllowRawToken bool     `yaml:"allow_raw_token"`
+			Backends      []string `yaml:"backends"`
 		}{
-			AdminToken: "admin-secret-token",
-			JWTSecret:  jwtSecret,
+			AdminToken:    "admin-secret-token",
+			JWTSecret:     jwtSecret,
+			AllowRawToken: true,
+			Backends:      []string{"local"},
 		},
 		Storage: struct {
-			UploadDir string `yaml:"upload_dir"`
+			UploadDir           string        `yaml:"upload_dir"`
+			TrashRetention      time.Duration `yaml:"trash_retention"`
+			TrashPurgeInterval  time.Duration `yaml:"trash_purge_interval"`
+			DeleteRetryInterval time.Duration `yaml:"delete_retry_interval"`
 		}{
-			UploadDir: "uploads",
+			UploadDir:           "uploads",
+			TrashRetention:      30 * 24 * time.Hour,
+			TrashPurgeInterval:  time.Hour,
+			DeleteRetryInterval: time.Minute,
 		},
 	}
 
+	config.PasswordHashing.Algorithm = "argon2id"
+	config.PasswordHashing.Argon2id.Memory = password.DefaultArgon2id.Memory
+	config.PasswordHashing.Argon2id.Time = password.DefaultArgon2id.Time
+	config.PasswordHashing.Argon2id.Threads = password.DefaultArgon2id.Threads
+	config.PasswordHashing.BcryptCost = 10
+	defaultPolicy := service.DefaultCredentialPolicy()
+	config.CredentialPolicy.Login.MinLength = defaultPolicy.LoginMinLength
+	config.CredentialPolicy.Login.MaxLength = defaultPolicy.LoginMaxLength
+	config.CredentialPolicy.Login.Charset = defaultPolicy.LoginCharset
+	config.CredentialPolicy.Password.MinLength = defaultPolicy.PasswordMinLength
+	config.CredentialPolicy.Password.RequireUpper = defaultPolicy.RequireUpper
+	config.CredentialPolicy.Password.RequireLower = defaultPolicy.RequireLower
+	config.CredentialPolicy.Password.RequireDigit = defaultPolicy.RequireDigit
+	config.CredentialPolicy.Password.RequireSpecial = defaultPolicy.RequireSpecial
+	config.JWT.Issuer = "docs-server"
+	config.JWT.Audience = "docs-server"
+	config.JWT.AccessTTL = 24 * time.Hour
+	config.JWT.RefreshTTL = 30 * 24 * time.Hour
+	config.JWT.Leeway = 30 * time.Second
+	config.JWT.DenylistStore = "memory"
+	config.JWT.DenylistSync = 10 * time.Second
+	config.Retention.CheckInterval = 5 * time.Minute
+	config.SMTP.Port = 587
+	config.PasswordReset.TTL = time.Hour
+	config.MFA.Issuer = "docs-server"
+	config.MFA.ChallengeTTL = 5 * time.Minute
+	config.OIDC.LoginClaim = "preferred_username"
+	config.OIDC.AutoProvision = true
+	config.LDAP.UserFilter = "(&(objectClass=person)(uid=%s))"
+	config.LDAP.LoginAttribute = "uid"
+	config.LDAP.EmailAttribute = "mail"
+	config.LDAP.GroupAttribute = "memberOf"
+	config.LDAP.AutoProvision = true
+	config.LDAP.Timeout = 10 * time.Second
+	config.LoginProtection.Enabled = true
+	config.LoginProtection.MaxAttempts = 10
+	config.LoginProtection.MaxAttemptsPerIP = 50
+	config.LoginProtection.FreeAttempts = 3
+	config.LoginProtection.BaseDelay = time.Second
+	config.LoginProtection.MaxDelay = time.Minute
+	config.LoginProtection.LockoutDuration = 15 * time.Minute
+	config.LoginProtection.Window = 15 * time.Minute
+	config.LoginProtection.Store = "memory"
+	config.GC.Interval = 24 * time.Hour
+	config.GC.GracePeriod = time.Hour
+	config.Audit.CheckpointInterval = time.Hour
+	config.Webhooks.Enabled = true
+	config.Webhooks.Timeout = 10 * time.Second
+	config.Webhooks.MaxAttempts = 8
+	config.Webhooks.RetryInterval = 15 * time.Second
+	config.Events.Store = "memory"
+	config.Events.Capacity = 1000
+	config.Events.PollInterval = 2 * time.Second
+	config.Events.Heartbeat = 15 * time.Second
+	config.Outbox.Sinks = []string{"webhook", "stream"}
+	config.Outbox.Timeout = 10 * time.Second
+	config.Outbox.MaxAttempts = 20
+	config.Outbox.RelayInterval = 5 * time.Second
+	config.Outbox.Retention = 24 * time.Hour
+	config.Outbox.NATS.Subject = "docs.events"
+
 	// Пути к возможным расположениям конфигурационных файлов
 	configPaths := []string{
 		"./config.yml",                      // В текущей директории
@@ -91,7 +318,266 @@ func generateRandomSecret() string {
 
 // GetJWTSecret возвращает декодированный JWT секрет
 func (c *Config) GetJWTSecret() ([]byte, error) {
-	return base64.StdEncoding.DecodeString(c.Auth.JWTSecret)
+	secret, err := base64.StdEncoding.DecodeString(c.Auth.JWTSecret)
+	if err != nil {
+		return nil, fmt.Errorf("auth.jwt_secret must be base64-encoded: %w", err)
+	}
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("auth.jwt_secret must be at least 32 bytes, got %d", len(secret))
+	}
+	return secret, nil
+}
+
+// NewKeySet возвращает ключи подписи JWT. При подписи асимметричным ключом токены,
+// подписанные jwt_secret до перехода, остаются действительными до истечения
+func (c *Config) NewKeySet() (*jose.KeySet, error) {
+	if c.JWT.SigningKey == "" {
+		secret, err := c.GetJWTSecret()
+		if err != nil {
+			return nil, err
+		}
+		return jose.NewHMACKeySet(secret)
+	}
+
+	var secret []byte
+	if c.Auth.JWTSecret != "" {
+		var err error
+		if secret, err = c.GetJWTSecret(); err != nil {
+			return nil, err
+		}
+	}
+	return jose.LoadKeySet(c.JWT.SigningKey, c.JWT.VerificationKeys, secret)
+}
+
+// NewAuditCheckpointKeys возвращает ключи подписи контрольных точек журнала аудита
+// или nil, если audit.checkpoint_key не задан
+func (c *Config) NewAuditCheckpointKeys() (*jose.KeySet, error) {
+	if c.Audit.CheckpointKey == "" {
+		return nil, nil
+	}
+	return jose.LoadKeySet(c.Audit.CheckpointKey, c.Audit.CheckpointVerificationKeys, nil)
+}
+
+// NewTokenIssuer возвращает выпуск и проверку токенов по настройкам jwt
+func (c *Config) NewTokenIssuer() (*jose.TokenIssuer, error) {
+	keys, err := c.NewKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	return jose.NewTokenIssuer(keys, jose.TokenConfig{
+		Issuer:     c.JWT.Issuer,
+		Audience:   c.JWT.Audience,
+		TTL:        c.JWT.AccessTTL,
+		RefreshTTL: c.JWT.RefreshTTL,
+		Leeway:     c.JWT.Leeway,
+	})
+}
+
+// NewTokenDenylist возвращает список отозванных токенов для режима jwt.stateless или nil, если режим отключен
+func (c *Config) NewTokenDenylist() *service.TokenDenylist {
+	if !c.JWT.Stateless {
+		return nil
+	}
+
+	var store service.RevocationStore
+	if c.JWT.DenylistStore == "database" {
+		store = repository.NewRevokedTokenRepository(c.Database.DSN)
+	}
+	return service.NewTokenDenylist(store, c.JWT.DenylistSync)
+}
+
+// NewEventStream возвращает журнал событий для потока /api/events. Хранилище database
+// нужно, когда запущено несколько экземпляров: поток видит события всех экземпляров
+func (c *Config) NewEventStream() *service.EventStream {
+	var store service.EventLogStore
+	if c.Events.Store == "database" {
+		store = repository.NewEventRepository(c.Database.DSN)
+	}
+	return service.NewEventStream(store, c.Events.Capacity, c.Events.PollInterval)
+}
+
+// NewOutbox возвращает публикацию событий документов в приемники outbox.sinks.
+// Приемник webhook подключается, только если включены webhooks
+func (c *Config) NewOutbox(webhooks *service.WebhookService, stream *service.EventStream) (*service.Outbox, error) {
+	sinks := make(map[string]sink.Sink)
+	for _, name := range c.Outbox.Sinks {
+		switch name {
+		case "webhook":
+			if c.Webhooks.Enabled {
+				sinks[name] = webhooks
+			}
+		case "stream":
+			// При events.store: memory сообщение публикует тот экземпляр, чей relay забрал его первым,
+			// и событие видят только подключенные к нему клиенты. Нескольким экземплярам нужен database
+			sinks[name] = stream
+		case "nats":
+			if c.Outbox.NATS.URL == "" {
+				return nil, fmt.Errorf("outbox: nats sink requires outbox.nats.url")
+			}
+			sinks[name] = sink.NewNATSSink(sink.NATSConfig{
+				URL:      c.Outbox.NATS.URL,
+				Subject:  c.Outbox.NATS.Subject,
+				Token:    c.Outbox.NATS.Token,
+				Username: c.Outbox.NATS.Username,
+				Password: c.Outbox.NATS.Password,
+				Timeout:  c.Outbox.Timeout,
+			})
+		case "file":
+			if c.Outbox.File.Path == "" {
+				return nil, fmt.Errorf("outbox: file sink requires outbox.file.path")
+			}
+			sinks[name] = sink.NewFileSink(c.Outbox.File.Path)
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+
+	return service.NewOutbox(repository.NewOutboxRepository(c.Database.DSN), sinks, service.OutboxPolicy{
+		Timeout:     c.Outbox.Timeout,
+		MaxAttempts: c.Outbox.MaxAttempts,
+		Retention:   c.Outbox.Retention,
+	}), nil
+}
+
+// NewNotifier возвращает отправку писем по настройкам SMTP или nil, если SMTP не настроен
+func (c *Config) NewNotifier() notify.Notifier {
+	if c.SMTP.Host == "" {
+		return nil
+	}
+	return notify.NewSMTPNotifier(c.SMTP.Host, c.SMTP.Port, c.SMTP.Username, c.SMTP.Password, c.SMTP.From)
+}
+
+// NewLoginGuard возвращает защиту от перебора паролей или nil, если она отключена.
+// Хранилище database сохраняет счетчики между перезапусками и между экземплярами сервиса
+func (c *Config) NewLoginGuard(cache *cache.MemoryCache) *service.LoginGuard {
+	lp := c.LoginProtection
+	if !lp.Enabled {
+		return nil
+	}
+
+	var store service.AttemptStore
+	if lp.Store == "database" {
+		store = repository.NewLoginAttemptRepository(c.Database.DSN)
+	} else {
+		store = service.NewCacheAttemptStore(cache)
+	}
+
+	return service.NewLoginGuard(store, service.LoginPolicy{
+		MaxAttempts:      lp.MaxAttempts,
+		MaxAttemptsPerIP: lp.MaxAttemptsPerIP,
+		FreeAttempts:     lp.FreeAttempts,
+		BaseDelay:        lp.BaseDelay,
+		MaxDelay:         lp.MaxDelay,
+		LockoutDuration:  lp.LockoutDuration,
+		Window:           lp.Window,
+	})
+}
+
+// NewOIDCLogin возвращает настройки входа через OpenID Connect. Без issuer вход отключен
+func (c *Config) NewOIDCLogin() service.OIDCLogin {
+	if c.OIDC.Issuer == "" {
+		return service.OIDCLogin{}
+	}
+
+	return service.OIDCLogin{
+		Provider:      oidc.NewProvider(c.OIDC.Issuer, c.OIDC.ClientID, c.OIDC.ClientSecret, c.OIDC.RedirectURL, c.OIDC.Scopes),
+		LoginClaim:    c.OIDC.LoginClaim,
+		AutoProvision: c.OIDC.AutoProvision,
+	}
+}
+
+// NewPasswordHasher возвращает хеширование паролей алгоритмом password_hashing.algorithm.
+// Хеши второго алгоритма принимаются и пересчитываются при входе
+func (c *Config) NewPasswordHasher() (password.Hasher, error) {
+	ph := c.PasswordHashing
+	argon := password.Argon2id{Memory: ph.Argon2id.Memory, Time: ph.Argon2id.Time, Threads: ph.Argon2id.Threads}
+	bcrypt := password.Bcrypt{Cost: ph.BcryptCost}
+
+	switch ph.Algorithm {
+	case "argon2id":
+		if err := argon.Validate(); err != nil {
+			return nil, err
+		}
+		return password.New(argon, bcrypt), nil
+	case "bcrypt":
+		if err := bcrypt.Validate(); err != nil {
+			return nil, err
+		}
+		return password.New(bcrypt, argon), nil
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm %q", ph.Algorithm)
+	}
+}
+
+// NewCredentialPolicy возвращает требования к логинам и паролям и загружает список скомпрометированных паролей
+func (c *Config) NewCredentialPolicy() (*service.CredentialPolicy, error) {
+	login, pswd := c.CredentialPolicy.Login, c.CredentialPolicy.Password
+
+	if login.MinLength < 1 || login.MaxLength < login.MinLength || login.MaxLength > 255 {
+		return nil, fmt.Errorf("credential policy: login length must be between 1 and 255, got %d-%d", login.MinLength, login.MaxLength)
+	}
+	if login.Charset != "ascii" && login.Charset != "unicode" {
+		return nil, fmt.Errorf("credential policy: unknown login charset %q", login.Charset)
+	}
+	if pswd.MinLength < 1 || (pswd.MaxLength != 0 && pswd.MaxLength < pswd.MinLength) {
+		return nil, fmt.Errorf("credential policy: invalid password length %d-%d", pswd.MinLength, pswd.MaxLength)
+	}
+
+	policy := &service.CredentialPolicy{
+		LoginMinLength:    login.MinLength,
+		LoginMaxLength:    login.MaxLength,
+		LoginCharset:      login.Charset,
+		LoginSymbols:      login.Symbols,
+		EmailLogins:       login.Email,
+		PasswordMinLength: pswd.MinLength,
+		PasswordMaxLength: pswd.MaxLength,
+		RequireUpper:      pswd.RequireUpper,
+		RequireLower:      pswd.RequireLower,
+		RequireDigit:      pswd.RequireDigit,
+		RequireSpecial:    pswd.RequireSpecial,
+	}
+	if pswd.BlocklistFile != "" {
+		if err := policy.LoadBlocklist(pswd.BlocklistFile); err != nil {
+			return nil, err
+		}
+	}
+	return policy, nil
+}
+
+// NewAuthenticators возвращает источники проверки пароля в порядке auth.backends
+func (c *Config) NewAuthenticators(userRepo *repository.UserRepository, hasher password.Hasher, policy *service.CredentialPolicy) ([]service.Authenticator, error) {
+	authenticators := make([]service.Authenticator, 0, len(c.Auth.Backends))
+	for _, backend := range c.Auth.Backends {
+		switch backend {
+		case "local":
+			authenticators = append(authenticators, service.NewPasswordAuthenticator(userRepo, hasher))
+		case "ldap":
+			ldapConfig := service.LDAPConfig{
+				URL:                c.LDAP.URL,
+				StartTLS:           c.LDAP.StartTLS,
+				InsecureSkipVerify: c.LDAP.InsecureSkipVerify,
+				BindDN:             c.LDAP.BindDN,
+				BindPassword:       c.LDAP.BindPassword,
+				BaseDN:             c.LDAP.BaseDN,
+				UserFilter:         c.LDAP.UserFilter,
+				LoginAttribute:     c.LDAP.LoginAttribute,
+				EmailAttribute:     c.LDAP.EmailAttribute,
+				GroupAttribute:     c.LDAP.GroupAttribute,
+				AdminGroups:        c.LDAP.AdminGroups,
+				AllowedGroups:      c.LDAP.AllowedGroups,
+				AutoProvision:      c.LDAP.AutoProvision,
+				Timeout:            c.LDAP.Timeout,
+			}
+			if err := ldapConfig.Validate(); err != nil {
+				return nil, err
+			}
+			authenticators = append(authenticators, service.NewLDAPAuthenticator(ldapConfig, userRepo, policy))
+		default:
+			return nil, fmt.Errorf("unknown auth backend %q", backend)
+		}
+	}
+	return authenticators, nil
 }
 
 // LoadConfigFromFile явно загружает конфигурацию из указанного файла
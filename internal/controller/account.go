@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"context"
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccountController учетная запись текущего пользователя
+type AccountController struct {
+	userService *service.UserService
+	authService *service.AuthService
+}
+
+func NewAccountController(userService *service.UserService, authService *service.AuthService) *AccountController {
+	return &AccountController{
+		userService: userService,
+		authService: authService,
+	}
+}
+
+// GetProfile профиль текущего пользователя
+func (c *AccountController) GetProfile(ctx *fiber.Ctx) error {
+	current, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// В режиме jwt.stateless в контексте только утверждения токена, полный профиль читается из БД
+	user, err := c.userService.GetUserByID(reqCtx, current.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: model.NewUserResponse(user),
+	})
+}
+
+// ChangePassword сменить пароль. Остальные сессии пользователя завершаются
+func (c *AccountController) ChangePassword(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	var currentSessionID string
+	if session, ok := ctx.Locals("session").(*model.Session); ok {
+		currentSessionID = session.ID
+	}
+
+	type ChangePasswordRequest struct {
+		OldPswd string `json:"old_pswd"`
+		NewPswd string `json:"new_pswd"`
+	}
+
+	var req ChangePasswordRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.userService.ChangePassword(reqCtx, user.ID, currentSessionID, req.OldPswd, req.NewPswd); err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			"password": true,
+		},
+	})
+}
+
+// DeleteAccount удалить свою учетную запись. Документы передаются transfer_to или удаляются
+func (c *AccountController) DeleteAccount(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	type DeleteAccountRequest struct {
+		Pswd       string `json:"pswd"`
+		TransferTo string `json:"transfer_to"`
+	}
+
+	var req DeleteAccountRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := c.userService.DeleteAccount(reqCtx, user.ID, req.Pswd, req.TransferTo); err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			user.ID: true,
+		},
+	})
+}
+
+// LinkOIDC начать привязку учетной записи OpenID Connect провайдера: адрес его страницы входа.
+// Переходить на него нужно из браузера, получившего cookie с привязкой state.
+// После возврата от провайдера на /api/auth/oidc/callback учетные записи связываются
+func (c *AccountController) LinkOIDC(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	authURL, binding, err := c.authService.BeginOIDCLink(user, ctx.Get(fiber.HeaderUserAgent))
+	if err != nil {
+		return err
+	}
+	setOIDCStateCookie(ctx, binding)
+
+	return ctx.JSON(model.Response{
+		Data: fiber.Map{"url": authURL},
+	})
+}
+
+// EnrollTOTP начать подключение второго фактора: секрет и ссылка otpauth://
+func (c *AccountController) EnrollTOTP(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	enrollment, err := c.authService.BeginTOTPEnrollment(reqCtx, user)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: enrollment,
+	})
+}
+
+// ConfirmTOTP подтвердить подключение второго фактора кодом из приложения.
+// Коды восстановления показываются только в этом ответе
+func (c *AccountController) ConfirmTOTP(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	type ConfirmTOTPRequest struct {
+		Code string `json:"code"`
+	}
+
+	var req ConfirmTOTPRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	codes, err := c.authService.ConfirmTOTP(reqCtx, user.ID, req.Code)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: fiber.Map{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// DisableTOTP отключить второй фактор (требуется пароль)
+func (c *AccountController) DisableTOTP(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	type DisableTOTPRequest struct {
+		Pswd string `json:"pswd"`
+	}
+
+	var req DisableTOTPRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.authService.DisableTOTP(reqCtx, user.ID, req.Pswd); err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			"totp": false,
+		},
+	})
+}
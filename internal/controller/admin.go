@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"context"
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminController управление пользователями (только для администраторов)
+type AdminController struct {
+	userService *service.UserService
+	authService *service.AuthService
+}
+
+func NewAdminController(userService *service.UserService, authService *service.AuthService) *AdminController {
+	return &AdminController{
+		userService: userService,
+		authService: authService,
+	}
+}
+
+// ListUsers список пользователей с пагинацией
+func (c *AdminController) ListUsers(ctx *fiber.Ctx) error {
+	limit := ctx.QueryInt("limit", 50)
+	offset := ctx.QueryInt("offset", 0)
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	users, total, err := c.userService.ListUsers(reqCtx, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	list := model.UserListResponse{
+		Users: make([]model.UserResponse, 0, len(users)),
+		Total: total,
+	}
+	for _, user := range users {
+		list.Users = append(list.Users, model.NewUserResponse(user))
+	}
+
+	return ctx.JSON(model.Response{
+		Data: list,
+	})
+}
+
+// CreateUser создать пользователя
+func (c *AdminController) CreateUser(ctx *fiber.Ctx) error {
+	type CreateUserRequest struct {
+		Login   string `json:"login"`
+		Pswd    string `json:"pswd"`
+		Email   string `json:"email"`
+		IsAdmin bool   `json:"is_admin"`
+	}
+
+	var req CreateUserRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	user, err := c.authService.CreateUser(req.Login, req.Pswd, req.Email, req.IsAdmin)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: model.NewUserResponse(user),
+	})
+}
+
+// GetUser получить пользователя
+func (c *AdminController) GetUser(ctx *fiber.Ctx) error {
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := c.userService.GetUserByID(reqCtx, ctx.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: model.NewUserResponse(user),
+	})
+}
+
+// UpdateUser переименовать, заблокировать, изменить роль или квоты пользователя
+func (c *AdminController) UpdateUser(ctx *fiber.Ctx) error {
+	type UpdateUserRequest struct {
+		Login          *string `json:"login"`
+		Email          *string `json:"email"`
+		IsAdmin        *bool   `json:"is_admin"`
+		Disabled       *bool   `json:"disabled"`
+		QuotaBytes     *int64  `json:"quota_bytes"`
+		QuotaDocuments *int64  `json:"quota_documents"`
+	}
+
+	var req UpdateUserRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := c.userService.UpdateUser(reqCtx, ctx.Params("id"), &model.UserPatch{
+		Login:          req.Login,
+		Email:          req.Email,
+		IsAdmin:        req.IsAdmin,
+		Disabled:       req.Disabled,
+		QuotaBytes:     req.QuotaBytes,
+		QuotaDocuments: req.QuotaDocuments,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: model.NewUserResponse(user),
+	})
+}
+
+// DeleteUser удалить пользователя. Параметр transfer_to передает его документы
+// другому пользователю, без него документы удаляются
+func (c *AdminController) DeleteUser(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := c.userService.DeleteUser(reqCtx, id, ctx.Query("transfer_to")); err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			id: true,
+		},
+	})
+}
+
+// ResetPassword выдать пользователю токен сброса пароля. Если токен не удалось
+// отправить пользователю, он возвращается в ответе
+func (c *AdminController) ResetPassword(ctx *fiber.Ctx) error {
+	token, sent, err := c.authService.AdminPasswordReset(ctx.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	response := fiber.Map{
+		"sent": sent,
+	}
+	if !sent {
+		response["token"] = token
+	}
+
+	return ctx.JSON(model.Response{
+		Response: response,
+	})
+}
+
+// UnlockUser снять блокировку входа после неудачных попыток
+func (c *AdminController) UnlockUser(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.authService.UnlockUser(reqCtx, id); err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			id: true,
+		},
+	})
+}
+
+// ResetTOTP отключить второй фактор пользователя (например, при утере устройства)
+func (c *AdminController) ResetTOTP(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := c.authService.ResetTOTP(reqCtx, id); err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			id: true,
+		},
+	})
+}
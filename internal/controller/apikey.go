@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"docs-server/internal/model"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateAPIKey создать API-ключ. Ключ возвращается только в этом ответе.
+func (c *AuthController) CreateAPIKey(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	type CreateAPIKeyRequest struct {
+		Name      string     `json:"name"`
+		ReadOnly  bool       `json:"read_only"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	var req CreateAPIKeyRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	key, secret, err := c.authService.CreateAPIKey(user.ID, req.Name, req.ReadOnly, req.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			"key":     secret,
+			"api_key": key,
+		},
+	})
+}
+
+// ListAPIKeys список API-ключей текущего пользователя
+func (c *AuthController) ListAPIKeys(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	keys, err := c.authService.ListAPIKeys(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: fiber.Map{
+			"keys": keys,
+		},
+	})
+}
+
+// RevokeAPIKey отозвать API-ключ
+func (c *AuthController) RevokeAPIKey(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	id := ctx.Params("id")
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "API key ID required")
+	}
+
+	if err := c.authService.RevokeAPIKey(user.ID, id); err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			id: true,
+		},
+	})
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuditController журнал аудита (только для администраторов)
+type AuditController struct {
+	auditService *service.AuditService
+}
+
+func NewAuditController(auditService *service.AuditService) *AuditController {
+	return &AuditController{auditService: auditService}
+}
+
+// ListEvents страница журнала аудита с фильтрами, новые записи первыми
+func (c *AuditController) ListEvents(ctx *fiber.Ctx) error {
+	filter, err := auditFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, total, err := c.auditService.List(reqCtx, filter, ctx.QueryInt("limit", 100), ctx.QueryInt("offset", 0))
+	if err != nil {
+		return err
+	}
+	if events == nil {
+		events = []*model.AuditEvent{}
+	}
+
+	return ctx.JSON(model.Response{
+		Data: model.AuditListResponse{
+			Events: events,
+			Total:  total,
+		},
+	})
+}
+
+// ExportEvents выгрузка журнала аудита по тем же фильтрам в формате JSON Lines
+func (c *AuditController) ExportEvents(ctx *fiber.Ctx) error {
+	filter, err := auditFilter(ctx)
+	if err != nil {
+		return err
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	ctx.Set(fiber.HeaderContentType, "application/x-ndjson")
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="audit-`+time.Now().UTC().Format("20060102-150405")+`.jsonl"`)
+	return c.auditService.Export(reqCtx, filter, ctx.Response().BodyWriter())
+}
+
+// auditFilter фильтр журнала из параметров запроса. from и to в формате RFC 3339
+func auditFilter(ctx *fiber.Ctx) (*model.AuditFilter, error) {
+	filter := &model.AuditFilter{
+		Action:     ctx.Query("action"),
+		Outcome:    ctx.Query("outcome"),
+		Actor:      ctx.Query("actor"),
+		DocumentID: ctx.Query("document"),
+		IP:         ctx.Query("ip"),
+	}
+
+	var err error
+	if filter.From, err = timeQuery(ctx, "from"); err != nil {
+		return nil, err
+	}
+	if filter.To, err = timeQuery(ctx, "to"); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+func timeQuery(ctx *fiber.Ctx, param string) (*time.Time, error) {
+	value := ctx.Query(param)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid "+param+" format")
+	}
+	return &t, nil
+}
+
+// recordAudit записывает действие текущего запроса. Пользователь, IP и User-Agent берутся из запроса,
+// err != nil отмечает действие как неудачное
+func recordAudit(ctx *fiber.Ctx, auditService *service.AuditService, event *model.AuditEvent, err error) {
+	if auditService == nil {
+		return
+	}
+
+	if user, ok := ctx.Locals("user").(*model.User); ok && event.ActorID == "" {
+		event.ActorID = user.ID
+		event.ActorLogin = user.Login
+	}
+	if key, ok := ctx.Locals("api_key").(*model.APIKey); ok {
+		event.Details = withDetail(event.Details, "api_key_id", key.ID)
+	}
+	event.IP = ctx.IP()
+	event.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+
+	if err != nil {
+		event.Outcome = model.AuditFailure
+		event.Details = withDetail(event.Details, "error", err.Error())
+	}
+
+	auditService.Record(event)
+}
+
+func withDetail(details map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details[key] = value
+	return details
+}
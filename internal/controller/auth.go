@@ -4,17 +4,19 @@ import (
 	"docs-server/internal/model"
 	"docs-server/internal/service"
 	"errors"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // AuthController авторизация
 type AuthController struct {
-	authService *service.AuthService
+	authService  *service.AuthService
+	auditService *service.AuditService
 }
 
-func NewAuthController(authService *service.AuthService) *AuthController {
-	return &AuthController{authService: authService}
+func NewAuthController(authService *service.AuthService, auditService *service.AuditService) *AuthController {
+	return &AuthController{authService: authService, auditService: auditService}
 }
 
 func (c *AuthController) GetAuthService() *service.AuthService {
@@ -53,8 +55,9 @@ func (c *AuthController) Register(ctx *fiber.Ctx) error {
 // Authenticate установить авторизацию
 func (c *AuthController) Authenticate(ctx *fiber.Ctx) error {
 	type AuthRequest struct {
-		Login string `json:"login"`
-		Pswd  string `json:"pswd"`
+		Login  string `json:"login"`
+		Pswd   string `json:"pswd"`
+		Device string `json:"device"` // Необязательное имя устройства, по умолчанию User-Agent
 	}
 
 	var req AuthRequest
@@ -62,32 +65,307 @@ func (c *AuthController) Authenticate(ctx *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	token, err := c.authService.Authenticate(req.Login, req.Pswd)
+	device := req.Device
+	if device == "" {
+		device = ctx.Get(fiber.HeaderUserAgent)
+	}
+
+	tokens, challenge, err := c.authService.Authenticate(req.Login, req.Pswd, device, ctx.IP())
+	if err != nil || challenge == nil {
+		// Вход со вторым фактором записывается после проверки кода
+		c.auditLogin(ctx, "password", req.Login, tokens, err)
+	}
+	if err != nil {
+		return err
+	}
+	if challenge != nil {
+		return ctx.JSON(model.Response{
+			Response: challenge,
+		})
+	}
+
+	return ctx.JSON(model.Response{
+		Response: tokens,
+	})
+}
+
+// CompleteMFA второй шаг входа: код из приложения-аутентификатора или код восстановления
+func (c *AuthController) CompleteMFA(ctx *fiber.Ctx) error {
+	type MFARequest struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+
+	var req MFARequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.MFAToken == "" || req.Code == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "MFA token and code required")
+	}
+
+	tokens, err := c.authService.CompleteMFA(req.MFAToken, req.Code, ctx.IP())
+	c.auditLogin(ctx, "totp", "", tokens, err)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: tokens,
+	})
+}
+
+// BeginMFAEnrollment подключение второго фактора во время входа, если его требует политика
+func (c *AuthController) BeginMFAEnrollment(ctx *fiber.Ctx) error {
+	type EnrollRequest struct {
+		MFAToken string `json:"mfa_token"`
+	}
+
+	var req EnrollRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	enrollment, err := c.authService.BeginMFAEnrollment(req.MFAToken)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: enrollment,
+	})
+}
+
+// OIDCLogin перенаправить на страницу входа OpenID Connect провайдера
+func (c *AuthController) OIDCLogin(ctx *fiber.Ctx) error {
+	device := ctx.Query("device")
+	if device == "" {
+		device = ctx.Get(fiber.HeaderUserAgent)
+	}
+
+	authURL, binding, err := c.authService.BeginOIDCLogin(device)
+	if err != nil {
+		return err
+	}
+	setOIDCStateCookie(ctx, binding)
+
+	return ctx.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback завершить вход после возврата от провайдера
+func (c *AuthController) OIDCCallback(ctx *fiber.Ctx) error {
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		return fiber.NewError(fiber.StatusUnauthorized, "Identity provider error: "+providerErr)
+	}
+
+	binding := ctx.Cookies(oidcStateCookie)
+	clearOIDCStateCookie(ctx)
+
+	tokens, challenge, err := c.authService.CompleteOIDCLogin(ctx.Query("state"), binding, ctx.Query("code"), ctx.IP())
+	if err != nil || challenge == nil {
+		c.auditLogin(ctx, "oidc", "", tokens, err)
+	}
+	if err != nil {
+		return err
+	}
+	if challenge != nil {
+		return ctx.JSON(model.Response{
+			Response: challenge,
+		})
+	}
+
+	return ctx.JSON(model.Response{
+		Response: tokens,
+	})
+}
+
+// oidcStateCookie cookie с привязкой state к браузеру, начавшему вход или привязку через провайдера.
+// SameSite=Lax: провайдер возвращает пользователя переходом с другого сайта
+const oidcStateCookie = "oidc_state"
+
+func setOIDCStateCookie(ctx *fiber.Ctx, binding string) {
+	ctx.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    binding,
+		Path:     "/api/auth/oidc",
+		Expires:  time.Now().Add(service.OIDCStateTTL),
+		Secure:   ctx.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+func clearOIDCStateCookie(ctx *fiber.Ctx) {
+	ctx.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/api/auth/oidc",
+		Expires:  time.Unix(0, 0),
+		Secure:   ctx.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// auditLogin записывает результат входа. login - введенный логин, если он известен
+func (c *AuthController) auditLogin(ctx *fiber.Ctx, method, login string, tokens *model.TokenPair, err error) {
+	event := &model.AuditEvent{
+		Action:     model.AuditLogin,
+		ActorLogin: login,
+		Details:    map[string]interface{}{"method": method},
+	}
+	if err != nil {
+		event.Action = model.AuditLoginFailed
+	} else {
+		event.ActorID = tokens.UserID
+		event.Details["session_id"] = tokens.SessionID
+	}
+	recordAudit(ctx, c.auditService, event, err)
+}
+
+// JWKS открытые ключи проверки access-токенов (RFC 7517). Ответ в стандартном формате, без обертки Response
+func (c *AuthController) JWKS(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.JSON(c.authService.JWKS())
+}
+
+// Refresh обменять refresh-токен на новую пару токенов
+func (c *AuthController) Refresh(ctx *fiber.Ctx) error {
+	type RefreshRequest struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	var req RefreshRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.RefreshToken == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Refresh token required")
+	}
+
+	tokens, err := c.authService.Refresh(req.RefreshToken, ctx.IP())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: tokens,
+	})
+}
+
+// Logout выйти из системы (завершить сессию, токен которой передан в заголовке)
+func (c *AuthController) Logout(ctx *fiber.Ctx) error {
+	session, ok := ctx.Locals("session").(*model.Session)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	err := c.authService.Logout(session)
+	recordAudit(ctx, c.auditService, &model.AuditEvent{
+		Action:  model.AuditLogout,
+		Details: map[string]interface{}{"session_id": session.ID},
+	}, err)
 	if err != nil {
 		return err
 	}
 
 	return ctx.JSON(model.Response{
 		Response: fiber.Map{
-			"token": token,
+			session.ID: true,
 		},
 	})
 }
 
-// Logout выйти из системы
-func (c *AuthController) Logout(ctx *fiber.Ctx) error {
-	token := ctx.Params("token")
-	if token == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "Token required")
+// RequestPasswordReset запросить токен сброса пароля. Ответ не зависит от существования логина
+func (c *AuthController) RequestPasswordReset(ctx *fiber.Ctx) error {
+	type ResetRequest struct {
+		Login string `json:"login"`
+	}
+
+	var req ResetRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Login == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Login required")
+	}
+
+	if err := c.authService.RequestPasswordReset(req.Login); err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			"requested": true,
+		},
+	})
+}
+
+// ResetPassword установить новый пароль по токену сброса
+func (c *AuthController) ResetPassword(ctx *fiber.Ctx) error {
+	type ResetPasswordRequest struct {
+		Token   string `json:"token"`
+		NewPswd string `json:"new_pswd"`
+	}
+
+	var req ResetPasswordRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.authService.ResetPassword(req.Token, req.NewPswd); err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			"password": true,
+		},
+	})
+}
+
+// ListSessions список активных сессий текущего пользователя
+func (c *AuthController) ListSessions(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	var currentSessionID string
+	if session, ok := ctx.Locals("session").(*model.Session); ok {
+		currentSessionID = session.ID
+	}
+
+	sessions, err := c.authService.ListSessions(user.ID, currentSessionID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: fiber.Map{
+			"sessions": sessions,
+		},
+	})
+}
+
+// RevokeSession завершить сессию текущего пользователя
+func (c *AuthController) RevokeSession(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	id := ctx.Params("id")
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Session ID required")
 	}
 
-	if err := c.authService.Logout(token); err != nil {
+	if err := c.authService.RevokeSession(user.ID, id); err != nil {
 		return err
 	}
 
 	return ctx.JSON(model.Response{
 		Response: fiber.Map{
-			token: true,
+			id: true,
 		},
 	})
 }
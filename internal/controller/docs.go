@@ -3,28 +3,32 @@ package controller
 import (
 	"docs-server/internal/model"
 	"docs-server/internal/service"
+	"encoding/json"
 	"io"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type DocsController struct {
-	docService  *service.DocumentService
-	userService *service.UserService
+	docService   *service.DocumentService
+	userService  *service.UserService
+	auditService *service.AuditService
 }
 
-func NewDocsController(docService *service.DocumentService, userService *service.UserService) *DocsController {
+func NewDocsController(docService *service.DocumentService, userService *service.UserService, auditService *service.AuditService) *DocsController {
 	return &DocsController{
-		docService:  docService,
-		userService: userService,
+		docService:   docService,
+		userService:  userService,
+		auditService: auditService,
 	}
 }
 
 // UploadDocument Метод загрузки документа
 func (c *DocsController) UploadDocument(ctx *fiber.Ctx) error {
-	// Получение токена из заголовков
-	token := ctx.Get("Authorization")
-	if token == "" {
+	// Пользователь, установленный AuthMiddleware
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
 		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
 	}
 
@@ -64,7 +68,8 @@ func (c *DocsController) UploadDocument(ctx *fiber.Ctx) error {
 	}
 
 	// Вызов сервиса с преобразованными файлами
-	doc, err := c.docService.UploadDocument(token, meta[0], uploadedFiles)
+	doc, err := c.docService.UploadDocument(user, meta[0], uploadedFiles)
+	c.auditUpload(ctx, doc, err)
 	if err != nil {
 		return err
 	}
@@ -79,8 +84,8 @@ func (c *DocsController) UploadDocument(ctx *fiber.Ctx) error {
 
 // GetDocumentsList Получить список документов
 func (c *DocsController) GetDocumentsList(ctx *fiber.Ctx) error {
-	token := ctx.Get("Authorization")
-	if token == "" {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
 		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
 	}
 
@@ -90,7 +95,7 @@ func (c *DocsController) GetDocumentsList(ctx *fiber.Ctx) error {
 	value := ctx.Query("value")
 	limit := ctx.QueryInt("limit", 10)
 
-	docs, err := c.docService.GetDocumentsList(token, login, key, value, limit)
+	docs, err := c.docService.GetDocumentsList(user, login, key, value, limit)
 	if err != nil {
 		return err
 	}
@@ -104,8 +109,8 @@ func (c *DocsController) GetDocumentsList(ctx *fiber.Ctx) error {
 
 // GetDocument Получить документ
 func (c *DocsController) GetDocument(ctx *fiber.Ctx) error {
-	token := ctx.Get("Authorization")
-	if token == "" {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
 		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
 	}
 
@@ -114,7 +119,12 @@ func (c *DocsController) GetDocument(ctx *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Document ID required")
 	}
 
-	doc, err := c.docService.GetDocument(token, id)
+	doc, err := c.docService.GetDocument(user, id)
+	action := model.AuditDocumentRead
+	if err == nil && doc.File {
+		action = model.AuditDocumentDownload
+	}
+	recordAudit(ctx, c.auditService, &model.AuditEvent{Action: action, DocumentID: id}, err)
 	if err != nil {
 		return err
 	}
@@ -130,10 +140,60 @@ func (c *DocsController) GetDocument(ctx *fiber.Ctx) error {
 	})
 }
 
+// UpdateDocument Изменить срок хранения и юридическое удержание документа
+func (c *DocsController) UpdateDocument(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	id := ctx.Params("id")
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Document ID required")
+	}
+
+	type UpdateRequest struct {
+		ExpiresAt json.RawMessage `json:"expires_at"` // null снимает срок хранения
+		LegalHold *bool           `json:"legal_hold"`
+	}
+
+	var req UpdateRequest
+	if err := json.Unmarshal(ctx.Body(), &req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	patch := &model.DocumentPatch{LegalHold: req.LegalHold}
+	if len(req.ExpiresAt) > 0 {
+		if string(req.ExpiresAt) == "null" {
+			patch.ClearExpiry = true
+		} else {
+			var expiresAt time.Time
+			if err := json.Unmarshal(req.ExpiresAt, &expiresAt); err != nil {
+				return fiber.NewError(fiber.StatusBadRequest, "Invalid expires_at format")
+			}
+			patch.ExpiresAt = &expiresAt
+		}
+	}
+
+	doc, err := c.docService.UpdateDocument(user, id, patch)
+	recordAudit(ctx, c.auditService, &model.AuditEvent{
+		Action:     model.AuditDocumentUpdate,
+		DocumentID: id,
+		Details:    documentPatchDetails(patch),
+	}, err)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: doc,
+	})
+}
+
 // DeleteDocument Удалить документ
 func (c *DocsController) DeleteDocument(ctx *fiber.Ctx) error {
-	token := ctx.Get("Authorization")
-	if token == "" {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
 		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
 	}
 
@@ -142,7 +202,8 @@ func (c *DocsController) DeleteDocument(ctx *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Document ID required")
 	}
 
-	success, err := c.docService.DeleteDocument(token, id)
+	success, err := c.docService.DeleteDocument(user, id)
+	recordAudit(ctx, c.auditService, &model.AuditEvent{Action: model.AuditDocumentDelete, DocumentID: id}, err)
 	if err != nil {
 		return err
 	}
@@ -153,3 +214,60 @@ func (c *DocsController) DeleteDocument(ctx *fiber.Ctx) error {
 		},
 	})
 }
+
+// auditUpload записывает загрузку документа, а если при загрузке выданы права доступа - и их изменение
+func (c *DocsController) auditUpload(ctx *fiber.Ctx, doc *model.Document, err error) {
+	if err != nil {
+		recordAudit(ctx, c.auditService, &model.AuditEvent{Action: model.AuditDocumentUpload}, err)
+		return
+	}
+
+	recordAudit(ctx, c.auditService, &model.AuditEvent{
+		Action:     model.AuditDocumentUpload,
+		DocumentID: doc.ID,
+		Details: map[string]interface{}{
+			"name":   doc.Name,
+			"mime":   doc.Mime,
+			"size":   doc.Size,
+			"public": doc.Public,
+		},
+	}, nil)
+	if len(doc.Grant) > 0 {
+		recordAudit(ctx, c.auditService, &model.AuditEvent{
+			Action:     model.AuditGrantChange,
+			DocumentID: doc.ID,
+			Details:    map[string]interface{}{"granted": doc.Grant},
+		}, nil)
+	}
+}
+
+// documentPatchDetails измененные поля документа для журнала аудита
+func documentPatchDetails(patch *model.DocumentPatch) map[string]interface{} {
+	details := make(map[string]interface{})
+	if patch.LegalHold != nil {
+		details["legal_hold"] = *patch.LegalHold
+	}
+	if patch.ClearExpiry {
+		details["expires_at"] = nil
+	} else if patch.ExpiresAt != nil {
+		details["expires_at"] = patch.ExpiresAt.UTC()
+	}
+	return details
+}
+
+// GetUsage Получить использование хранилища текущим пользователем
+func (c *DocsController) GetUsage(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	usage, err := c.docService.GetUsage(user)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: usage,
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"docs-server/internal/model"
 	"docs-server/internal/service"
 	"errors"
+	"math"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -25,46 +27,123 @@ func UnifiedErrorHandler(ctx *fiber.Ctx) error {
 
 	// Обрабатываем стандартные Fiber ошибки
 	var fiberErr *fiber.Error
+	var lockoutErr *service.LockoutError
+	var validationErr *service.ValidationError
 	if errors.As(err, &fiberErr) {
 		status = fiberErr.Code
 		message = fiberErr.Message
+	} else if errors.As(err, &lockoutErr) {
+		status, message = fiber.StatusTooManyRequests, err.Error()
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(lockoutErr.RetryAfter.Seconds()))))
+	} else if errors.As(err, &validationErr) {
+		// Логин или пароль не соответствует политике: в ответе поле и нарушенное правило
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.Response{
+			Data: fiber.Map{
+				"code":    fiber.StatusBadRequest,
+				"message": validationErr.Message,
+				"field":   validationErr.Field,
+				"rule":    validationErr.Rule,
+			},
+		})
 	} else {
 		// Обрабатываем кастомные ошибки
 		switch {
 		// Аутентификация
-		case errors.Is(err, service.ErrInvalidAdminToken):
+		case errors.Is(err, service.ErrInvalidAdminToken),
+			errors.Is(err, service.ErrAdminExists),
+			errors.Is(err, service.ErrUserDisabled):
 			status, message = fiber.StatusForbidden, err.Error()
 		case errors.Is(err, service.ErrInvalidCredentials),
 			errors.Is(err, service.ErrTokenExpired),
-			errors.Is(err, service.ErrInvalidToken):
+			errors.Is(err, service.ErrInvalidToken),
+			errors.Is(err, service.ErrInvalidRefreshToken),
+			errors.Is(err, service.ErrRefreshTokenReused),
+			errors.Is(err, service.ErrSessionRevoked),
+			errors.Is(err, service.ErrInvalidMFAToken),
+			errors.Is(err, service.ErrOIDCLoginFailed),
+			errors.Is(err, service.ErrInvalidMFACode),
+			errors.Is(err, service.ErrInvalidAPIKey),
+			errors.Is(err, service.ErrAPIKeyExpired):
 			status, message = fiber.StatusUnauthorized, err.Error()
+		case errors.Is(err, service.ErrSessionNotFound),
+			errors.Is(err, service.ErrAPIKeyNotFound):
+			status, message = fiber.StatusNotFound, err.Error()
+		case errors.Is(err, service.ErrAPIKeyReadOnly):
+			status, message = fiber.StatusForbidden, err.Error()
+		case errors.Is(err, service.ErrAPIKeyNameEmpty),
+			errors.Is(err, service.ErrInvalidTOTPCode),
+			errors.Is(err, service.ErrTOTPNotEnrolled):
+			status, message = fiber.StatusBadRequest, err.Error()
+		case errors.Is(err, service.ErrInvalidOIDCState):
+			status, message = fiber.StatusBadRequest, err.Error()
+		case errors.Is(err, service.ErrOIDCUnknownUser):
+			status, message = fiber.StatusForbidden, err.Error()
+		case errors.Is(err, service.ErrOIDCNotLinked),
+			errors.Is(err, service.ErrOIDCLinked),
+			errors.Is(err, service.ErrOIDCLDAPUser):
+			status, message = fiber.StatusConflict, err.Error()
+		case errors.Is(err, service.ErrOIDCDisabled):
+			status, message = fiber.StatusNotFound, err.Error()
+		case errors.Is(err, service.ErrTOTPAlreadyEnabled):
+			status, message = fiber.StatusConflict, err.Error()
+		case errors.Is(err, service.ErrTOTPRequired):
+			status, message = fiber.StatusForbidden, err.Error()
 
 		// Документы
 		case errors.Is(err, service.ErrDocumentNameRequired),
 			errors.Is(err, service.ErrInvalidMetaFormat),
-			errors.Is(err, service.ErrInvalidDocumentData):
+			errors.Is(err, service.ErrInvalidDocumentData),
+			errors.Is(err, service.ErrInvalidExpiry):
 			status, message = fiber.StatusBadRequest, err.Error()
 		case errors.Is(err, service.ErrDocumentNotFound):
 			status, message = fiber.StatusNotFound, err.Error()
 		case errors.Is(err, service.ErrPermissionDenied),
 			errors.Is(err, service.ErrNotDocumentOwner),
+			errors.Is(err, service.ErrLegalHoldAdminOnly),
 			errors.Is(err, service.ErrForbidden):
 			status, message = fiber.StatusForbidden, err.Error()
 		case errors.Is(err, service.ErrFailedToCreateDir),
 			errors.Is(err, service.ErrFailedToSaveFile),
 			errors.Is(err, service.ErrFailedToDeleteFile):
 			status, message = fiber.StatusInternalServerError, err.Error()
+		case errors.Is(err, service.ErrLegalHold):
+			status, message = fiber.StatusConflict, err.Error()
+		case errors.Is(err, service.ErrFileTooLarge):
+			status, message = fiber.StatusRequestEntityTooLarge, err.Error()
+		case errors.Is(err, service.ErrQuotaExceeded):
+			status, message = fiber.StatusInsufficientStorage, err.Error()
+
+		// Webhook
+		case errors.Is(err, service.ErrInvalidWebhookURL),
+			errors.Is(err, service.ErrInvalidWebhookEvent):
+			status, message = fiber.StatusBadRequest, err.Error()
+		case errors.Is(err, service.ErrWebhookNotFound),
+			errors.Is(err, service.ErrDeliveryNotFound):
+			status, message = fiber.StatusNotFound, err.Error()
 
 		// Пользователи
 		case errors.Is(err, service.ErrUserIDEmpty),
 			errors.Is(err, service.ErrLoginEmpty),
 			errors.Is(err, service.ErrUserNil):
 			status, message = fiber.StatusBadRequest, err.Error()
+		case errors.Is(err, service.ErrResetUnavailable):
+			status, message = fiber.StatusServiceUnavailable, err.Error()
 		case errors.Is(err, service.ErrUserNotFound):
 			status, message = fiber.StatusNotFound, err.Error()
 		case errors.Is(err, service.ErrInvalidLimit),
-			errors.Is(err, service.ErrInvalidOffset):
+			errors.Is(err, service.ErrInvalidOffset),
+			errors.Is(err, service.ErrSameUser),
+			errors.Is(err, service.ErrInvalidAuditFilter):
+			status, message = fiber.StatusBadRequest, err.Error()
+		case errors.Is(err, service.ErrWrongPassword):
+			status, message = fiber.StatusForbidden, err.Error()
+		case errors.Is(err, service.ErrSamePassword),
+			errors.Is(err, service.ErrInvalidResetToken),
+			errors.Is(err, service.ErrInvalidEmail):
 			status, message = fiber.StatusBadRequest, err.Error()
+		case errors.Is(err, service.ErrLoginTaken),
+			errors.Is(err, service.ErrLastAdmin):
+			status, message = fiber.StatusConflict, err.Error()
 		}
 	}
 
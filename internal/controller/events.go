@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EventsController поток событий документов в формате Server-Sent Events
+type EventsController struct {
+	stream    *service.EventStream
+	heartbeat time.Duration
+}
+
+func NewEventsController(stream *service.EventStream, heartbeat time.Duration) *EventsController {
+	return &EventsController{
+		stream:    stream,
+		heartbeat: heartbeat,
+	}
+}
+
+// Stream передает события документов, доступных пользователю: своих, выданных ему и публичных.
+// После переподключения поток продолжается с события из заголовка Last-Event-ID (или параметра last_event_id).
+// Авторизация повторяется с периодом heartbeat; если она не проходит, поток завершается событием revoked
+func (c *EventsController) Stream(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	lastEventID := ctx.Get("Last-Event-ID", ctx.Query("last_event_id"))
+	var lastID int64
+	if lastEventID != "" {
+		var err error
+		if lastID, err = strconv.ParseInt(lastEventID, 10, 64); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid Last-Event-ID")
+		}
+	}
+
+	// Подписка до определения позиции, чтобы не пропустить события между ними
+	wake, unsubscribe := c.stream.Subscribe()
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, reset, err := c.stream.Resume(reqCtx, lastID, lastEventID != "")
+	if err != nil {
+		unsubscribe()
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set(fiber.HeaderConnection, "keep-alive")
+	ctx.Set("X-Accel-Buffering", "no") // Отключает буферизацию ответа в nginx
+
+	reauthorize, _ := ctx.Locals("reauthorize").(Reauthorize)
+
+	userID := user.ID
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		heartbeat := time.NewTicker(c.heartbeat)
+		defer heartbeat.Stop()
+		authorized := time.Now()
+
+		fmt.Fprintf(w, "retry: 3000\n\n")
+		if reset {
+			fmt.Fprintf(w, "event: reset\ndata: {}\n\n")
+		}
+
+		for {
+			// Проверка по времени, а не по тику: при непрерывном потоке событий тики не обрабатываются
+			if reauthorize != nil && time.Since(authorized) >= c.heartbeat {
+				if err := reauthorize(); err != nil {
+					fmt.Fprintf(w, "event: revoked\ndata: {}\n\n")
+					w.Flush()
+					return
+				}
+				authorized = time.Now()
+			}
+
+			readCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			events, next, err := c.stream.Read(readCtx, userID, cursor)
+			cancel()
+			if errors.Is(err, service.ErrEventsLost) {
+				fmt.Fprintf(w, "event: reset\ndata: {}\n\n")
+			} else if err != nil {
+				log.Printf("event stream: %v", err)
+			}
+
+			for _, event := range events {
+				if err := writeEvent(w, event); err != nil {
+					log.Printf("event stream: %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return // Клиент отключился
+			}
+
+			// Журнал прочитан не до конца - следующая страница сразу
+			if next != cursor {
+				cursor = next
+				continue
+			}
+
+			select {
+			case <-wake:
+			case <-heartbeat.C:
+				fmt.Fprintf(w, ": ping\n\n")
+			}
+		}
+	})
+
+	return nil
+}
+
+// writeEvent записывает событие в формате text/event-stream
+func writeEvent(w *bufio.Writer, event *model.DocumentEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+	return err
+}
@@ -4,16 +4,19 @@ import (
 	"docs-server/internal/model"
 	"docs-server/internal/service"
 	"errors"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// AuthMiddleware проверяет JWT токен и добавляет пользователя в контекст
-func AuthMiddleware(authService *service.AuthService) fiber.Handler {
+// AuthMiddleware проверяет JWT токен или API-ключ и добавляет пользователя в контекст.
+// allowRawToken разрешает устаревшую передачу токена без схемы Bearer
+func AuthMiddleware(authService *service.AuthService, allowRawToken bool) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		// Получаем токен из заголовка Authorization
-		token := ctx.Get("Authorization")
-		if token == "" {
+		token, ok := bearerToken(ctx.Get(fiber.HeaderAuthorization), allowRawToken)
+		if !ok {
+			ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="docs-server"`)
 			return ctx.Status(fiber.StatusUnauthorized).JSON(model.Response{
 				Data: fiber.Map{
 					"code":    fiber.StatusUnauthorized,
@@ -22,16 +25,63 @@ func AuthMiddleware(authService *service.AuthService) fiber.Handler {
 			})
 		}
 
+		// API-ключ вместо JWT
+		if service.IsAPIKey(token) {
+			user, key, err := authService.ValidateAPIKey(token)
+			if err != nil {
+				message := "Invalid API key"
+				switch {
+				case errors.Is(err, service.ErrAPIKeyExpired):
+					message = "API key expired"
+				case errors.Is(err, service.ErrUserDisabled):
+					message = "User disabled"
+				}
+
+				ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="docs-server", error="invalid_token"`)
+				return ctx.Status(fiber.StatusUnauthorized).JSON(model.Response{
+					Data: fiber.Map{
+						"code":    fiber.StatusUnauthorized,
+						"message": message,
+					},
+				})
+			}
+
+			// Ключ только для чтения допускает лишь безопасные методы
+			if key.ReadOnly && ctx.Method() != fiber.MethodGet && ctx.Method() != fiber.MethodHead {
+				return ctx.Status(fiber.StatusForbidden).JSON(model.Response{
+					Data: fiber.Map{
+						"code":    fiber.StatusForbidden,
+						"message": service.ErrAPIKeyReadOnly.Error(),
+					},
+				})
+			}
+
+			ctx.Locals("user", user)
+			ctx.Locals("api_key", key)
+			ctx.Locals("reauthorize", Reauthorize(func() error {
+				_, _, err := authService.ValidateAPIKey(token)
+				return err
+			}))
+
+			return ctx.Next()
+		}
+
 		// Валидируем токен
-		user, err := authService.ValidateToken(token)
+		user, session, err := authService.ValidateToken(token)
 		if err != nil {
 			statusCode := fiber.StatusUnauthorized
 			message := "Invalid token"
 
-			if errors.Is(err, service.ErrTokenExpired) {
+			switch {
+			case errors.Is(err, service.ErrTokenExpired):
 				message = "Token expired"
+			case errors.Is(err, service.ErrSessionRevoked):
+				message = "Session revoked"
+			case errors.Is(err, service.ErrUserDisabled):
+				message = "User disabled"
 			}
 
+			ctx.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="docs-server", error="invalid_token"`)
 			return ctx.Status(statusCode).JSON(model.Response{
 				Data: fiber.Map{
 					"code":    statusCode,
@@ -40,9 +90,60 @@ func AuthMiddleware(authService *service.AuthService) fiber.Handler {
 			})
 		}
 
-		// Добавляем пользователя в контекст
+		// Добавляем пользователя и сессию в контекст
 		ctx.Locals("user", user)
+		ctx.Locals("session", session)
+		ctx.Locals("reauthorize", Reauthorize(func() error {
+			_, _, err := authService.ValidateToken(token)
+			return err
+		}))
 
 		return ctx.Next()
 	}
 }
+
+// Reauthorize повторно проверяет токен или API-ключ запроса. Используется долгими
+// соединениями, чтобы прекратить ответ после выхода, отзыва сессии, блокировки или истечения токена
+type Reauthorize func() error
+
+// SessionOnly запрещает доступ по API-ключу (например, к управлению самими ключами)
+func SessionOnly(ctx *fiber.Ctx) error {
+	if _, ok := ctx.Locals("api_key").(*model.APIKey); ok {
+		return fiber.NewError(fiber.StatusForbidden, "This endpoint requires a user session")
+	}
+	return ctx.Next()
+}
+
+// AdminOnly допускает только администраторов
+func AdminOnly(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok || !user.IsAdmin {
+		return fiber.NewError(fiber.StatusForbidden, "Admin role required")
+	}
+	return ctx.Next()
+}
+
+// bearerToken извлекает токен из заголовка Authorization по RFC 6750 ("Bearer <token>").
+// Схема не чувствительна к регистру; при allowRaw принимается и токен без схемы
+func bearerToken(header string, allowRaw bool) (string, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", false
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		token = strings.TrimLeft(token, " ")
+		if token == "" || strings.ContainsAny(token, " \t") {
+			return "", false
+		}
+		return token, true
+	}
+
+	// Устаревший формат: токен целиком в заголовке
+	if allowRaw && !found {
+		return header, true
+	}
+
+	return "", false
+}
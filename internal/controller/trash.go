@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"docs-server/internal/model"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetTrash Получить список документов в корзине
+func (c *DocsController) GetTrash(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	limit := ctx.QueryInt("limit", 10)
+
+	docs, err := c.docService.GetTrash(user, limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: fiber.Map{
+			"docs": docs,
+		},
+	})
+}
+
+// RestoreDocument Восстановить документ из корзины
+func (c *DocsController) RestoreDocument(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	id := ctx.Params("id")
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Document ID required")
+	}
+
+	doc, err := c.docService.RestoreDocument(user, id)
+	recordAudit(ctx, c.auditService, &model.AuditEvent{Action: model.AuditDocumentRestore, DocumentID: id}, err)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: doc,
+	})
+}
+
+// PurgeDocument Окончательно удалить документ из корзины
+func (c *DocsController) PurgeDocument(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	id := ctx.Params("id")
+	if id == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "Document ID required")
+	}
+
+	success, err := c.docService.PurgeDocument(user, id)
+	recordAudit(ctx, c.auditService, &model.AuditEvent{Action: model.AuditDocumentPurge, DocumentID: id}, err)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			id: success,
+		},
+	})
+}
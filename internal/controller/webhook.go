@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"docs-server/internal/model"
+	"docs-server/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// WebhookController управление webhook. Экземпляр с global работает с глобальными webhook
+// администраторов, без него - с webhook текущего пользователя
+type WebhookController struct {
+	webhookService *service.WebhookService
+	global         bool
+}
+
+func NewWebhookController(webhookService *service.WebhookService, global bool) *WebhookController {
+	return &WebhookController{webhookService: webhookService, global: global}
+}
+
+// CreateWebhook зарегистрировать webhook. Ключ подписи возвращается только в этом ответе
+func (c *WebhookController) CreateWebhook(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	type CreateWebhookRequest struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	var req CreateWebhookRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	hook, secret, err := c.webhookService.CreateWebhook(user.ID, c.global, req.URL, req.Events)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			"secret":  secret,
+			"webhook": hook,
+		},
+	})
+}
+
+// ListWebhooks список webhook
+func (c *WebhookController) ListWebhooks(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	hooks, err := c.webhookService.ListWebhooks(user.ID, c.global)
+	if err != nil {
+		return err
+	}
+	if hooks == nil {
+		hooks = []*model.Webhook{}
+	}
+
+	return ctx.JSON(model.Response{
+		Data: fiber.Map{
+			"webhooks": hooks,
+		},
+	})
+}
+
+// DeleteWebhook удалить webhook
+func (c *WebhookController) DeleteWebhook(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	id := ctx.Params("id")
+	if err := c.webhookService.DeleteWebhook(user.ID, c.global, id); err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Response: fiber.Map{
+			id: true,
+		},
+	})
+}
+
+// ListDeliveries журнал доставок webhook, новые первыми
+func (c *WebhookController) ListDeliveries(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	deliveries, err := c.webhookService.ListDeliveries(user.ID, c.global, ctx.Params("id"), ctx.QueryInt("limit", 50))
+	if err != nil {
+		return err
+	}
+	if deliveries == nil {
+		deliveries = []*model.WebhookDelivery{}
+	}
+
+	return ctx.JSON(model.Response{
+		Data: fiber.Map{
+			"deliveries": deliveries,
+		},
+	})
+}
+
+// RedeliverDelivery повторно отправить событие доставки
+func (c *WebhookController) RedeliverDelivery(ctx *fiber.Ctx) error {
+	user, ok := ctx.Locals("user").(*model.User)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authorization token required")
+	}
+
+	delivery, err := c.webhookService.Redeliver(user.ID, c.global, ctx.Params("id"), ctx.Params("delivery"))
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(model.Response{
+		Data: delivery,
+	})
+}
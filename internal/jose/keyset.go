@@ -0,0 +1,223 @@
+package jose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key ключ подписи JWT. У ключей только для проверки signKey не задан
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet ключ для подписи новых токенов и ключи для проверки, включая прежние.
+// При ротации новый ключ становится ключом подписи, а прежний остается в наборе,
+// пока не истекут подписанные им токены
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewHMACKeySet набор из одного симметричного ключа HS256. Токены подписываются без kid
+func NewHMACKeySet(secret []byte) (*KeySet, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("hmac secret cannot be empty")
+	}
+
+	key := &Key{Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+	return &KeySet{
+		signing: key,
+		keys:    map[string]*Key{"": key},
+	}, nil
+}
+
+// LoadKeySet загружает ключ подписи и ключи проверки из PEM-файлов.
+// Если задан hmacSecret, токены HS256 без kid, выданные до перехода на асимметричные ключи, тоже принимаются
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string, hmacSecret []byte) (*KeySet, error) {
+	set := &KeySet{keys: make(map[string]*Key)}
+
+	signing, err := loadKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if signing.signKey == nil {
+		return nil, fmt.Errorf("%s: signing key must be a private key", signingKeyFile)
+	}
+	set.signing = signing
+	set.keys[signing.ID] = signing
+
+	for _, file := range verificationKeyFiles {
+		key, err := loadKey(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := set.keys[key.ID]; !exists {
+			set.keys[key.ID] = key
+		}
+	}
+
+	if len(hmacSecret) > 0 {
+		set.keys[""] = &Key{Method: jwt.SigningMethodHS256, verifyKey: hmacSecret}
+	}
+
+	return set, nil
+}
+
+// LoadVerificationKeySet набор только для проверки подписей, например открытыми ключами.
+// Подписывать им нельзя
+func LoadVerificationKeySet(files []string) (*KeySet, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no verification keys")
+	}
+
+	set := &KeySet{keys: make(map[string]*Key)}
+	for _, file := range files {
+		key, err := loadKey(file)
+		if err != nil {
+			return nil, err
+		}
+		set.keys[key.ID] = key
+	}
+	return set, nil
+}
+
+// Sign подписывает утверждения текущим ключом подписи
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if s.signing == nil {
+		return "", errors.New("key set has no signing key")
+	}
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	if s.signing.ID != "" {
+		token.Header["kid"] = s.signing.ID
+	}
+	return token.SignedString(s.signing.signKey)
+}
+
+// Keyfunc выбирает ключ проверки по kid. Алгоритм токена должен совпадать с алгоритмом ключа,
+// иначе открытый ключ можно было бы использовать как секрет HMAC
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q for key %q", token.Method.Alg(), kid)
+	}
+	return key.verifyKey, nil
+}
+
+// Methods алгоритмы ключей набора для jwt.WithValidMethods
+func (s *KeySet) Methods() []string {
+	seen := make(map[string]bool)
+	methods := make([]string, 0, len(s.keys))
+	for _, key := range s.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+// JWKS открытые ключи набора. Симметричные ключи не публикуются
+func (s *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range s.keys {
+		if key.ID == "" {
+			continue
+		}
+		jwk, err := NewJWK(key.ID, key.Method.Alg(), key.verifyKey)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// loadKey читает закрытый или открытый ключ из PEM-файла. kid - отпечаток открытого ключа (RFC 7638)
+func loadKey(file string) (*Key, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", file)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", file, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	key := &Key{}
+	var public crypto.PublicKey
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.signKey = signer
+		public = signer.Public()
+	} else {
+		public = parsed
+	}
+
+	switch public := public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: rsa key must be at least 2048 bits", file)
+		}
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		switch public.Curve.Params().Name {
+		case "P-256":
+			key.Method = jwt.SigningMethodES256
+		case "P-384":
+			key.Method = jwt.SigningMethodES384
+		default:
+			return nil, fmt.Errorf("%s: unsupported curve %s", file, public.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T", file, public)
+	}
+	key.verifyKey = public
+
+	jwk, err := NewJWK("", key.Method.Alg(), public)
+	if err != nil {
+		return nil, err
+	}
+	if key.ID, err = jwk.Thumbprint(); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,141 @@
+package jose
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims утверждения токена. Стандартные поля заполняет и проверяет TokenIssuer
+type Claims interface {
+	jwt.Claims
+	Registered() *jwt.RegisteredClaims
+}
+
+// TokenConfig параметры выпуска и проверки токенов
+type TokenConfig struct {
+	Issuer     string           // Значение iss, проверяется при разборе
+	Audience   string           // Получатель по умолчанию (aud)
+	TTL        time.Duration    // Срок действия access-токена
+	RefreshTTL time.Duration    // Срок действия refresh-токена
+	Leeway     time.Duration    // Допустимое расхождение часов при проверке exp, nbf и iat
+	Clock      func() time.Time // Источник времени, nil - time.Now
+}
+
+// TokenIssuer выпускает и проверяет подписанные токены сервиса
+type TokenIssuer struct {
+	keys *KeySet
+	cfg  TokenConfig
+}
+
+func NewTokenIssuer(keys *KeySet, cfg TokenConfig) (*TokenIssuer, error) {
+	if keys == nil {
+		return nil, errors.New("token keys cannot be empty")
+	}
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("token issuer and audience are required")
+	}
+	if cfg.TTL <= 0 || cfg.RefreshTTL <= 0 {
+		return nil, errors.New("token ttl must be positive")
+	}
+	if cfg.Leeway < 0 {
+		return nil, errors.New("token leeway cannot be negative")
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = time.Now
+	}
+
+	return &TokenIssuer{keys: keys, cfg: cfg}, nil
+}
+
+// Issue заполняет iss, aud, iat, exp и jti и подписывает токен текущим ключом.
+// Пустой audience - получатель по умолчанию, нулевой ttl - срок access-токена
+func (t *TokenIssuer) Issue(claims Claims, audience string, ttl time.Duration) (string, time.Time, error) {
+	if audience == "" {
+		audience = t.cfg.Audience
+	}
+	if ttl == 0 {
+		ttl = t.cfg.TTL
+	}
+
+	now := t.cfg.Clock()
+	expiresAt := now.Add(ttl)
+
+	registered := claims.Registered()
+	registered.Issuer = t.cfg.Issuer
+	registered.Audience = jwt.ClaimStrings{audience}
+	registered.IssuedAt = jwt.NewNumericDate(now)
+	registered.ExpiresAt = jwt.NewNumericDate(expiresAt)
+	if registered.ID == "" {
+		id, err := randomID()
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		registered.ID = id
+	}
+
+	token, err := t.keys.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// Verify проверяет подпись, издателя, получателя и срок действия с учетом leeway и заполняет claims.
+// Ошибки оборачивают ErrInvalidToken и ошибки jwt (например, jwt.ErrTokenExpired)
+func (t *TokenIssuer) Verify(tokenString string, claims Claims, audience string) error {
+	if audience == "" {
+		audience = t.cfg.Audience
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, t.keys.Keyfunc,
+		jwt.WithValidMethods(t.keys.Methods()),
+		jwt.WithIssuer(t.cfg.Issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(t.cfg.Leeway),
+		jwt.WithTimeFunc(t.cfg.Clock),
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if !token.Valid {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// Expired сообщает, истек ли срок с учетом допустимого расхождения часов
+func (t *TokenIssuer) Expired(expiresAt time.Time) bool {
+	return t.cfg.Clock().After(expiresAt.Add(t.cfg.Leeway))
+}
+
+// Leeway допустимое расхождение часов
+func (t *TokenIssuer) Leeway() time.Duration {
+	return t.cfg.Leeway
+}
+
+// RefreshTTL срок действия refresh-токена
+func (t *TokenIssuer) RefreshTTL() time.Duration {
+	return t.cfg.RefreshTTL
+}
+
+// JWKS открытые ключи проверки токенов
+func (t *TokenIssuer) JWKS() JWKSet {
+	return t.keys.JWKS()
+}
+
+func randomID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Действия, записываемые в журнал аудита
+const (
+	AuditLogin            = "auth.login"
+	AuditLoginFailed      = "auth.login_failed"
+	AuditLogout           = "auth.logout"
+	AuditDocumentUpload   = "document.upload"
+	AuditDocumentRead     = "document.read"
+	AuditDocumentDownload = "document.download"
+	AuditDocumentUpdate   = "document.update"
+	AuditDocumentDelete   = "document.delete"
+	AuditDocumentRestore  = "document.restore"
+	AuditDocumentPurge    = "document.purge"
+	AuditGrantChange      = "grant.change"
+)
+
+// Результат действия
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEvent запись журнала аудита
+type AuditEvent struct {
+	ID         int64                  `json:"id"`
+	Time       time.Time              `json:"time"`
+	Action     string                 `json:"action"`
+	Outcome    string                 `json:"outcome"`
+	ActorID    string                 `json:"actor_id,omitempty"`
+	ActorLogin string                 `json:"actor_login,omitempty"` // Для неудачного входа - введенный логин
+	IP         string                 `json:"ip,omitempty"`
+	UserAgent  string                 `json:"user_agent,omitempty"`
+	DocumentID string                 `json:"document_id,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	PrevHash   string                 `json:"prev_hash,omitempty"` // Хеш предыдущей записи цепочки
+	Hash       string                 `json:"hash,omitempty"`      // Пустой у записей, добавленных до включения цепочки
+}
+
+// ChainHash хеш записи в цепочке: SHA-256 от хеша предыдущей записи и полей записи, кроме ID.
+// Details кодируются повторно после разбора JSON, чтобы хеш не зависел от типов значений
+// до сохранения и после чтения из БД
+func (e *AuditEvent) ChainHash(prevHash string) (string, error) {
+	var details interface{}
+	if len(e.Details) > 0 {
+		raw, err := json.Marshal(e.Details)
+		if err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(raw, &details); err != nil {
+			return "", err
+		}
+	}
+
+	payload, err := json.Marshal(struct {
+		PrevHash   string      `json:"prev_hash"`
+		Time       string      `json:"time"`
+		Action     string      `json:"action"`
+		Outcome    string      `json:"outcome"`
+		ActorID    string      `json:"actor_id"`
+		ActorLogin string      `json:"actor_login"`
+		IP         string      `json:"ip"`
+		UserAgent  string      `json:"user_agent"`
+		DocumentID string      `json:"document_id"`
+		Details    interface{} `json:"details"`
+	}{
+		PrevHash:   prevHash,
+		Time:       e.Time.UTC().Format(time.RFC3339),
+		Action:     e.Action,
+		Outcome:    e.Outcome,
+		ActorID:    e.ActorID,
+		ActorLogin: e.ActorLogin,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		DocumentID: e.DocumentID,
+		Details:    details,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditFilter условия выборки журнала аудита. Пустые поля не ограничивают выборку
+type AuditFilter struct {
+	Action     string
+	Outcome    string
+	Actor      string // ID или логин
+	DocumentID string
+	IP         string
+	From       *time.Time
+	To         *time.Time
+}
+
+// AuditListResponse страница журнала аудита
+type AuditListResponse struct {
+	Events []*AuditEvent `json:"events"`
+	Total  int           `json:"total"`
+}
+
+// AuditCheckpoint подписанная контрольная точка цепочки за сутки (UTC)
+type AuditCheckpoint struct {
+	Day       time.Time `json:"day"`
+	LastID    int64     `json:"last_id"`   // Последняя запись на конец суток
+	LastHash  string    `json:"last_hash"` // Ее хеш
+	Events    int       `json:"events"`    // Записей за сутки
+	Signature string    `json:"signature"` // JWS с полями контрольной точки
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditChainReport результат проверки цепочки журнала
+type AuditChainReport struct {
+	Events            int64            `json:"events"`    // Проверено записей цепочки
+	Unchained         int64            `json:"unchained"` // Записи, добавленные до включения цепочки
+	Checkpoints       int              `json:"checkpoints"`
+	SignaturesChecked bool             `json:"signatures_checked"`
+	Broken            *AuditChainBreak `json:"broken,omitempty"` // Первое найденное нарушение
+}
+
+// AuditChainBreak нарушение цепочки: запись или контрольная точка и причина
+type AuditChainBreak struct {
+	EventID    int64  `json:"event_id,omitempty"`
+	Checkpoint string `json:"checkpoint,omitempty"` // День контрольной точки, YYYY-MM-DD
+	Reason     string `json:"reason"`
+}
@@ -3,14 +3,38 @@ package model
 import "time"
 
 type Document struct {
-	ID       string      `json:"id"`
-	Name     string      `json:"name"`
-	Mime     string      `json:"mime"`
-	File     bool        `json:"file"`
-	Public   bool        `json:"public"`
-	Created  time.Time   `json:"created"`
-	Grant    []string    `json:"grant"`
-	FilePath string      `json:"-"`
-	JSONData interface{} `json:"-"`
-	Owner    string      `json:"-"`
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Mime      string      `json:"mime"`
+	File      bool        `json:"file"`
+	Public    bool        `json:"public"`
+	Created   time.Time   `json:"created"`
+	Size      int64       `json:"size"`
+	Grant     []string    `json:"grant"`
+	FilePath  string      `json:"-"`
+	JSONData  interface{} `json:"-"`
+	Owner     string      `json:"-"`
+	DeletedAt *time.Time  `json:"deleted_at,omitempty"` // Время перемещения в корзину
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"` // Время автоматического удаления
+	LegalHold bool        `json:"legal_hold"`           // Юридическое удержание блокирует удаление
+}
+
+// DocumentPatch изменяемые поля документа
+type DocumentPatch struct {
+	ExpiresAt   *time.Time
+	ClearExpiry bool // Снять срок хранения
+	LegalHold   *bool
+}
+
+// PendingDelete файл удаленного документа, ожидающий удаления с диска
+type PendingDelete struct {
+	ID         int64
+	FilePath   string
+	DocumentID string
+	Attempts   int
+}
+
+// Expired истек ли срок хранения документа (документы на удержании не истекают)
+func (d *Document) Expired(now time.Time) bool {
+	return d.ExpiresAt != nil && !d.LegalHold && !d.ExpiresAt.After(now)
 }
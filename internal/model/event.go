@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Типы событий документов
+const (
+	EventDocumentCreated = "document.created"
+	EventDocumentUpdated = "document.updated"
+	EventDocumentDeleted = "document.deleted"
+	EventGrantChanged    = "grant.changed"
+)
+
+// DocumentEvent изменение документа, о котором сообщается подписчикам
+type DocumentEvent struct {
+	Seq        int64                  `json:"-"` // Номер в журнале событий, возрастает
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Time       time.Time              `json:"time"`
+	DocumentID string                 `json:"document_id"`
+	Owner      string                 `json:"owner"`
+	Document   *Document              `json:"document"`          // Состояние документа после изменения (для удаления - до)
+	Changes    map[string]interface{} `json:"changes,omitempty"` // Измененные поля
+	AppendedAt time.Time              `json:"-"`                 // Время записи в журнал (по часам этого экземпляра)
+}
+
+// VisibleTo доступно ли событие пользователю: владельцу, получателям доступа и всем для публичных документов
+func (e *DocumentEvent) VisibleTo(userID string) bool {
+	if e.Owner == userID {
+		return true
+	}
+	if e.Document == nil {
+		return false
+	}
+	if e.Document.Public {
+		return true
+	}
+	for _, grant := range e.Document.Grant {
+		if grant == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+package model
+
+// GCReport результат сверки каталога загрузок с таблицей documents
+type GCReport struct {
+	OrphanFiles       []string `json:"orphan_files"`       // Файлы без записи в БД
+	DanglingDocuments []string `json:"dangling_documents"` // Записи, ссылающиеся на отсутствующие файлы
+	HeldDocuments     []string `json:"held_documents"`     // Записи без файлов под удержанием, не удаляются
+	RemovedFiles      int      `json:"removed_files"`
+	RemovedDocuments  int      `json:"removed_documents"`
+}
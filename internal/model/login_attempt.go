@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// LoginAttempts неудачные попытки входа по логину или IP-адресу
+type LoginAttempts struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
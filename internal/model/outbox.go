@@ -0,0 +1,29 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Состояния сообщения outbox
+const (
+	OutboxPending   = "pending"
+	OutboxPublished = "published"
+	OutboxFailed    = "failed"
+)
+
+// OutboxMessage событие документа, записанное в одной транзакции с изменением документа,
+// для публикации в приемник Sink. ID события служит ключом идемпотентности
+type OutboxMessage struct {
+	ID            int64
+	Sink          string
+	EventID       string
+	EventType     string
+	Payload       json.RawMessage // DocumentEvent в JSON
+	Status        string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	PublishedAt   *time.Time
+	CreatedAt     time.Time
+}
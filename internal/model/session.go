@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// Session сессия пользователя на одном устройстве
+type Session struct {
+	ID              string     `json:"id"`
+	UserID          string     `json:"-"`
+	Device          string     `json:"device"`
+	IP              string     `json:"ip"`
+	AccessTokenHash string     `json:"-"`
+	AccessTokenID   string     `json:"-"` // jti текущего access-токена
+	AccessExpiry    time.Time  `json:"-"`
+	RefreshExpiry   time.Time  `json:"expires_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      time.Time  `json:"last_used_at"`
+	RevokedAt       *time.Time `json:"-"`
+	Current         bool       `json:"current"`
+}
+
+// RefreshToken одноразовый refresh-токен сессии
+type RefreshToken struct {
+	TokenHash string
+	SessionID string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// TokenPair пара токенов, выдаваемая при входе и обновлении
+type TokenPair struct {
+	AccessToken  string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	SessionID    string    `json:"session_id"`
+	UserID       string    `json:"-"`
+	// Коды восстановления, выдаются один раз при подключении второго фактора во время входа
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
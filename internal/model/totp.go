@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// TOTP второй фактор пользователя. Пока EnabledAt не задан, секрет ожидает подтверждения
+type TOTP struct {
+	UserID    string
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  int64 // Последний принятый шаг, повторно код того же шага не принимается
+}
+
+// Enabled сообщает, подтверждено ли подключение второго фактора
+func (t *TOTP) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// TOTPEnrollment данные для добавления секрета в приложение-аутентификатор
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// MFAChallenge выдается после проверки пароля, если требуется второй фактор
+type MFAChallenge struct {
+	MFAToken           string    `json:"mfa_token"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	EnrollmentRequired bool      `json:"enrollment_required"` // Второй фактор обязателен, но еще не подключен
+}
@@ -4,19 +4,59 @@ import (
 	"time"
 )
 
+// Источники учетных записей
+const (
+	UserSourceLocal = "local" // Создана в приложении
+	UserSourceOIDC  = "oidc"  // Создана или привязана при входе через OpenID Connect
+	UserSourceLDAP  = "ldap"  // Создана при входе через каталог LDAP и синхронизируется с ним
+)
+
 type User struct {
-	ID          string    `json:"id"`
-	Login       string    `json:"login"`
-	Password    string    `json:"-"`
-	Token       string    `json:"-"`
-	TokenExpiry time.Time `json:"-"`
-	CreatedAt   time.Time `json:"created_at,omitempty"`
+	ID         string     `json:"id"`
+	Login      string     `json:"login"`
+	Password   string     `json:"-"`
+	Email      string     `json:"email,omitempty"`
+	IsAdmin    bool       `json:"is_admin"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at,omitempty"`
+	Source     string     `json:"-"` // Источник учетной записи (UserSource*)
+	ExternalID string     `json:"-"` // Идентификатор у внешнего источника
+}
+
+// Disabled сообщает, заблокирован ли пользователь администратором
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
 }
 
 type UserResponse struct {
-	ID        string    `json:"id"`
-	Login     string    `json:"login"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         string     `json:"id"`
+	Login      string     `json:"login"`
+	Email      string     `json:"email,omitempty"`
+	IsAdmin    bool       `json:"is_admin"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewUserResponse формирует представление пользователя для API
+func NewUserResponse(u *User) UserResponse {
+	return UserResponse{
+		ID:         u.ID,
+		Login:      u.Login,
+		Email:      u.Email,
+		IsAdmin:    u.IsAdmin,
+		DisabledAt: u.DisabledAt,
+		CreatedAt:  u.CreatedAt,
+	}
+}
+
+// UserPatch изменения пользователя администратором (nil - без изменений)
+type UserPatch struct {
+	Login          *string
+	Email          *string
+	IsAdmin        *bool
+	Disabled       *bool
+	QuotaBytes     *int64 // Персональная квота: 0 - без ограничений, отрицательное - квота по умолчанию
+	QuotaDocuments *int64
 }
 
 type UserListResponse struct {
@@ -24,6 +64,14 @@ type UserListResponse struct {
 	Total int            `json:"total"`
 }
 
+// Usage текущее использование хранилища и действующие квоты (0 - без ограничений)
+type Usage struct {
+	Bytes          int64 `json:"bytes"`
+	Documents      int64 `json:"documents"`
+	QuotaBytes     int64 `json:"quota_bytes"`
+	QuotaDocuments int64 `json:"quota_documents"`
+}
+
 type UploadedFile struct {
 	Filename string
 	Data     []byte
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Состояния доставки webhook
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // Попытки исчерпаны
+)
+
+// Webhook адрес, на который отправляются события документов.
+// Webhook пользователя получает события доступных ему документов, глобальный (администратора) - все события
+type Webhook struct {
+	ID        string    `json:"id"`
+	OwnerID   string    `json:"-"`
+	Global    bool      `json:"global"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"` // Пустой - все типы событий
+	Secret    string    `json:"-"`      // Ключ HMAC-подписи, показывается только при создании
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Accepts подписан ли webhook на тип события
+func (w *Webhook) Accepts(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery доставка события на webhook с историей попыток
+type WebhookDelivery struct {
+	ID            string          `json:"id"`
+	WebhookID     string          `json:"webhook_id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  int             `json:"response_code,omitempty"` // Код ответа последней попытки
+	LastError     string          `json:"last_error,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package notify
+
+import "context"
+
+// Message сообщение пользователю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier доставляет сообщения пользователям (почта, мессенджеры и т.п.)
+type Notifier interface {
+	Send(ctx context.Context, msg *Message) error
+}
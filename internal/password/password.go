@@ -0,0 +1,209 @@
+// Package password хеширование паролей (argon2id, bcrypt) с параметрами, сохраненными в самом хеше
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hasher создает и проверяет хеши паролей
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Verify сравнивает пароль с хешем. Хеш неизвестного формата не совпадает ни с одним паролем
+	Verify(encoded, password string) bool
+	// NeedsRehash сообщает, что хеш создан другим алгоритмом или с устаревшими параметрами
+	NeedsRehash(encoded string) bool
+}
+
+// Algorithm алгоритм хеширования, распознающий свои хеши
+type Algorithm interface {
+	Hasher
+	Name() string
+	Matches(encoded string) bool
+}
+
+var b64 = base64.RawStdEncoding
+
+// Argon2id параметры argon2id. Хеш хранится в формате PHC:
+// $argon2id$v=19$m=<память в КиБ>,t=<итерации>,p=<потоки>$<соль>$<хеш>
+type Argon2id struct {
+	Memory  uint32 // Память в КиБ
+	Time    uint32 // Количество итераций
+	Threads uint8
+	SaltLen uint32 // 0 - 16 байт
+	KeyLen  uint32 // 0 - 32 байта
+}
+
+// DefaultArgon2id минимальные параметры, рекомендованные OWASP
+var DefaultArgon2id = Argon2id{Memory: 19 * 1024, Time: 2, Threads: 1}
+
+func (a Argon2id) Name() string {
+	return "argon2id"
+}
+
+func (a Argon2id) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (a Argon2id) Validate() error {
+	if a.Memory < 8*uint32(a.Threads) || a.Time < 1 || a.Threads < 1 {
+		return errors.New("argon2id: memory must be at least 8 KiB per thread, time and threads at least 1")
+	}
+	return nil
+}
+
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, withDefault(a.SaltLen, 16))
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, withDefault(a.KeyLen, 32))
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Time, a.Threads, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+func (a Argon2id) Verify(encoded, password string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1
+}
+
+func (a Argon2id) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory != a.Memory || params.Time != a.Time || params.Threads != a.Threads ||
+		uint32(len(salt)) != withDefault(a.SaltLen, 16) || uint32(len(key)) != withDefault(a.KeyLen, 32)
+}
+
+// decodeArgon2id разбирает хеш в формате PHC
+func decodeArgon2id(encoded string) (Argon2id, []byte, []byte, error) {
+	var params Argon2id
+
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", соль, хеш
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errors.New("argon2id: malformed hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("argon2id: unsupported version")
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return params, nil, nil, fmt.Errorf("argon2id: malformed parameters: %w", err)
+	}
+	if err := params.Validate(); err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("argon2id: malformed salt: %w", err)
+	}
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("argon2id: malformed key")
+	}
+	return params, salt, key, nil
+}
+
+// Bcrypt параметры bcrypt. Стоимость хранится в самом хеше ($2a$<cost>$...)
+type Bcrypt struct {
+	Cost int
+}
+
+func (b Bcrypt) Name() string {
+	return "bcrypt"
+}
+
+func (b Bcrypt) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b Bcrypt) Validate() error {
+	if b.Cost < bcrypt.MinCost || b.Cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt: cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
+
+func (b Bcrypt) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hashed), nil
+}
+
+func (b Bcrypt) Verify(encoded, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)) == nil
+}
+
+func (b Bcrypt) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.Cost
+}
+
+// Policy создает хеши основным алгоритмом и проверяет хеши любого из известных.
+// Хеш другого алгоритма или с другими параметрами требует пересчета
+type Policy struct {
+	preferred Algorithm
+	known     []Algorithm
+}
+
+// New возвращает Policy с основным алгоритмом preferred. legacy - алгоритмы, хеши которых еще принимаются
+func New(preferred Algorithm, legacy ...Algorithm) *Policy {
+	return &Policy{preferred: preferred, known: append([]Algorithm{preferred}, legacy...)}
+}
+
+// Default argon2id с параметрами по умолчанию, хеши bcrypt принимаются
+func Default() *Policy {
+	return New(DefaultArgon2id, Bcrypt{Cost: bcrypt.DefaultCost})
+}
+
+func (p *Policy) Hash(password string) (string, error) {
+	return p.preferred.Hash(password)
+}
+
+func (p *Policy) Verify(encoded, password string) bool {
+	algorithm := p.algorithm(encoded)
+	return algorithm != nil && algorithm.Verify(encoded, password)
+}
+
+func (p *Policy) NeedsRehash(encoded string) bool {
+	if !p.preferred.Matches(encoded) {
+		// Хеш неизвестного формата (например, пользователь без пароля) пересчитать нельзя
+		return p.algorithm(encoded) != nil
+	}
+	return p.preferred.NeedsRehash(encoded)
+}
+
+func (p *Policy) algorithm(encoded string) Algorithm {
+	for _, algorithm := range p.known {
+		if algorithm.Matches(encoded) {
+			return algorithm
+		}
+	}
+	return nil
+}
+
+func withDefault(value, def uint32) uint32 {
+	if value == 0 {
+		return def
+	}
+	return value
+}
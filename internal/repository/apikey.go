@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"docs-server/internal/model"
+	"fmt"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(dsn string) *APIKeyRepository {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		panic(err)
+	}
+
+	// Устанавливаем настройки пула соединений
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO api_keys (id, user_id, name, prefix, key_hash, read_only, expires_at, created_at)
+        VALUES (UUID_TO_BIN(?), UUID_TO_BIN(?), ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.ReadOnly, key.ExpiresAt, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert api key: %v", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT UUID_TO_STRING(id), UUID_TO_STRING(user_id), name, prefix, key_hash, read_only,
+            expires_at, created_at, last_used_at, revoked_at
+        FROM api_keys WHERE key_hash = ?`, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	return scanAPIKey(rows)
+}
+
+func (r *APIKeyRepository) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT UUID_TO_STRING(id), UUID_TO_STRING(user_id), name, prefix, key_hash, read_only,
+            expires_at, created_at, last_used_at, revoked_at
+        FROM api_keys WHERE id = UUID_TO_BIN(?)`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	return scanAPIKey(rows)
+}
+
+// ListUserAPIKeys возвращает неотозванные ключи пользователя
+func (r *APIKeyRepository) ListUserAPIKeys(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT UUID_TO_STRING(id), UUID_TO_STRING(user_id), name, prefix, key_hash, read_only,
+            expires_at, created_at, last_used_at, revoked_at
+        FROM api_keys
+        WHERE user_id = UUID_TO_BIN(?) AND revoked_at IS NULL
+        ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = ? WHERE id = UUID_TO_BIN(?) AND revoked_at IS NULL",
+		time.Now(), id)
+	return err
+}
+
+func (r *APIKeyRepository) UpdateLastUsed(ctx context.Context, id string, lastUsed time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE api_keys SET last_used_at = ? WHERE id = UUID_TO_BIN(?)", lastUsed, id)
+	return err
+}
+
+// scanAPIKey читает ключ из текущей строки выборки
+func scanAPIKey(rows *sql.Rows) (*model.APIKey, error) {
+	key := &model.APIKey{}
+	var expiresAt, createdAt, lastUsedAt, revokedAt []byte
+
+	if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &key.ReadOnly,
+		&expiresAt, &createdAt, &lastUsedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if key.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(createdAt)); err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %v", err)
+	}
+	if key.ExpiresAt, err = parseNullTime(expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to parse expires_at: %v", err)
+	}
+	if key.LastUsedAt, err = parseNullTime(lastUsedAt); err != nil {
+		return nil, fmt.Errorf("failed to parse last_used_at: %v", err)
+	}
+	if key.RevokedAt, err = parseNullTime(revokedAt); err != nil {
+		return nil, fmt.Errorf("failed to parse revoked_at: %v", err)
+	}
+
+	return key, nil
+}
+
+// parseNullTime разбирает значение DATETIME, допускающее NULL
+func parseNullTime(value []byte) (*time.Time, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02 15:04:05", string(value))
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *APIKeyRepository) Close() error {
+	return r.db.Close()
+}
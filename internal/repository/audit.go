@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"docs-server/internal/model"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// AuditRepository журнал аудита. Записи только добавляются: изменение и удаление
+// запрещены и триггерами таблицы audit_events
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(dsn string) *AuditRepository {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		panic(err)
+	}
+
+	// Устанавливаем настройки пула соединений
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	return &AuditRepository{db: db}
+}
+
+// InsertAuditEvent добавляет запись в журнал, заполняет ее ID и хеши цепочки.
+// Строка audit_chain блокируется до коммита, поэтому записи разных экземпляров
+// выстраиваются в одну цепочку
+func (r *AuditRepository) InsertAuditEvent(ctx context.Context, event *model.AuditEvent) error {
+	var details sql.NullString
+	if len(event.Details) > 0 {
+		raw, err := json.Marshal(event.Details)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+		details = sql.NullString{String: string(raw), Valid: true}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var prevHash string
+	if err := tx.QueryRowContext(ctx,
+		"SELECT last_hash FROM audit_chain WHERE id = 1 FOR UPDATE").Scan(&prevHash); err != nil {
+		return fmt.Errorf("failed to lock audit chain: %v", err)
+	}
+
+	hash, err := event.ChainHash(prevHash)
+	if err != nil {
+		return fmt.Errorf("failed to hash audit event: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+        INSERT INTO audit_events
+            (created_at, action, outcome, actor_id, actor_login, ip, user_agent, document_id, details, prev_hash, hash)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		event.Time, event.Action, event.Outcome, nullString(event.ActorID), nullString(event.ActorLogin),
+		nullString(event.IP), nullString(event.UserAgent), nullString(event.DocumentID), details, prevHash, hash)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE audit_chain SET last_id = ?, last_hash = ? WHERE id = 1", id, hash); err != nil {
+		return fmt.Errorf("failed to update audit chain: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit audit event: %v", err)
+	}
+
+	event.ID = id
+	event.PrevHash = prevHash
+	event.Hash = hash
+	return nil
+}
+
+// ListAuditEvents возвращает записи по фильтру, новые первыми
+func (r *AuditRepository) ListAuditEvents(ctx context.Context, filter *model.AuditFilter, limit, offset int) ([]*model.AuditEvent, error) {
+	where, args := auditWhere(filter)
+	rows, err := r.db.QueryContext(ctx, auditSelect+where+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*model.AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// CountAuditEvents возвращает количество записей по фильтру
+func (r *AuditRepository) CountAuditEvents(ctx context.Context, filter *model.AuditFilter) (int, error) {
+	where, args := auditWhere(filter)
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events"+where, args...).Scan(&count)
+	return count, err
+}
+
+// ExportAuditEvents передает записи по фильтру в fn в порядке добавления, не загружая выборку в память
+func (r *AuditRepository) ExportAuditEvents(ctx context.Context, filter *model.AuditFilter, fn func(*model.AuditEvent) error) error {
+	where, args := auditWhere(filter)
+	rows, err := r.db.QueryContext(ctx, auditSelect+where+" ORDER BY id", args...)
+	if err != nil {
+		return fmt.Errorf("failed to export audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// AuditChainHead возвращает последнюю запись цепочки по таблице audit_chain
+func (r *AuditRepository) AuditChainHead(ctx context.Context) (int64, string, error) {
+	var lastID int64
+	var lastHash string
+	err := r.db.QueryRowContext(ctx,
+		"SELECT last_id, last_hash FROM audit_chain WHERE id = 1").Scan(&lastID, &lastHash)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get audit chain head: %v", err)
+	}
+	return lastID, lastHash, nil
+}
+
+// LastAuditEventBefore возвращает последнюю запись, добавленную раньше before, или nil
+func (r *AuditRepository) LastAuditEventBefore(ctx context.Context, before time.Time) (*model.AuditEvent, error) {
+	event, err := scanAuditEvent(r.db.QueryRowContext(ctx,
+		auditSelect+" WHERE created_at < ? ORDER BY id DESC LIMIT 1", before.UTC()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return event, err
+}
+
+// FirstAuditEventTime возвращает время первой записи журнала или nil, если журнал пуст
+func (r *AuditRepository) FirstAuditEventTime(ctx context.Context) (*time.Time, error) {
+	var createdAt []byte
+	err := r.db.QueryRowContext(ctx, "SELECT created_at FROM audit_events ORDER BY id LIMIT 1").Scan(&createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := time.Parse("2006-01-02 15:04:05", string(createdAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %v", err)
+	}
+	return &t, nil
+}
+
+// InsertAuditCheckpoint сохраняет контрольную точку. Уже созданная за этот день
+// (например, другим экземпляром) не перезаписывается
+func (r *AuditRepository) InsertAuditCheckpoint(ctx context.Context, checkpoint *model.AuditCheckpoint) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT IGNORE INTO audit_checkpoints (day, last_id, last_hash, events, signature, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)`,
+		checkpoint.Day.Format("2006-01-02"), checkpoint.LastID, checkpoint.LastHash, checkpoint.Events,
+		checkpoint.Signature, checkpoint.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit checkpoint: %v", err)
+	}
+	return nil
+}
+
+// ListAuditCheckpoints возвращает контрольные точки по возрастанию дня
+func (r *AuditRepository) ListAuditCheckpoints(ctx context.Context) ([]*model.AuditCheckpoint, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT day, last_id, last_hash, events, signature, created_at
+        FROM audit_checkpoints ORDER BY day`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit checkpoints: %v", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []*model.AuditCheckpoint
+	for rows.Next() {
+		checkpoint := &model.AuditCheckpoint{}
+		var day, createdAt []byte
+		if err := rows.Scan(&day, &checkpoint.LastID, &checkpoint.LastHash, &checkpoint.Events,
+			&checkpoint.Signature, &createdAt); err != nil {
+			return nil, err
+		}
+		if checkpoint.Day, err = time.Parse("2006-01-02", string(day)); err != nil {
+			return nil, fmt.Errorf("failed to parse day: %v", err)
+		}
+		if checkpoint.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(createdAt)); err != nil {
+			return nil, fmt.Errorf("failed to parse created_at: %v", err)
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+	return checkpoints, rows.Err()
+}
+
+func (r *AuditRepository) Close() error {
+	return r.db.Close()
+}
+
+const auditSelect = `
+        SELECT id, created_at, action, outcome, actor_id, actor_login, ip, user_agent, document_id, details, prev_hash, hash
+        FROM audit_events`
+
+// auditWhere условие WHERE для фильтра
+func auditWhere(filter *model.AuditFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, "(actor_id = ? OR actor_login = ?)")
+		args = append(args, filter.Actor, filter.Actor)
+	}
+	if filter.DocumentID != "" {
+		conditions = append(conditions, "document_id = ?")
+		args = append(args, filter.DocumentID)
+	}
+	if filter.IP != "" {
+		conditions = append(conditions, "ip = ?")
+		args = append(args, filter.IP)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// scanAuditEvent читает запись журнала из строки выборки
+func scanAuditEvent(row interface{ Scan(...any) error }) (*model.AuditEvent, error) {
+	event := &model.AuditEvent{}
+	var createdAt []byte
+	var actorID, actorLogin, ip, userAgent, documentID, details sql.NullString
+
+	if err := row.Scan(&event.ID, &createdAt, &event.Action, &event.Outcome,
+		&actorID, &actorLogin, &ip, &userAgent, &documentID, &details, &event.PrevHash, &event.Hash); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if event.Time, err = time.Parse("2006-01-02 15:04:05", string(createdAt)); err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %v", err)
+	}
+	event.ActorID = actorID.String
+	event.ActorLogin = actorLogin.String
+	event.IP = ip.String
+	event.UserAgent = userAgent.String
+	event.DocumentID = documentID.String
+	if details.Valid {
+		if err := json.Unmarshal([]byte(details.String), &event.Details); err != nil {
+			return nil, fmt.Errorf("failed to decode audit details: %v", err)
+		}
+	}
+
+	return event, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"docs-server/internal/model"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -34,13 +35,43 @@ func NewDocumentRepository(dsn string) *DocumentRepository {
 	return &DocumentRepository{db: db}
 }
 
-func (r *DocumentRepository) CreateDocument(ctx context.Context, doc *model.Document) error {
+// QuotaCheck проверяет, поместится ли новый документ в квоту владельца. Получает текущее
+// использование хранилища и персональные квоты (NULL - используется квота по умолчанию)
+type QuotaCheck func(bytes, count int64, quotaBytes, quotaDocs sql.NullInt64) error
+
+// CreateDocument добавляет документ с правами доступа и сообщения outbox о его создании в одной транзакции.
+// Строка владельца блокируется до коммита, поэтому check видит использование с учетом параллельных загрузок
+func (r *DocumentRepository) CreateDocument(ctx context.Context, doc *model.Document, check QuotaCheck, outbox ...*model.OutboxMessage) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %v", err)
 	}
 	defer tx.Rollback()
 
+	if check != nil {
+		var quotaBytes, quotaDocs sql.NullInt64
+		err = tx.QueryRowContext(ctx,
+			"SELECT quota_bytes, quota_docs FROM users WHERE id = UUID_TO_BIN(?) FOR UPDATE", doc.Owner).
+			Scan(&quotaBytes, &quotaDocs)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to lock owner: %v", err)
+		}
+
+		var bytes, count int64
+		err = tx.QueryRowContext(ctx, `
+            SELECT COALESCE(SUM(size), 0), COUNT(*)
+            FROM documents
+            WHERE owner_id = UUID_TO_BIN(?)`, doc.Owner).
+			Scan(&bytes, &count)
+		if err != nil {
+			return fmt.Errorf("failed to get user usage: %v", err)
+		}
+
+		if err := check(bytes, count, quotaBytes, quotaDocs); err != nil {
+			return err
+		}
+	}
+
 	// Если есть jsondata записываем
 	var jsonData []byte
 	if doc.JSONData != nil {
@@ -53,10 +84,10 @@ func (r *DocumentRepository) CreateDocument(ctx context.Context, doc *model.Docu
 	// Добавляем документ
 	_, err = tx.ExecContext(ctx, `
         INSERT INTO documents 
-        (id, name, mime, is_file, is_public, created_at, owner_id, file_path, json_data) 
-        VALUES (UUID_TO_BIN(?), ?, ?, ?, ?, ?, UUID_TO_BIN(?), ?, ?)`,
+        (id, name, mime, is_file, is_public, created_at, owner_id, file_path, json_data, size, expires_at, legal_hold) 
+        VALUES (UUID_TO_BIN(?), ?, ?, ?, ?, ?, UUID_TO_BIN(?), ?, ?, ?, ?, ?)`,
 		doc.ID, doc.Name, doc.Mime, doc.File, doc.Public,
-		doc.Created, doc.Owner, doc.FilePath, jsonData)
+		doc.Created, doc.Owner, doc.FilePath, jsonData, doc.Size, doc.ExpiresAt, doc.LegalHold)
 	if err != nil {
 		return fmt.Errorf("failed to insert document: %v", err)
 	}
@@ -93,6 +124,9 @@ func (r *DocumentRepository) CreateDocument(ctx context.Context, doc *model.Docu
 			}
 		}
 	}
+	if err := insertOutboxMessages(ctx, tx, outbox); err != nil {
+		return err
+	}
 	// Пишем транзакцию
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %v", err)
@@ -102,16 +136,18 @@ func (r *DocumentRepository) CreateDocument(ctx context.Context, doc *model.Docu
 }
 func (r *DocumentRepository) GetDocumentByID(ctx context.Context, id string) (*model.Document, error) {
 	doc := &model.Document{}
-	var createdAtBytes []byte
+	var createdAtBytes, deletedAtBytes, expiresAtBytes []byte
 
 	// Получаем основные данные документа
 	err := r.db.QueryRowContext(ctx, `
         SELECT 
             UUID_TO_STRING(id), name, mime, is_file, is_public, 
-            created_at, file_path, json_data, UUID_TO_STRING(owner_id)
+            created_at, file_path, json_data, UUID_TO_STRING(owner_id), size, deleted_at,
+            expires_at, legal_hold
         FROM documents WHERE id = UUID_TO_BIN(?)`, id).
 		Scan(&doc.ID, &doc.Name, &doc.Mime, &doc.File, &doc.Public,
-			&createdAtBytes, &doc.FilePath, &doc.JSONData, &doc.Owner)
+			&createdAtBytes, &doc.FilePath, &doc.JSONData, &doc.Owner, &doc.Size, &deletedAtBytes,
+			&expiresAtBytes, &doc.LegalHold)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -127,6 +163,22 @@ func (r *DocumentRepository) GetDocumentByID(ctx context.Context, id string) (*m
 	}
 	doc.Created = createdAt
 
+	if len(deletedAtBytes) > 0 {
+		deletedAt, err := time.Parse("2006-01-02 15:04:05", string(deletedAtBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse deleted_at: %v", err)
+		}
+		doc.DeletedAt = &deletedAt
+	}
+
+	if len(expiresAtBytes) > 0 {
+		expiresAt, err := time.Parse("2006-01-02 15:04:05", string(expiresAtBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse expires_at: %v", err)
+		}
+		doc.ExpiresAt = &expiresAt
+	}
+
 	// Получаем список прав доступа из document_grants
 	rows, err := r.db.QueryContext(ctx, `
         SELECT UUID_TO_STRING(user_id) 
@@ -157,11 +209,12 @@ func (r *DocumentRepository) GetDocumentByID(ctx context.Context, id string) (*m
 func (r *DocumentRepository) GetUserDocuments(ctx context.Context, userid string, limit int) ([]*model.Document, error) {
 	rows, err := r.db.QueryContext(ctx, `
         SELECT 
-            UUID_TO_STRING(id), name, mime, is_file, is_public, created_at
+            UUID_TO_STRING(id), name, mime, is_file, is_public, created_at, size
         FROM documents 
-        WHERE owner_id = UUID_TO_BIN(?)
+        WHERE owner_id = UUID_TO_BIN(?) AND deleted_at IS NULL
+        AND (expires_at IS NULL OR expires_at > ? OR legal_hold = TRUE)
         ORDER BY name, created_at
-        LIMIT ?`, userid, limit)
+        LIMIT ?`, userid, time.Now(), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -179,6 +232,7 @@ func (r *DocumentRepository) GetUserDocuments(ctx context.Context, userid string
 			&doc.File,
 			&doc.Public,
 			&createdAtBytes,
+			&doc.Size,
 		); err != nil {
 			return nil, err
 		}
@@ -210,13 +264,16 @@ func (r *DocumentRepository) GetSharedDocuments(ctx context.Context, currentUser
             d.created_at,
             d.file_path,
             d.json_data,
-            UUID_TO_STRING(d.owner_id)
+            UUID_TO_STRING(d.owner_id),
+            d.size
         FROM documents d
         LEFT JOIN document_grants g ON d.id = g.document_id
         WHERE d.owner_id = UUID_TO_BIN(?)
+        AND d.deleted_at IS NULL
+        AND (d.expires_at IS NULL OR d.expires_at > ? OR d.legal_hold = TRUE)
         AND (d.is_public = TRUE OR g.user_id = UUID_TO_BIN(?))
         ORDER BY d.name, d.created_at
-        LIMIT ?`, ownerID, currentUserID, limit)
+        LIMIT ?`, ownerID, time.Now(), currentUserID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -237,6 +294,7 @@ func (r *DocumentRepository) GetSharedDocuments(ctx context.Context, currentUser
 			&doc.FilePath,
 			&doc.JSONData,
 			&doc.Owner,
+			&doc.Size,
 		)
 		if err != nil {
 			return nil, err
@@ -258,11 +316,316 @@ func (r *DocumentRepository) GetSharedDocuments(ctx context.Context, currentUser
 
 	return docs, nil
 }
-func (r *DocumentRepository) DeleteDocument(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM documents WHERE id = UUID_TO_BIN(?)", id)
+
+// GetUserUsage возвращает суммарный размер и количество документов пользователя
+func (r *DocumentRepository) GetUserUsage(ctx context.Context, userID string) (int64, int64, error) {
+	var bytes, count int64
+	err := r.db.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(size), 0), COUNT(*)
+        FROM documents
+        WHERE owner_id = UUID_TO_BIN(?)`, userID).
+		Scan(&bytes, &count)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get user usage: %v", err)
+	}
+	return bytes, count, nil
+}
+
+// TrashDocument помечает документ удаленным (перемещает в корзину)
+func (r *DocumentRepository) TrashDocument(ctx context.Context, id string, deletedAt time.Time, outbox ...*model.OutboxMessage) error {
+	return r.execWithOutbox(ctx, outbox,
+		"UPDATE documents SET deleted_at = ? WHERE id = UUID_TO_BIN(?) AND deleted_at IS NULL",
+		deletedAt, id)
+}
+
+// RestoreDocument возвращает документ из корзины
+func (r *DocumentRepository) RestoreDocument(ctx context.Context, id string, outbox ...*model.OutboxMessage) error {
+	return r.execWithOutbox(ctx, outbox,
+		"UPDATE documents SET deleted_at = NULL WHERE id = UUID_TO_BIN(?)", id)
+}
+
+// GetTrashedDocuments возвращает документы пользователя, находящиеся в корзине
+func (r *DocumentRepository) GetTrashedDocuments(ctx context.Context, userID string, limit int) ([]*model.Document, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT 
+            UUID_TO_STRING(id), name, mime, is_file, is_public, created_at, size, deleted_at
+        FROM documents 
+        WHERE owner_id = UUID_TO_BIN(?) AND deleted_at IS NOT NULL
+        ORDER BY deleted_at DESC
+        LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []*model.Document
+	for rows.Next() {
+		doc := &model.Document{}
+		var createdAtBytes, deletedAtBytes []byte
+
+		if err := rows.Scan(
+			&doc.ID,
+			&doc.Name,
+			&doc.Mime,
+			&doc.File,
+			&doc.Public,
+			&createdAtBytes,
+			&doc.Size,
+			&deletedAtBytes,
+		); err != nil {
+			return nil, err
+		}
+
+		createdAt, err := time.Parse("2006-01-02 15:04:05", string(createdAtBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse created_at: %v", err)
+		}
+		doc.Created = createdAt
+
+		deletedAt, err := time.Parse("2006-01-02 15:04:05", string(deletedAtBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse deleted_at: %v", err)
+		}
+		doc.DeletedAt = &deletedAt
+
+		doc.Owner = userID
+		docs = append(docs, doc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// GetTrashedBefore возвращает ID документов, удаленных в корзину раньше before
+func (r *DocumentRepository) GetTrashedBefore(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT UUID_TO_STRING(id)
+        FROM documents
+        WHERE deleted_at IS NOT NULL AND deleted_at < ? AND legal_hold = FALSE
+        ORDER BY deleted_at
+        LIMIT ?`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// UpdateDocumentRetention обновляет срок хранения и признак юридического удержания документа
+func (r *DocumentRepository) UpdateDocumentRetention(ctx context.Context, id string, expiresAt *time.Time, legalHold bool, outbox ...*model.OutboxMessage) error {
+	return r.execWithOutbox(ctx, outbox,
+		"UPDATE documents SET expires_at = ?, legal_hold = ? WHERE id = UUID_TO_BIN(?)",
+		expiresAt, legalHold, id)
+}
+
+// GetExpiredDocuments возвращает ID документов с истекшим сроком хранения, не находящихся на удержании
+func (r *DocumentRepository) GetExpiredDocuments(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT UUID_TO_STRING(id)
+        FROM documents
+        WHERE expires_at IS NOT NULL AND expires_at <= ? AND legal_hold = FALSE
+        ORDER BY expires_at
+        LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetFileDocuments возвращает ID, путь к файлу и владельца всех файловых документов
+func (r *DocumentRepository) GetFileDocuments(ctx context.Context) ([]*model.Document, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT UUID_TO_STRING(id), file_path, UUID_TO_STRING(owner_id)
+        FROM documents
+        WHERE is_file = TRUE`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []*model.Document
+	for rows.Next() {
+		doc := &model.Document{File: true}
+		var filePath sql.NullString
+		if err := rows.Scan(&doc.ID, &filePath, &doc.Owner); err != nil {
+			return nil, err
+		}
+		doc.FilePath = filePath.String
+		docs = append(docs, doc)
+	}
+
+	return docs, rows.Err()
+}
+
+// GetOwnedDocumentIDs возвращает ID всех документов пользователя, включая находящиеся в корзине
+func (r *DocumentRepository) GetOwnedDocumentIDs(ctx context.Context, ownerID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT UUID_TO_STRING(id) FROM documents WHERE owner_id = UUID_TO_BIN(?)", ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// DeleteDocument удаляет документ и его права доступа в одной транзакции.
+// Файл документа не удаляется: его путь ставится в очередь pending_deletes
+// в той же транзакции и возвращается для удаления после коммита.
+// Сообщения outbox об удалении записываются в той же транзакции
+func (r *DocumentRepository) DeleteDocument(ctx context.Context, id string, outbox ...*model.OutboxMessage) (*model.PendingDelete, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var isFile bool
+	var filePath sql.NullString
+	err = tx.QueryRowContext(ctx,
+		"SELECT is_file, file_path FROM documents WHERE id = UUID_TO_BIN(?) FOR UPDATE", id).
+		Scan(&isFile, &filePath)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock document: %v", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM document_grants WHERE document_id = UUID_TO_BIN(?)", id); err != nil {
+		return nil, fmt.Errorf("failed to delete grants: %v", err)
+	}
+
+	var pending *model.PendingDelete
+	if isFile && filePath.String != "" {
+		res, err := tx.ExecContext(ctx, `
+            INSERT INTO pending_deletes (file_path, document_id, next_attempt_at)
+            VALUES (?, UUID_TO_BIN(?), ?)`,
+			filePath.String, id, time.Now().Truncate(time.Second))
+		if err != nil {
+			return nil, fmt.Errorf("failed to queue file deletion: %v", err)
+		}
+		pendingID, err := res.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pending delete id: %v", err)
+		}
+		pending = &model.PendingDelete{ID: pendingID, FilePath: filePath.String, DocumentID: id}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM documents WHERE id = UUID_TO_BIN(?)", id); err != nil {
+		return nil, fmt.Errorf("failed to delete document: %v", err)
+	}
+	if err := insertOutboxMessages(ctx, tx, outbox); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return pending, nil
+}
+
+// GetPendingDeletes возвращает файлы, ожидающие удаления, срок повторной попытки которых наступил
+func (r *DocumentRepository) GetPendingDeletes(ctx context.Context, now time.Time, limit int) ([]*model.PendingDelete, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, file_path, UUID_TO_STRING(document_id), attempts
+        FROM pending_deletes
+        WHERE next_attempt_at <= ?
+        ORDER BY next_attempt_at
+        LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []*model.PendingDelete
+	for rows.Next() {
+		p := &model.PendingDelete{}
+		if err := rows.Scan(&p.ID, &p.FilePath, &p.DocumentID, &p.Attempts); err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+
+	return pending, rows.Err()
+}
+
+// CompletePendingDelete удаляет запись из очереди после успешного удаления файла
+func (r *DocumentRepository) CompletePendingDelete(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM pending_deletes WHERE id = ?", id)
+	return err
+}
+
+// FailPendingDelete фиксирует неудачную попытку удаления файла и время следующей попытки
+func (r *DocumentRepository) FailPendingDelete(ctx context.Context, id int64, lastError string, nextAttempt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE pending_deletes
+        SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?
+        WHERE id = ?`, lastError, nextAttempt, id)
 	return err
 }
 
+// execWithOutbox выполняет изменение документа и добавляет сообщения outbox в одной транзакции
+func (r *DocumentRepository) execWithOutbox(ctx context.Context, outbox []*model.OutboxMessage, query string, args ...interface{}) error {
+	if len(outbox) == 0 {
+		_, err := r.db.ExecContext(ctx, query, args...)
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	if err := insertOutboxMessages(ctx, tx, outbox); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
 func (r *DocumentRepository) Close() error {
 	return r.db.Close()
 }
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"docs-server/internal/model"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// EventRepository общий журнал событий документов для потока /api/events
+type EventRepository struct {
+	db *sql.DB
+}
+
+func NewEventRepository(dsn string) *EventRepository {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		panic(err)
+	}
+
+	// Устанавливаем настройки пула соединений
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	return &EventRepository{db: db}
+}
+
+// AppendEvent сохраняет событие и возвращает его номер в журнале.
+// Повторно переданное событие не добавляется, для него возвращается 0
+func (r *EventRepository) AppendEvent(ctx context.Context, event *model.DocumentEvent) (int64, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	// created_at - время записи в журнал по часам БД, а не время изменения документа:
+	// через outbox событие может попасть в журнал намного позже
+	res, err := r.db.ExecContext(ctx,
+		"INSERT IGNORE INTO document_events (event_id, payload, created_at) VALUES (?, ?, NOW(6))",
+		event.ID, string(payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert event: %v", err)
+	}
+	return res.LastInsertId()
+}
+
+// ListEventsAfter возвращает до limit событий с номером больше seq по возрастанию номера.
+// AppendedAt вычисляется по возрасту записи в БД, поэтому не зависит от расхождения часов
+func (r *EventRepository) ListEventsAfter(ctx context.Context, seq int64, limit int) ([]*model.DocumentEvent, error) {
+	now := time.Now()
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT seq, payload, TIMESTAMPDIFF(MICROSECOND, created_at, NOW(6))
+        FROM document_events WHERE seq > ? ORDER BY seq LIMIT ?`,
+		seq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []*model.DocumentEvent
+	for rows.Next() {
+		var payload string
+		var age int64
+		event := &model.DocumentEvent{}
+		if err := rows.Scan(&event.Seq, &payload, &age); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(payload), event); err != nil {
+			return nil, fmt.Errorf("failed to decode event %d: %v", event.Seq, err)
+		}
+		event.AppendedAt = now.Add(-time.Duration(age) * time.Microsecond)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// EventLogBounds возвращает номера первого и последнего событий журнала, 0 для пустого журнала
+func (r *EventRepository) EventLogBounds(ctx context.Context) (int64, int64, error) {
+	var first, last int64
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COALESCE(MIN(seq), 0), COALESCE(MAX(seq), 0) FROM document_events").Scan(&first, &last)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read event log bounds: %v", err)
+	}
+	return first, last, nil
+}
+
+// TrimEvents оставляет в журнале keep последних событий
+func (r *EventRepository) TrimEvents(ctx context.Context, keep int) error {
+	_, last, err := r.EventLogBounds(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM document_events WHERE seq <= ?", last-int64(keep)); err != nil {
+		return fmt.Errorf("failed to trim events: %v", err)
+	}
+	return nil
+}
+
+func (r *EventRepository) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"docs-server/internal/model"
+	"fmt"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// LoginAttemptRepository хранит счетчики неудачных попыток входа в БД,
+// чтобы блокировки сохранялись после перезапуска
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(dsn string) *LoginAttemptRepository {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		panic(err)
+	}
+
+	// Устанавливаем настройки пула соединений
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(10)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	return &LoginAttemptRepository{db: db}
+}
+
+// UpdateAttempts атомарно изменяет счетчики по ключу: строка блокируется вставкой
+// в той же транзакции, поэтому параллельные попытки обрабатываются по очереди.
+// update получает действующие счетчики (нулевые, если попыток не было или срок истек);
+// если он возвращает ошибку, изменения не сохраняются
+func (r *LoginAttemptRepository) UpdateAttempts(ctx context.Context, key string, ttl time.Duration, update func(*model.LoginAttempts) error) error {
+	now := time.Now()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// expires_at присваивается последним, чтобы условия видели прежний срок
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO login_attempts (attempt_key, failures, last_failure, locked_until, expires_at)
+        VALUES (?, 0, ?, NULL, ?)
+        ON DUPLICATE KEY UPDATE
+            failures = IF(expires_at <= ?, 0, failures),
+            locked_until = IF(expires_at <= ?, NULL, locked_until),
+            expires_at = VALUES(expires_at)`,
+		key, now, now.Add(ttl), now, now)
+	if err != nil {
+		return fmt.Errorf("failed to lock login attempts: %w", err)
+	}
+
+	attempts := &model.LoginAttempts{}
+	var lastFailure, lockedUntil []byte
+	if err := tx.QueryRowContext(ctx,
+		"SELECT failures, last_failure, locked_until FROM login_attempts WHERE attempt_key = ?", key).
+		Scan(&attempts.Failures, &lastFailure, &lockedUntil); err != nil {
+		return fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	if attempts.LastFailure, err = time.Parse("2006-01-02 15:04:05", string(lastFailure)); err != nil {
+		return fmt.Errorf("failed to parse last_failure: %v", err)
+	}
+	locked, err := parseNullTime(lockedUntil)
+	if err != nil {
+		return fmt.Errorf("failed to parse locked_until: %v", err)
+	}
+	if locked != nil {
+		attempts.LockedUntil = *locked
+	}
+
+	if err := update(attempts); err != nil {
+		return err
+	}
+
+	var newLockedUntil *time.Time
+	if !attempts.LockedUntil.IsZero() {
+		newLockedUntil = &attempts.LockedUntil
+	}
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE login_attempts SET failures = ?, last_failure = ?, locked_until = ?
+        WHERE attempt_key = ?`,
+		attempts.Failures, attempts.LastFailure, newLockedUntil, key); err != nil {
+		return fmt.Errorf("failed to save login attempts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit login attempts: %v", err)
+	}
+
+	// Попутно удаляем истекшие записи
+	_, err = r.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE expires_at <= ?", time.Now())
+	return err
+}
+
+// DeleteAttempts сбрасывает счетчики по ключу
+func (r *LoginAttemptRepository) DeleteAttempts(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE attempt_key = ?", key)
+	return err
+}
+
+func (r *LoginAttemptRepository) Close() error {
+	return r.db.Close()
+}
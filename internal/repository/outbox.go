@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"docs-server/internal/model"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// OutboxRepository сообщения outbox для публикации событий документов
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(dsn string) *OutboxRepository {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		panic(err)
+	}
+
+	// Устанавливаем настройки пула соединений
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	return &OutboxRepository{db: db}
+}
+
+// ClaimOutboxMessages закрепляет за вызывающим до limit сообщений, срок попытки которых наступил:
+// их следующая попытка переносится на now + lease, поэтому другие экземпляры их не выберут
+func (r *OutboxRepository) ClaimOutboxMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.OutboxMessage, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT id, sink, event_id, event_type, payload, status, attempts, last_error, next_attempt_at, published_at, created_at
+        FROM event_outbox
+        WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`,
+		model.OutboxPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox messages: %v", err)
+	}
+	var due []*model.OutboxMessage
+	for rows.Next() {
+		message, err := scanOutboxMessage(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	leased := now.Add(lease).Truncate(time.Second)
+	var claimed []*model.OutboxMessage
+	for _, message := range due {
+		res, err := r.db.ExecContext(ctx, `
+            UPDATE event_outbox SET next_attempt_at = ?
+            WHERE id = ? AND status = ? AND next_attempt_at = ?`,
+			leased, message.ID, model.OutboxPending, message.NextAttemptAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim outbox message: %v", err)
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			continue
+		}
+		message.NextAttemptAt = leased
+		claimed = append(claimed, message)
+	}
+
+	return claimed, nil
+}
+
+// UpdateOutboxMessage сохраняет результат попытки публикации
+func (r *OutboxRepository) UpdateOutboxMessage(ctx context.Context, message *model.OutboxMessage) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE event_outbox
+        SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ?, published_at = ?
+        WHERE id = ?`,
+		message.Status, message.Attempts, nullString(message.LastError),
+		message.NextAttemptAt, message.PublishedAt, message.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message: %v", err)
+	}
+	return nil
+}
+
+// DeletePublishedOutboxMessages удаляет сообщения, опубликованные до before
+func (r *OutboxRepository) DeletePublishedOutboxMessages(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM event_outbox WHERE status = ? AND published_at < ?", model.OutboxPublished, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox messages: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+func (r *OutboxRepository) Close() error {
+	return r.db.Close()
+}
+
+// insertOutboxMessages добавляет сообщения в транзакции изменения документа.
+// Повтор сообщения о том же событии для того же приемника игнорируется
+func insertOutboxMessages(ctx context.Context, tx *sql.Tx, messages []*model.OutboxMessage) error {
+	for _, message := range messages {
+		res, err := tx.ExecContext(ctx, `
+            INSERT IGNORE INTO event_outbox (sink, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			message.Sink, message.EventID, message.EventType, string(message.Payload),
+			message.Status, message.Attempts, message.NextAttemptAt, message.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert outbox message: %v", err)
+		}
+		if message.ID, err = res.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get outbox message id: %v", err)
+		}
+	}
+	return nil
+}
+
+// scanOutboxMessage читает сообщение outbox из строки выборки
+func scanOutboxMessage(row interface{ Scan(...any) error }) (*model.OutboxMessage, error) {
+	message := &model.OutboxMessage{}
+	var payload string
+	var lastError sql.NullString
+	var nextAttemptAt, publishedAt, createdAt []byte
+
+	if err := row.Scan(&message.ID, &message.Sink, &message.EventID, &message.EventType, &payload,
+		&message.Status, &message.Attempts, &lastError, &nextAttemptAt, &publishedAt, &createdAt); err != nil {
+		return nil, err
+	}
+
+	message.Payload = json.RawMessage(payload)
+	message.LastError = lastError.String
+
+	var err error
+	if message.NextAttemptAt, err = time.Parse("2006-01-02 15:04:05", string(nextAttemptAt)); err != nil {
+		return nil, fmt.Errorf("failed to parse next_attempt_at: %v", err)
+	}
+	if message.PublishedAt, err = parseNullTime(publishedAt); err != nil {
+		return nil, fmt.Errorf("failed to parse published_at: %v", err)
+	}
+	if message.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(createdAt)); err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %v", err)
+	}
+	return message, nil
+}
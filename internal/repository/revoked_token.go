@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// RevokedTokenRepository хранит отозванные access-токены (jti) до истечения их срока,
+// чтобы отзыв был виден всем экземплярам сервиса и сохранялся после перезапуска
+type RevokedTokenRepository struct {
+	db *sql.DB
+}
+
+func NewRevokedTokenRepository(dsn string) *RevokedTokenRepository {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		panic(err)
+	}
+
+	// Устанавливаем настройки пула соединений
+	db.SetMaxOpenConns(5)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	return &RevokedTokenRepository{db: db}
+}
+
+// AddRevokedToken добавляет токен в список отозванных до expiresAt
+func (r *RevokedTokenRepository) AddRevokedToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO revoked_tokens (token_id, expires_at) VALUES (?, ?)
+        ON DUPLICATE KEY UPDATE expires_at = GREATEST(expires_at, VALUES(expires_at))`,
+		tokenID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to add revoked token: %w", err)
+	}
+
+	// Попутно удаляем истекшие записи
+	_, err = r.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at <= ?", time.Now())
+	return err
+}
+
+// ListRevokedTokens возвращает действующие записи списка отозванных токенов
+func (r *RevokedTokenRepository) ListRevokedTokens(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT token_id, expires_at FROM revoked_tokens WHERE expires_at > ?", time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revoked tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make(map[string]time.Time)
+	for rows.Next() {
+		var tokenID string
+		var expiresAt []byte
+		if err := rows.Scan(&tokenID, &expiresAt); err != nil {
+			return nil, err
+		}
+		expires, err := time.Parse("2006-01-02 15:04:05", string(expiresAt))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse expires_at: %v", err)
+		}
+		tokens[tokenID] = expires
+	}
+
+	return tokens, rows.Err()
+}
+
+func (r *RevokedTokenRepository) Close() error {
+	return r.db.Close()
+}
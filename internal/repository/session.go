@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"docs-server/internal/model"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(dsn string) *SessionRepository {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		panic(err)
+	}
+
+	// Устанавливаем настройки пула соединений
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	return &SessionRepository{db: db}
+}
+
+// CreateSession создает сессию вместе с первым refresh-токеном
+func (r *SessionRepository) CreateSession(ctx context.Context, session *model.Session, refreshHash string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO sessions
+        (id, user_id, device, ip, access_token_hash, access_token_id, access_expiry, refresh_expiry, created_at, last_used_at)
+        VALUES (UUID_TO_BIN(?), UUID_TO_BIN(?), ?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, session.UserID, session.Device, session.IP, session.AccessTokenHash, session.AccessTokenID,
+		session.AccessExpiry, session.RefreshExpiry, session.CreatedAt, session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert session: %v", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO refresh_tokens (token_hash, session_id, expires_at)
+        VALUES (?, UUID_TO_BIN(?), ?)`,
+		refreshHash, session.ID, session.RefreshExpiry)
+	if err != nil {
+		return fmt.Errorf("failed to insert refresh token: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) GetSession(ctx context.Context, id string) (*model.Session, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT UUID_TO_STRING(id), UUID_TO_STRING(user_id), device, ip, access_token_hash, access_token_id,
+            access_expiry, refresh_expiry, created_at, last_used_at, revoked_at
+        FROM sessions WHERE id = UUID_TO_BIN(?)`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	return scanSession(rows)
+}
+
+// ListUserSessions возвращает действующие сессии пользователя
+func (r *SessionRepository) ListUserSessions(ctx context.Context, userID string) ([]*model.Session, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT UUID_TO_STRING(id), UUID_TO_STRING(user_id), device, ip, access_token_hash, access_token_id,
+            access_expiry, refresh_expiry, created_at, last_used_at, revoked_at
+        FROM sessions
+        WHERE user_id = UUID_TO_BIN(?) AND revoked_at IS NULL AND refresh_expiry > ?
+        ORDER BY last_used_at DESC`, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*model.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RevokeSession отзывает сессию
+func (r *SessionRepository) RevokeSession(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = ? WHERE id = UUID_TO_BIN(?) AND revoked_at IS NULL",
+		time.Now(), id)
+	return err
+}
+
+// RevokeUserSessions отзывает все сессии пользователя, кроме exceptID (пустой - все), и возвращает отозванные
+func (r *SessionRepository) RevokeUserSessions(ctx context.Context, userID, exceptID string) ([]*model.Session, error) {
+	query := `
+        SELECT UUID_TO_STRING(id), UUID_TO_STRING(user_id), device, ip, access_token_hash, access_token_id,
+            access_expiry, refresh_expiry, created_at, last_used_at, revoked_at
+        FROM sessions
+        WHERE user_id = UUID_TO_BIN(?) AND revoked_at IS NULL`
+	args := []interface{}{userID}
+	if exceptID != "" {
+		query += " AND id <> UUID_TO_BIN(?)"
+		args = append(args, exceptID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*model.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		if err := r.RevokeSession(ctx, session.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
+func (r *SessionRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	token := &model.RefreshToken{}
+	var expiresAt, usedAt []byte
+
+	err := r.db.QueryRowContext(ctx, `
+        SELECT token_hash, UUID_TO_STRING(session_id), expires_at, used_at
+        FROM refresh_tokens WHERE token_hash = ?`, tokenHash).
+		Scan(&token.TokenHash, &token.SessionID, &expiresAt, &usedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	token.ExpiresAt, err = time.Parse("2006-01-02 15:04:05", string(expiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse expires_at: %v", err)
+	}
+	if len(usedAt) > 0 {
+		used, err := time.Parse("2006-01-02 15:04:05", string(usedAt))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse used_at: %v", err)
+		}
+		token.UsedAt = &used
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken помечает refresh-токен использованным и выдает сессии новую пару токенов.
+// Возвращает false, если токен уже был использован (например, параллельным запросом).
+func (r *SessionRepository) RotateRefreshToken(ctx context.Context, oldHash, newHash string, session *model.Session) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE refresh_tokens SET used_at = ? WHERE token_hash = ? AND used_at IS NULL",
+		time.Now(), oldHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token used: %v", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO refresh_tokens (token_hash, session_id, expires_at)
+        VALUES (?, UUID_TO_BIN(?), ?)`,
+		newHash, session.ID, session.RefreshExpiry)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert refresh token: %v", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+        UPDATE sessions
+        SET access_token_hash = ?, access_token_id = ?, access_expiry = ?, refresh_expiry = ?, last_used_at = ?, ip = ?
+        WHERE id = UUID_TO_BIN(?)`,
+		session.AccessTokenHash, session.AccessTokenID, session.AccessExpiry, session.RefreshExpiry, session.LastUsedAt,
+		session.IP, session.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update session: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return true, nil
+}
+
+// scanSession читает сессию из текущей строки выборки
+func scanSession(rows *sql.Rows) (*model.Session, error) {
+	session := &model.Session{}
+	var device, ip sql.NullString
+	var accessExpiry, refreshExpiry, createdAt, lastUsedAt, revokedAt []byte
+
+	if err := rows.Scan(&session.ID, &session.UserID, &device, &ip, &session.AccessTokenHash, &session.AccessTokenID,
+		&accessExpiry, &refreshExpiry, &createdAt, &lastUsedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	session.Device = device.String
+	session.IP = ip.String
+
+	var err error
+	for _, field := range []struct {
+		name  string
+		value []byte
+		dest  *time.Time
+	}{
+		{"access_expiry", accessExpiry, &session.AccessExpiry},
+		{"refresh_expiry", refreshExpiry, &session.RefreshExpiry},
+		{"created_at", createdAt, &session.CreatedAt},
+		{"last_used_at", lastUsedAt, &session.LastUsedAt},
+	} {
+		if *field.dest, err = time.Parse("2006-01-02 15:04:05", string(field.value)); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", field.name, err)
+		}
+	}
+
+	if len(revokedAt) > 0 {
+		revoked, err := time.Parse("2006-01-02 15:04:05", string(revokedAt))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse revoked_at: %v", err)
+		}
+		session.RevokedAt = &revoked
+	}
+
+	return session, nil
+}
+
+func (r *SessionRepository) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"docs-server/internal/model"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GetTOTP возвращает второй фактор пользователя или nil, если он не подключался
+func (r *UserRepository) GetTOTP(ctx context.Context, userID string) (*model.TOTP, error) {
+	var (
+		t         model.TOTP
+		enabledAt []byte
+	)
+	err := r.db.QueryRowContext(ctx, `
+        SELECT UUID_TO_STRING(user_id), secret, enabled_at, last_step
+        FROM user_totp WHERE user_id = UUID_TO_BIN(?)`, userID).Scan(&t.UserID, &t.Secret, &enabledAt, &t.LastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totp: %v", err)
+	}
+
+	if t.EnabledAt, err = parseNullTime(enabledAt); err != nil {
+		return nil, fmt.Errorf("failed to parse enabled_at: %v", err)
+	}
+
+	return &t, nil
+}
+
+// SaveTOTPSecret сохраняет новый неподтвержденный секрет пользователя
+func (r *UserRepository) SaveTOTPSecret(ctx context.Context, userID, secret string) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO user_totp (user_id, secret) VALUES (UUID_TO_BIN(?), ?)
+        ON DUPLICATE KEY UPDATE secret = VALUES(secret), enabled_at = NULL, last_step = 0`,
+		userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save totp secret: %v", err)
+	}
+	return nil
+}
+
+// EnableTOTP подтверждает второй фактор и заменяет коды восстановления
+func (r *UserRepository) EnableTOTP(ctx context.Context, userID string, step int64, recoveryHashes []string, now time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE user_totp SET enabled_at = ?, last_step = ? WHERE user_id = UUID_TO_BIN(?)",
+		now, step, userID); err != nil {
+		return fmt.Errorf("failed to enable totp: %v", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM totp_recovery_codes WHERE user_id = UUID_TO_BIN(?)", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %v", err)
+	}
+
+	for _, hash := range recoveryHashes {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO totp_recovery_codes (code_hash, user_id) VALUES (?, UUID_TO_BIN(?))",
+			hash, userID); err != nil {
+			return fmt.Errorf("failed to save recovery code: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep отмечает шаг использованным. false - шаг не новее последнего принятого (повтор кода)
+func (r *UserRepository) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE user_totp SET last_step = ? WHERE user_id = UUID_TO_BIN(?) AND last_step < ?",
+		step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to update totp step: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ConsumeRecoveryCode помечает код восстановления использованным. false - код не найден или уже использован
+func (r *UserRepository) ConsumeRecoveryCode(ctx context.Context, userID, codeHash string, now time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+        UPDATE totp_recovery_codes SET used_at = ?
+        WHERE code_hash = ? AND user_id = UUID_TO_BIN(?) AND used_at IS NULL`,
+		now, codeHash, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume recovery code: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeleteTOTP отключает второй фактор и удаляет коды восстановления
+func (r *UserRepository) DeleteTOTP(ctx context.Context, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM totp_recovery_codes WHERE user_id = UUID_TO_BIN(?)", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM user_totp WHERE user_id = UUID_TO_BIN(?)", userID); err != nil {
+		return fmt.Errorf("failed to delete totp: %v", err)
+	}
+
+	return tx.Commit()
+}
@@ -33,12 +33,9 @@ func NewUserRepository(dsn string) *UserRepository {
 }
 
 func (r *UserRepository) GetUserByID(ctx context.Context, id string) (*model.User, error) {
-	user := &model.User{}
-	var tokenExpiry []byte
-
-	err := r.db.QueryRowContext(ctx,
-		"SELECT UUID_TO_STRING(id), login, password, token, token_expiry FROM users WHERE id = UUID_TO_BIN(?)", id).
-		Scan(&user.ID, &user.Login, &user.Password, &user.Token, &tokenExpiry)
+	user, err := scanUser(r.db.QueryRowContext(ctx, `
+        SELECT UUID_TO_STRING(id), login, password, email, is_admin, disabled_at, created_at, auth_source, external_id
+        FROM users WHERE id = UUID_TO_BIN(?)`, id))
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -47,29 +44,37 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id string) (*model.Use
 		return nil, err
 	}
 
-	if len(tokenExpiry) > 0 {
-		expiryStr := string(tokenExpiry)
-		expiry, err := time.Parse("2006-01-02 15:04:05", expiryStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse token expiry: %v", err)
-		}
-		user.TokenExpiry = expiry
+	return user, nil
+}
+
+// GetUserByExternalID возвращает пользователя по идентификатору у внешнего источника
+func (r *UserRepository) GetUserByExternalID(ctx context.Context, source, externalID string) (*model.User, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx, `
+        SELECT UUID_TO_STRING(id), login, password, email, is_admin, disabled_at, created_at, auth_source, external_id
+        FROM users WHERE auth_source = ? AND external_id = ?`, source, externalID))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by external id: %w", err)
 	}
 
 	return user, nil
 }
 
-func (r *UserRepository) GetUserByLogin(ctx context.Context, login string) (*model.User, error) {
-	user := &model.User{}
-	var (
-		token       sql.NullString
-		tokenExpiry []byte
-		password    sql.NullString
-	)
+// SetUserExternalID привязывает пользователя к учетной записи внешнего источника
+func (r *UserRepository) SetUserExternalID(ctx context.Context, id, source, externalID string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET auth_source = ?, external_id = ? WHERE id = UUID_TO_BIN(?)",
+		source, nullString(externalID), id)
+	return err
+}
 
-	err := r.db.QueryRowContext(ctx,
-		"SELECT UUID_TO_STRING(id), login, password, token, token_expiry FROM users WHERE login = ?", login).
-		Scan(&user.ID, &user.Login, &password, &token, &tokenExpiry)
+func (r *UserRepository) GetUserByLogin(ctx context.Context, login string) (*model.User, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx, `
+        SELECT UUID_TO_STRING(id), login, password, email, is_admin, disabled_at, created_at, auth_source, external_id
+        FROM users WHERE login = ?`, login))
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -78,97 +83,326 @@ func (r *UserRepository) GetUserByLogin(ctx context.Context, login string) (*mod
 		return nil, fmt.Errorf("failed to get user by login: %w", err)
 	}
 
-	if password.Valid {
-		user.Password = password.String
-	}
-	if token.Valid {
-		user.Token = token.String
-	}
-
-	if len(tokenExpiry) > 0 {
-		expiryStr := string(tokenExpiry)
-		expiry, err := time.Parse("2006-01-02 15:04:05", expiryStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse token expiry: %v", err)
-		}
-		user.TokenExpiry = expiry
-	}
-
 	return user, nil
 }
+
+// UpdateUser сохраняет логин, почту, роль и блокировку пользователя
 func (r *UserRepository) UpdateUser(ctx context.Context, user *model.User) error {
 	_, err := r.db.ExecContext(ctx,
-		"UPDATE users SET login = ? WHERE id = UUID_TO_BIN(?)",
-		user.Login, user.ID)
+		"UPDATE users SET login = ?, email = ?, is_admin = ?, disabled_at = ? WHERE id = UUID_TO_BIN(?)",
+		user.Login, nullString(user.Email), user.IsAdmin, user.DisabledAt, user.ID)
+	return err
+}
+
+// UpdatePassword сохраняет новый хеш пароля пользователя
+func (r *UserRepository) UpdatePassword(ctx context.Context, id, hashedPassword string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET password = ? WHERE id = UUID_TO_BIN(?)", hashedPassword, id)
 	return err
 }
 
-func (r *UserRepository) DeleteUser(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM users WHERE id = UUID_TO_BIN(?)", id)
+// ReplacePasswordHash заменяет хеш пароля, только если он не изменился с момента чтения
+func (r *UserRepository) ReplacePasswordHash(ctx context.Context, id, oldHash, newHash string) error {
+	_, err := r.db.ExecContext(ctx,
+		"UPDATE users SET password = ? WHERE id = UUID_TO_BIN(?) AND password = ?", newHash, id, oldHash)
 	return err
 }
 
+// DeleteUserWithDocuments удаляет пользователя вместе с его документами в одной транзакции.
+// Документы передаются пользователю transferTo (ID), а если он пустой - удаляются с правами доступа,
+// файлы ставятся в очередь pending_deletes и возвращаются для удаления после коммита.
+// При ошибке ничего не меняется, поэтому удаление можно повторить
+func (r *UserRepository) DeleteUserWithDocuments(ctx context.Context, id, transferTo string, outbox ...*model.OutboxMessage) ([]*model.PendingDelete, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var pending []*model.PendingDelete
+	if transferTo != "" {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE documents SET owner_id = UUID_TO_BIN(?) WHERE owner_id = UUID_TO_BIN(?)",
+			transferTo, id); err != nil {
+			return nil, fmt.Errorf("failed to transfer documents: %v", err)
+		}
+	} else {
+		if pending, err = deleteOwnedDocuments(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := insertOutboxMessages(ctx, tx, outbox); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM document_grants WHERE user_id = UUID_TO_BIN(?)", id); err != nil {
+		return nil, fmt.Errorf("failed to delete grants: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = UUID_TO_BIN(?)", id); err != nil {
+		return nil, fmt.Errorf("failed to delete user: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return pending, nil
+}
+
+// deleteOwnedDocuments удаляет документы владельца в транзакции tx и ставит их файлы в очередь pending_deletes.
+// Документы блокируются; если какой-то из них оказался на юридическом удержании, возвращается ошибка
+func deleteOwnedDocuments(ctx context.Context, tx *sql.Tx, ownerID string) ([]*model.PendingDelete, error) {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT UUID_TO_STRING(id), is_file, file_path, legal_hold
+        FROM documents
+        WHERE owner_id = UUID_TO_BIN(?) FOR UPDATE`, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock documents: %v", err)
+	}
+	var pending []*model.PendingDelete
+	for rows.Next() {
+		var docID string
+		var isFile, legalHold bool
+		var filePath sql.NullString
+		if err := rows.Scan(&docID, &isFile, &filePath, &legalHold); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if legalHold {
+			rows.Close()
+			return nil, fmt.Errorf("document %s is under legal hold", docID)
+		}
+		if isFile && filePath.String != "" {
+			pending = append(pending, &model.PendingDelete{FilePath: filePath.String, DocumentID: docID})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	for _, p := range pending {
+		res, err := tx.ExecContext(ctx, `
+            INSERT INTO pending_deletes (file_path, document_id, next_attempt_at)
+            VALUES (?, UUID_TO_BIN(?), ?)`,
+			p.FilePath, p.DocumentID, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to queue file deletion: %v", err)
+		}
+		if p.ID, err = res.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get pending delete id: %v", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        DELETE g FROM document_grants g
+        JOIN documents d ON d.id = g.document_id
+        WHERE d.owner_id = UUID_TO_BIN(?)`, ownerID); err != nil {
+		return nil, fmt.Errorf("failed to delete grants: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM documents WHERE owner_id = UUID_TO_BIN(?)", ownerID); err != nil {
+		return nil, fmt.Errorf("failed to delete documents: %v", err)
+	}
+	return pending, nil
+}
+
 func (r *UserRepository) ListUsers(ctx context.Context, limit, offset int) ([]*model.User, error) {
-	rows, err := r.db.QueryContext(ctx,
-		"SELECT UUID_TO_STRING(id), login FROM users LIMIT ? OFFSET ?", limit, offset)
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT UUID_TO_STRING(id), login, password, email, is_admin, disabled_at, created_at, auth_source, external_id
+        FROM users
+        ORDER BY login
+        LIMIT ? OFFSET ?`, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 
 	var users []*model.User
 	for rows.Next() {
-		user := &model.User{}
-		if err := rows.Scan(&user.ID, &user.Login); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			rows.Close()
 			return nil, err
 		}
 		users = append(users, user)
 	}
 	rows.Close()
-	return users, nil
+	return users, rows.Err()
 }
 
-func (r *UserRepository) CreateUser(ctx context.Context, id string, login, hashedPassword string) error {
+// CountUsers возвращает общее количество пользователей
+func (r *UserRepository) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&count)
+	return count, err
+}
+
+// CountActiveAdmins возвращает количество незаблокированных администраторов
+func (r *UserRepository) CountActiveAdmins(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM users WHERE is_admin = TRUE AND disabled_at IS NULL").Scan(&count)
+	return count, err
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, id string, login, hashedPassword, email string, isAdmin bool) error {
 	_, err := r.db.ExecContext(ctx,
-		"INSERT INTO users (id, login, password) VALUES (UUID_TO_BIN(?), ?, ?)",
-		id, login, hashedPassword)
+		"INSERT INTO users (id, login, password, email, is_admin) VALUES (UUID_TO_BIN(?), ?, ?, ?, ?)",
+		id, login, hashedPassword, nullString(email), isAdmin)
 	return err
 }
 
-func (r *UserRepository) GetUserByToken(ctx context.Context, token string) (*model.User, error) {
-	user := &model.User{}
-	var tokenExpiry []byte
+// CreateExternalUser создает пользователя, учетная запись которого ведется во внешнем источнике
+func (r *UserRepository) CreateExternalUser(ctx context.Context, user *model.User) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO users (id, login, password, email, is_admin, auth_source, external_id)
+        VALUES (UUID_TO_BIN(?), ?, ?, ?, ?, ?, ?)`,
+		user.ID, user.Login, user.Password, nullString(user.Email), user.IsAdmin, user.Source, nullString(user.ExternalID))
+	return err
+}
 
-	err := r.db.QueryRowContext(ctx,
-		"SELECT UUID_TO_STRING(id), login, password, token_expiry FROM users WHERE token = ?", token).
-		Scan(&user.ID, &user.Login, &user.Password, &tokenExpiry)
-	fmt.Println(user.ID)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+// CreateFirstAdmin создает администратора, только если в системе еще нет ни одного.
+// Возвращает false, если администратор уже существует
+func (r *UserRepository) CreateFirstAdmin(ctx context.Context, id string, login, hashedPassword string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+        INSERT INTO users (id, login, password, is_admin)
+        SELECT UUID_TO_BIN(?), ?, ?, TRUE FROM DUAL
+        WHERE NOT EXISTS (SELECT 1 FROM users WHERE is_admin = TRUE)`,
+		id, login, hashedPassword)
+	if err != nil {
+		return false, err
 	}
+
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user by token: %w", err)
+		return false, err
 	}
+	return affected > 0, nil
+}
 
-	if len(tokenExpiry) > 0 {
-		expiryStr := string(tokenExpiry)
-		expiry, err := time.Parse("2006-01-02 15:04:05", expiryStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse token expiry: %v", err)
-		}
-		user.TokenExpiry = expiry
+// GetUserQuota возвращает персональные квоты пользователя (NULL - используется квота по умолчанию)
+func (r *UserRepository) GetUserQuota(ctx context.Context, userID string) (sql.NullInt64, sql.NullInt64, error) {
+	var quotaBytes, quotaDocs sql.NullInt64
+
+	err := r.db.QueryRowContext(ctx,
+		"SELECT quota_bytes, quota_docs FROM users WHERE id = UUID_TO_BIN(?)", userID).
+		Scan(&quotaBytes, &quotaDocs)
+	if errors.Is(err, sql.ErrNoRows) {
+		return quotaBytes, quotaDocs, nil
+	}
+	if err != nil {
+		return quotaBytes, quotaDocs, fmt.Errorf("failed to get user quota: %w", err)
 	}
 
-	return user, nil
+	return quotaBytes, quotaDocs, nil
 }
 
-func (r *UserRepository) UpdateUserToken(ctx context.Context, userID string, token string, expiry time.Time) error {
-	_, err := r.db.ExecContext(ctx,
-		"UPDATE users SET token = ?, token_expiry = ? WHERE id = UUID_TO_BIN(?)",
-		token, expiry.Format("2006-01-02 15:04:05"), userID)
+// UpdateUserWithQuota сохраняет пользователя вместе с персональными квотами одним запросом.
+// nil - квота не меняется, NULL - используется квота по умолчанию
+func (r *UserRepository) UpdateUserWithQuota(ctx context.Context, user *model.User, quotaBytes, quotaDocs *sql.NullInt64) error {
+	var bytes, docs sql.NullInt64
+	if quotaBytes != nil {
+		bytes = *quotaBytes
+	}
+	if quotaDocs != nil {
+		docs = *quotaDocs
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE users SET login = ?, email = ?, is_admin = ?, disabled_at = ?,
+            quota_bytes = IF(?, ?, quota_bytes),
+            quota_docs = IF(?, ?, quota_docs)
+        WHERE id = UUID_TO_BIN(?)`,
+		user.Login, nullString(user.Email), user.IsAdmin, user.DisabledAt,
+		quotaBytes != nil, bytes, quotaDocs != nil, docs, user.ID)
 	return err
 }
 
 func (r *UserRepository) Close() error {
 	return r.db.Close()
 }
+
+// CreatePasswordReset сохраняет хеш токена сброса пароля.
+// Ранее выданные неиспользованные токены пользователя аннулируются
+func (r *UserRepository) CreatePasswordReset(ctx context.Context, tokenHash, userID string, expiresAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM password_resets WHERE user_id = UUID_TO_BIN(?) AND used_at IS NULL", userID); err != nil {
+		return fmt.Errorf("failed to delete previous resets: %v", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES (?, UUID_TO_BIN(?), ?)",
+		tokenHash, userID, expiresAt); err != nil {
+		return fmt.Errorf("failed to create password reset: %v", err)
+	}
+
+	return tx.Commit()
+}
+
+// ConsumePasswordReset помечает действующий токен сброса использованным
+// и возвращает ID пользователя. Пустой ID - токен не найден, истек или уже использован
+func (r *UserRepository) ConsumePasswordReset(ctx context.Context, tokenHash string, now time.Time) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var userID string
+	err = tx.QueryRowContext(ctx, `
+        SELECT UUID_TO_STRING(user_id) FROM password_resets
+        WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+        FOR UPDATE`, tokenHash, now).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get password reset: %v", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE password_resets SET used_at = ? WHERE token_hash = ?", now, tokenHash); err != nil {
+		return "", fmt.Errorf("failed to consume password reset: %v", err)
+	}
+
+	return userID, tx.Commit()
+}
+
+// nullString сохраняет пустую строку как NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// scanUser читает пользователя из строки выборки
+func scanUser(row interface{ Scan(...any) error }) (*model.User, error) {
+	user := &model.User{}
+	var password, email, externalID sql.NullString
+	var disabledAt, createdAt []byte
+
+	if err := row.Scan(&user.ID, &user.Login, &password, &email, &user.IsAdmin, &disabledAt, &createdAt,
+		&user.Source, &externalID); err != nil {
+		return nil, err
+	}
+	user.Password = password.String
+	user.Email = email.String
+	user.ExternalID = externalID.String
+
+	var err error
+	if user.DisabledAt, err = parseNullTime(disabledAt); err != nil {
+		return nil, fmt.Errorf("failed to parse disabled_at: %v", err)
+	}
+	if len(createdAt) > 0 {
+		if user.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(createdAt)); err != nil {
+			return nil, fmt.Errorf("failed to parse created_at: %v", err)
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"docs-server/internal/model"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// WebhookRepository webhook и журнал их доставок
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(dsn string) *WebhookRepository {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		panic(err)
+	}
+
+	// Устанавливаем настройки пула соединений
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, hook *model.Webhook) error {
+	events, err := json.Marshal(hook.Events)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook events: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+        INSERT INTO webhooks (id, owner_id, is_global, url, events, secret, created_at)
+        VALUES (UUID_TO_BIN(?), UUID_TO_BIN(?), ?, ?, ?, ?, ?)`,
+		hook.ID, hook.OwnerID, hook.Global, hook.URL, string(events), hook.Secret, hook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook: %v", err)
+	}
+	return nil
+}
+
+// GetWebhook возвращает webhook по ID или nil, если его нет
+func (r *WebhookRepository) GetWebhook(ctx context.Context, id string) (*model.Webhook, error) {
+	hook, err := scanWebhook(r.db.QueryRowContext(ctx, webhookSelect+" WHERE id = UUID_TO_BIN(?)", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return hook, err
+}
+
+// ListWebhooks возвращает глобальные webhook (global) или webhook пользователя ownerID
+func (r *WebhookRepository) ListWebhooks(ctx context.Context, ownerID string, global bool) ([]*model.Webhook, error) {
+	if global {
+		return r.queryWebhooks(ctx, webhookSelect+" WHERE is_global = 1 ORDER BY created_at DESC")
+	}
+	return r.queryWebhooks(ctx, webhookSelect+" WHERE owner_id = UUID_TO_BIN(?) AND is_global = 0 ORDER BY created_at DESC", ownerID)
+}
+
+// ListAllWebhooks возвращает все webhook для рассылки события
+func (r *WebhookRepository) ListAllWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	return r.queryWebhooks(ctx, webhookSelect)
+}
+
+// DeleteWebhook удаляет webhook, журнал доставок удаляется каскадно
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = UUID_TO_BIN(?)", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	_, err := r.db.ExecContext(ctx, `
+        INSERT INTO webhook_deliveries
+            (id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+        VALUES (UUID_TO_BIN(?), UUID_TO_BIN(?), ?, ?, ?, ?, ?, ?, ?)`,
+		delivery.ID, delivery.WebhookID, delivery.EventID, delivery.EventType, string(delivery.Payload),
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook delivery: %v", err)
+	}
+	return nil
+}
+
+// GetDelivery возвращает доставку по ID или nil, если ее нет
+func (r *WebhookRepository) GetDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx, deliverySelect+" WHERE id = UUID_TO_BIN(?)", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+// ListDeliveries возвращает последние доставки webhook, новые первыми
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*model.WebhookDelivery, error) {
+	return r.queryDeliveries(ctx,
+		deliverySelect+" WHERE webhook_id = UUID_TO_BIN(?) ORDER BY created_at DESC LIMIT ?", webhookID, limit)
+}
+
+// DeliveryExists сообщает, есть ли у webhook доставка события eventID
+func (r *WebhookRepository) DeliveryExists(ctx context.Context, webhookID, eventID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM webhook_deliveries WHERE webhook_id = UUID_TO_BIN(?) AND event_id = ?)",
+		webhookID, eventID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check webhook delivery: %v", err)
+	}
+	return exists, nil
+}
+
+// ClaimDueDeliveries закрепляет за вызывающим до limit доставок, срок попытки которых наступил:
+// их следующая попытка переносится на now + lease, поэтому другие экземпляры их не выберут
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error) {
+	due, err := r.queryDeliveries(ctx, deliverySelect+`
+        WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?`,
+		model.DeliveryPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	leased := now.Add(lease).Truncate(time.Second)
+	var claimed []*model.WebhookDelivery
+	for _, delivery := range due {
+		res, err := r.db.ExecContext(ctx, `
+            UPDATE webhook_deliveries SET next_attempt_at = ?
+            WHERE id = UUID_TO_BIN(?) AND status = ? AND next_attempt_at = ?`,
+			leased, delivery.ID, model.DeliveryPending, delivery.NextAttemptAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim webhook delivery: %v", err)
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			continue
+		}
+		delivery.NextAttemptAt = leased
+		claimed = append(claimed, delivery)
+	}
+
+	return claimed, nil
+}
+
+// UpdateDelivery сохраняет результат попытки доставки
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	var responseCode sql.NullInt64
+	if delivery.ResponseCode != 0 {
+		responseCode = sql.NullInt64{Int64: int64(delivery.ResponseCode), Valid: true}
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE webhook_deliveries
+        SET status = ?, attempts = ?, response_code = ?, last_error = ?, next_attempt_at = ?, delivered_at = ?
+        WHERE id = UUID_TO_BIN(?)`,
+		delivery.Status, delivery.Attempts, responseCode, nullString(delivery.LastError),
+		delivery.NextAttemptAt, delivery.DeliveredAt, delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) Close() error {
+	return r.db.Close()
+}
+
+const webhookSelect = `
+        SELECT UUID_TO_STRING(id), UUID_TO_STRING(owner_id), is_global, url, events, secret, created_at
+        FROM webhooks`
+
+const deliverySelect = `
+        SELECT UUID_TO_STRING(id), UUID_TO_STRING(webhook_id), event_id, event_type, payload, status, attempts,
+            response_code, last_error, next_attempt_at, delivered_at, created_at
+        FROM webhook_deliveries`
+
+func (r *WebhookRepository) queryWebhooks(ctx context.Context, query string, args ...interface{}) ([]*model.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %v", err)
+	}
+	defer rows.Close()
+
+	var hooks []*model.Webhook
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *WebhookRepository) queryDeliveries(ctx context.Context, query string, args ...interface{}) ([]*model.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// scanWebhook читает webhook из строки выборки
+func scanWebhook(row interface{ Scan(...any) error }) (*model.Webhook, error) {
+	hook := &model.Webhook{}
+	var events string
+	var createdAt []byte
+
+	if err := row.Scan(&hook.ID, &hook.OwnerID, &hook.Global, &hook.URL, &events, &hook.Secret, &createdAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(events), &hook.Events); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook events: %v", err)
+	}
+	var err error
+	if hook.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(createdAt)); err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %v", err)
+	}
+	return hook, nil
+}
+
+// scanDelivery читает доставку из строки выборки
+func scanDelivery(row interface{ Scan(...any) error }) (*model.WebhookDelivery, error) {
+	delivery := &model.WebhookDelivery{}
+	var payload string
+	var responseCode sql.NullInt64
+	var lastError sql.NullString
+	var nextAttemptAt, deliveredAt, createdAt []byte
+
+	if err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &payload,
+		&delivery.Status, &delivery.Attempts, &responseCode, &lastError, &nextAttemptAt, &deliveredAt, &createdAt); err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = json.RawMessage(payload)
+	delivery.ResponseCode = int(responseCode.Int64)
+	delivery.LastError = lastError.String
+
+	var err error
+	if delivery.NextAttemptAt, err = time.Parse("2006-01-02 15:04:05", string(nextAttemptAt)); err != nil {
+		return nil, fmt.Errorf("failed to parse next_attempt_at: %v", err)
+	}
+	if delivery.DeliveredAt, err = parseNullTime(deliveredAt); err != nil {
+		return nil, fmt.Errorf("failed to parse delivered_at: %v", err)
+	}
+	if delivery.CreatedAt, err = time.Parse("2006-01-02 15:04:05", string(createdAt)); err != nil {
+		return nil, fmt.Errorf("failed to parse created_at: %v", err)
+	}
+	return delivery, nil
+}
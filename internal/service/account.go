@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrWrongPassword = errors.New("current password is incorrect")
+	ErrSamePassword  = errors.New("new password must differ from the current one")
+)
+
+// ChangePassword меняет пароль пользователя после проверки текущего.
+// Все сессии, кроме currentSessionID, отзываются
+func (s *UserService) ChangePassword(ctx context.Context, userID, currentSessionID, oldPassword, newPassword string) error {
+	// Пользователь из контекста запроса может быть кеширован, хеш пароля читаем заново
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !s.authService.checkPassword(user.Password, oldPassword) {
+		return ErrWrongPassword
+	}
+	if oldPassword == newPassword {
+		return ErrSamePassword
+	}
+
+	hashedPassword, err := s.authService.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return s.authService.RevokeOtherSessions(ctx, user.ID, currentSessionID)
+}
+
+// DeleteAccount удаляет учетную запись пользователя по его запросу.
+// Документы передаются пользователю transferTo (логин), а если он не указан - удаляются
+func (s *UserService) DeleteAccount(ctx context.Context, userID, password, transferTo string) error {
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !s.authService.checkPassword(user.Password, password) {
+		return ErrWrongPassword
+	}
+
+	if user.IsAdmin && !user.Disabled() {
+		if err := s.checkNotLastAdmin(ctx); err != nil {
+			return err
+		}
+	}
+
+	return s.deleteUser(ctx, user, transferTo)
+}
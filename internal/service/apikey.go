@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"docs-server/internal/model"
+)
+
+// APIKeyPrefix отличает API-ключи от JWT в заголовке Authorization
+const APIKeyPrefix = "dsk_"
+
+// apiKeyTouchInterval минимальный интервал обновления last_used_at, чтобы не писать в БД на каждый запрос
+const apiKeyTouchInterval = time.Minute
+
+var (
+	ErrInvalidAPIKey   = errors.New("invalid api key")
+	ErrAPIKeyExpired   = errors.New("api key expired")
+	ErrAPIKeyNotFound  = errors.New("api key not found")
+	ErrAPIKeyReadOnly  = errors.New("forbidden: api key is read-only")
+	ErrAPIKeyNameEmpty = errors.New("api key name cannot be empty")
+)
+
+// IsAPIKey проверяет, является ли токен API-ключом
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// CreateAPIKey создает API-ключ. Сам ключ возвращается только здесь, в БД хранится его хеш.
+func (s *AuthService) CreateAPIKey(userID, name string, readOnly bool, expiresAt *time.Time) (*model.APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", ErrAPIKeyNameEmpty
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", ErrInvalidExpiry
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, err := generateID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	secret = APIKeyPrefix + secret
+
+	key := &model.APIKey{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:len(APIKeyPrefix)+6],
+		KeyHash:   hashToken(secret),
+		ReadOnly:  readOnly,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+// ListAPIKeys возвращает API-ключи пользователя
+func (s *AuthService) ListAPIKeys(userID string) ([]*model.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.apiKeyRepo.ListUserAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKey отзывает API-ключ пользователя
+func (s *AuthService) RevokeAPIKey(userID, id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	key, err := s.apiKeyRepo.GetAPIKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key == nil || key.UserID != userID || key.RevokedAt != nil {
+		return ErrAPIKeyNotFound
+	}
+
+	if err := s.apiKeyRepo.RevokeAPIKey(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	s.tokenCache.Delete("apikey_" + key.KeyHash)
+
+	return nil
+}
+
+// ValidateAPIKey проверяет API-ключ и возвращает его владельца
+func (s *AuthService) ValidateAPIKey(secret string) (*model.User, *model.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	keyHash := hashToken(secret)
+	cacheKey := "apikey_" + keyHash
+
+	var key *model.APIKey
+	var user *model.User
+	if cached, found := s.tokenCache.Get(cacheKey); found {
+		if entry, ok := cached.(*cachedAPIKey); ok {
+			// Запись кеша общая для параллельных запросов: работаем с копиями
+			cachedKey, cachedUser := *entry.key, *entry.user
+			key, user = &cachedKey, &cachedUser
+		}
+	}
+
+	if key == nil {
+		var err error
+		key, err = s.apiKeyRepo.GetAPIKeyByHash(ctx, keyHash)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get api key: %w", err)
+		}
+		if key == nil || key.RevokedAt != nil {
+			return nil, nil, ErrInvalidAPIKey
+		}
+
+		user, err = s.userRepo.GetUserByID(ctx, key.UserID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user == nil {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		if user.Disabled() {
+			return nil, nil, ErrUserDisabled
+		}
+
+		cachedKey, cachedUser := *key, *user
+		s.tokenCache.Set(cacheKey, &cachedAPIKey{key: &cachedKey, user: &cachedUser}, sessionCacheTTL)
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, nil, ErrAPIKeyExpired
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.apiKeyRepo.UpdateLastUsed(ctx, key.ID, now); err != nil {
+			return nil, nil, fmt.Errorf("failed to update api key usage: %w", err)
+		}
+		key.LastUsedAt = &now
+	}
+
+	return user, key, nil
+}
+
+// ForgetAPIKeys удаляет из кеша API-ключи пользователя, чтобы следующий запрос
+// прочитал из БД его текущие роль и состояние
+func (s *AuthService) ForgetAPIKeys(ctx context.Context, userID string) error {
+	keys, err := s.apiKeyRepo.ListUserAPIKeys(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list api keys: %w", err)
+	}
+	for _, key := range keys {
+		s.tokenCache.Delete("apikey_" + key.KeyHash)
+	}
+	return nil
+}
+
+// cachedAPIKey ключ и его владелец в кеше. Не изменяются после записи в кеш
+type cachedAPIKey struct {
+	user *model.User
+	key  *model.APIKey
+}
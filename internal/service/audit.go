@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"docs-server/internal/model"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalidAuditFilter = errors.New("invalid audit filter")
+
+// maxAuditLimit наибольший размер страницы журнала аудита
+const maxAuditLimit = 1000
+
+// AuditStore хранилище журнала аудита (только добавление и чтение)
+type AuditStore interface {
+	InsertAuditEvent(ctx context.Context, event *model.AuditEvent) error
+	ListAuditEvents(ctx context.Context, filter *model.AuditFilter, limit, offset int) ([]*model.AuditEvent, error)
+	CountAuditEvents(ctx context.Context, filter *model.AuditFilter) (int, error)
+	ExportAuditEvents(ctx context.Context, filter *model.AuditFilter, fn func(*model.AuditEvent) error) error
+	AuditChainHead(ctx context.Context) (int64, string, error)
+	LastAuditEventBefore(ctx context.Context, before time.Time) (*model.AuditEvent, error)
+	FirstAuditEventTime(ctx context.Context) (*time.Time, error)
+	InsertAuditCheckpoint(ctx context.Context, checkpoint *model.AuditCheckpoint) error
+	ListAuditCheckpoints(ctx context.Context) ([]*model.AuditCheckpoint, error)
+}
+
+// AuditService журнал входов и доступа к документам
+type AuditService struct {
+	store AuditStore
+}
+
+func NewAuditService(store AuditStore) *AuditService {
+	return &AuditService{store: store}
+}
+
+// Record добавляет событие в журнал. Ошибка записи не прерывает действие пользователя
+// и только попадает в лог. Для nil-сервиса журнал отключен
+func (s *AuditService) Record(event *model.AuditEvent) {
+	if s == nil {
+		return
+	}
+
+	// Время с точностью до секунды, как хранится в БД
+	event.Time = time.Now().UTC().Truncate(time.Second)
+	if event.Outcome == "" {
+		event.Outcome = model.AuditSuccess
+	}
+	// Длина по размеру колонок. Обрезка по символам, чтобы в БД попало то же значение,
+	// от которого считается хеш цепочки
+	event.UserAgent = truncateRunes(event.UserAgent, 512)
+	event.ActorLogin = truncateRunes(event.ActorLogin, 255)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.store.InsertAuditEvent(ctx, event); err != nil {
+		log.Printf("audit: failed to record %s: %v", event.Action, err)
+	}
+}
+
+// List возвращает страницу журнала, новые записи первыми, и общее количество записей по фильтру
+func (s *AuditService) List(ctx context.Context, filter *model.AuditFilter, limit, offset int) ([]*model.AuditEvent, int, error) {
+	if limit <= 0 || limit > maxAuditLimit {
+		return nil, 0, ErrInvalidLimit
+	}
+	if offset < 0 {
+		return nil, 0, ErrInvalidOffset
+	}
+	if err := validateAuditFilter(filter); err != nil {
+		return nil, 0, err
+	}
+
+	events, err := s.store.ListAuditEvents(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.store.CountAuditEvents(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// Export записывает события по фильтру в w в формате JSON Lines в порядке добавления
+func (s *AuditService) Export(ctx context.Context, filter *model.AuditFilter, w io.Writer) error {
+	if err := validateAuditFilter(filter); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	return s.store.ExportAuditEvents(ctx, filter, func(event *model.AuditEvent) error {
+		return encoder.Encode(event)
+	})
+}
+
+// truncateRunes обрезает строку до n символов, заменяя некорректные последовательности UTF-8
+func truncateRunes(s string, n int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func validateAuditFilter(filter *model.AuditFilter) error {
+	if filter.Outcome != "" && filter.Outcome != model.AuditSuccess && filter.Outcome != model.AuditFailure {
+		return ErrInvalidAuditFilter
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return ErrInvalidAuditFilter
+	}
+	return nil
+}
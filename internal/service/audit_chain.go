@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"docs-server/internal/jose"
+	"docs-server/internal/model"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// auditCheckpointDelay задержка создания контрольной точки после конца суток,
+// чтобы успели сохраниться записи последних секунд
+const auditCheckpointDelay = time.Minute
+
+// auditCheckpointClaims подписываемые поля контрольной точки
+type auditCheckpointClaims struct {
+	Day      string `json:"day"`
+	LastID   int64  `json:"last_id"`
+	LastHash string `json:"last_hash"`
+	Events   int    `json:"events"`
+	jwt.RegisteredClaims
+}
+
+// SignAuditCheckpoint подписывает поля контрольной точки ключом keys
+func SignAuditCheckpoint(keys *jose.KeySet, checkpoint *model.AuditCheckpoint) (string, error) {
+	return keys.Sign(auditCheckpointClaims{
+		Day:      checkpoint.Day.Format("2006-01-02"),
+		LastID:   checkpoint.LastID,
+		LastHash: checkpoint.LastHash,
+		Events:   checkpoint.Events,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt: jwt.NewNumericDate(checkpoint.CreatedAt),
+		},
+	})
+}
+
+// VerifyAuditCheckpoint проверяет подпись контрольной точки и совпадение подписанных полей с сохраненными
+func VerifyAuditCheckpoint(keys *jose.KeySet, checkpoint *model.AuditCheckpoint) error {
+	claims := &auditCheckpointClaims{}
+	if _, err := jwt.ParseWithClaims(checkpoint.Signature, claims, keys.Keyfunc,
+		jwt.WithValidMethods(keys.Methods())); err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+
+	if claims.Day != checkpoint.Day.Format("2006-01-02") || claims.LastID != checkpoint.LastID ||
+		claims.LastHash != checkpoint.LastHash || claims.Events != checkpoint.Events {
+		return errors.New("signed fields do not match stored checkpoint")
+	}
+	return nil
+}
+
+// CreateCheckpoints подписывает контрольные точки за завершившиеся сутки (UTC), начиная
+// с дня после последней точки или с дня первой записи журнала. Возвращает количество новых точек
+func (s *AuditService) CreateCheckpoints(ctx context.Context, keys *jose.KeySet, now time.Time) (int, error) {
+	checkpoints, err := s.store.ListAuditCheckpoints(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var day time.Time
+	if len(checkpoints) > 0 {
+		day = checkpoints[len(checkpoints)-1].Day.AddDate(0, 0, 1)
+	} else {
+		first, err := s.store.FirstAuditEventTime(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if first == nil {
+			return 0, nil
+		}
+		day = startOfDay(*first)
+	}
+
+	today := startOfDay(now.Add(-auditCheckpointDelay))
+	created := 0
+	for ; day.Before(today); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+
+		last, err := s.store.LastAuditEventBefore(ctx, next)
+		if err != nil {
+			return created, err
+		}
+		if last == nil {
+			continue
+		}
+		count, err := s.store.CountAuditEvents(ctx, &model.AuditFilter{From: &day, To: &next})
+		if err != nil {
+			return created, err
+		}
+
+		checkpoint := &model.AuditCheckpoint{
+			Day:       day,
+			LastID:    last.ID,
+			LastHash:  last.Hash,
+			Events:    count,
+			CreatedAt: now.UTC().Truncate(time.Second),
+		}
+		if checkpoint.Signature, err = SignAuditCheckpoint(keys, checkpoint); err != nil {
+			return created, fmt.Errorf("failed to sign audit checkpoint: %w", err)
+		}
+		if err := s.store.InsertAuditCheckpoint(ctx, checkpoint); err != nil {
+			return created, err
+		}
+		created++
+	}
+
+	return created, nil
+}
+
+// errChainBroken останавливает обход журнала на первом нарушении
+var errChainBroken = errors.New("audit chain broken")
+
+// VerifyChain проходит журнал в порядке добавления и проверяет, что каждая запись ссылается
+// на хеш предыдущей и ее хеш совпадает с пересчитанным, а затем сверяет контрольные точки.
+// Подписи точек проверяются, если переданы ключи. Первое нарушение возвращается в отчете
+func (s *AuditService) VerifyChain(ctx context.Context, keys *jose.KeySet) (*model.AuditChainReport, error) {
+	checkpoints, err := s.store.ListAuditCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Голова читается до обхода: записи, добавленные во время проверки, ее не сдвинут
+	headID, headHash, err := s.store.AuditChainHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.AuditChainReport{
+		Checkpoints:       len(checkpoints),
+		SignaturesChecked: keys != nil,
+	}
+
+	// Хеши записей, на которые ссылаются контрольные точки и голова цепочки
+	observed := map[int64]*string{headID: nil}
+	for _, checkpoint := range checkpoints {
+		observed[checkpoint.LastID] = nil
+	}
+	dayCounts := make(map[string]int)
+
+	prevHash := ""
+	chained := false
+	err = s.store.ExportAuditEvents(ctx, &model.AuditFilter{}, func(event *model.AuditEvent) error {
+		if _, ok := observed[event.ID]; ok {
+			hash := event.Hash
+			observed[event.ID] = &hash
+		}
+		dayCounts[event.Time.UTC().Format("2006-01-02")]++
+
+		// Записи до включения цепочки пропускаются, пока не встретится первая с хешем
+		if event.Hash == "" && !chained {
+			report.Unchained++
+			return nil
+		}
+		chained = true
+
+		if event.PrevHash != prevHash {
+			report.Broken = &model.AuditChainBreak{EventID: event.ID,
+				Reason: "prev_hash does not match previous record: records were removed, inserted or reordered"}
+			return errChainBroken
+		}
+		hash, err := event.ChainHash(event.PrevHash)
+		if err != nil {
+			return err
+		}
+		if hash != event.Hash {
+			report.Broken = &model.AuditChainBreak{EventID: event.ID, Reason: "hash mismatch: record was modified"}
+			return errChainBroken
+		}
+
+		prevHash = event.Hash
+		report.Events++
+		return nil
+	})
+	if errors.Is(err, errChainBroken) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if headID > 0 {
+		if hash := observed[headID]; hash == nil || *hash != headHash {
+			report.Broken = &model.AuditChainBreak{EventID: headID,
+				Reason: "chain head does not match last record: records were removed from the end"}
+			return report, nil
+		}
+	}
+
+	for _, checkpoint := range checkpoints {
+		day := checkpoint.Day.Format("2006-01-02")
+		if keys != nil {
+			if err := VerifyAuditCheckpoint(keys, checkpoint); err != nil {
+				report.Broken = &model.AuditChainBreak{Checkpoint: day, Reason: err.Error()}
+				return report, nil
+			}
+		}
+
+		hash := observed[checkpoint.LastID]
+		switch {
+		case hash == nil:
+			report.Broken = &model.AuditChainBreak{EventID: checkpoint.LastID, Checkpoint: day,
+				Reason: "checkpoint record is missing"}
+		case *hash != checkpoint.LastHash:
+			report.Broken = &model.AuditChainBreak{EventID: checkpoint.LastID, Checkpoint: day,
+				Reason: "checkpoint hash does not match record"}
+		case dayCounts[day] != checkpoint.Events:
+			report.Broken = &model.AuditChainBreak{Checkpoint: day,
+				Reason: fmt.Sprintf("checkpoint has %d events, log has %d", checkpoint.Events, dayCounts[day])}
+		}
+		if report.Broken != nil {
+			return report, nil
+		}
+	}
+
+	return report, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// AuditCheckpointer периодически подписывает контрольные точки журнала аудита
+type AuditCheckpointer struct {
+	auditService *AuditService
+	keys         *jose.KeySet
+	interval     time.Duration
+}
+
+func NewAuditCheckpointer(auditService *AuditService, keys *jose.KeySet, interval time.Duration) *AuditCheckpointer {
+	return &AuditCheckpointer{
+		auditService: auditService,
+		keys:         keys,
+		interval:     interval,
+	}
+}
+
+// Run создает контрольные точки до отмены контекста
+func (c *AuditCheckpointer) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			created, err := c.auditService.CreateCheckpoints(ctx, c.keys, time.Now())
+			if err != nil {
+				log.Printf("audit checkpointer: %v", err)
+			}
+			if created > 0 {
+				log.Printf("audit checkpointer: signed %d checkpoints", created)
+			}
+		}
+	}
+}
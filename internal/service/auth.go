@@ -2,86 +2,129 @@ package service
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"regexp"
+	"log"
 	"time"
-	"unicode"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 
 	"docs-server/internal/cache"
+	"docs-server/internal/jose"
 	"docs-server/internal/model"
+	"docs-server/internal/password"
 	"docs-server/internal/repository"
 )
 
 var (
-	ErrInvalidAdminToken  = errors.New("invalid admin token")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrTokenExpired       = errors.New("token expired")
+	ErrInvalidAdminToken   = errors.New("invalid admin token")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrTokenExpired        = errors.New("token expired")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+	ErrSessionRevoked      = errors.New("session revoked")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrAdminExists         = errors.New("admin already exists: admin token is accepted only for bootstrap")
+	ErrUserDisabled        = errors.New("user is disabled")
+	ErrLoginTaken          = errors.New("login already taken")
 )
 
-// JWT секретный ключ (в продакшене должен храниться в безопасном месте)
-var jwtSecret = generateSecureKey(32) // 256-bit key
-
 type AuthService struct {
 	userRepo    *repository.UserRepository
+	sessionRepo *repository.SessionRepository
+	apiKeyRepo  *repository.APIKeyRepository
 	adminToken  string
-	jwtSecret   []byte
-	tokenExpiry time.Duration
-	tokenCache  *cache.MemoryCache // Кеш для токенов
+	hasher      password.Hasher    // Хеширование паролей
+	policy      *CredentialPolicy  // Требования к логинам и паролям
+	tokens      *jose.TokenIssuer  // Выпуск и проверка access-токенов и токенов второго шага
+	denylist    *TokenDenylist     // Отозванные токены в режиме без сессий (nil - проверка по сессии в БД)
+	tokenCache  *cache.MemoryCache // Кеш для сессий
+	reset       PasswordReset
+	resetSlots  chan struct{} // Ограничение параллельных фоновых отправок токенов сброса
+	guard       *LoginGuard   // Защита от перебора паролей (nil - отключена)
+	mfa         MFAPolicy
+	oidc        OIDCLogin
+	// Источники проверки пароля в порядке опроса
+	authenticators []Authenticator
 }
 
-// Claims - структура для хранения данных в токене
-type Claims struct {
-	UserID string `json:"user_id"`
-	Login  string `json:"login"`
-	jwt.RegisteredClaims
-}
 type jwtClaims struct {
-	UserID string `json:"user_id"`
-	Login  string `json:"login"`
+	UserID    string `json:"user_id"`
+	Login     string `json:"login"`
+	SessionID string `json:"sid"`
+	IsAdmin   bool   `json:"adm,omitempty"`
 	jwt.RegisteredClaims
 }
 
+func (c *jwtClaims) Registered() *jwt.RegisteredClaims {
+	return &c.RegisteredClaims
+}
+
 func NewAuthService(
 	userRepo *repository.UserRepository,
+	sessionRepo *repository.SessionRepository,
+	apiKeyRepo *repository.APIKeyRepository,
 	adminToken string,
-	jwtSecret []byte,
+	hasher password.Hasher,
+	policy *CredentialPolicy,
+	tokens *jose.TokenIssuer,
+	denylist *TokenDenylist,
 	tokenCache *cache.MemoryCache,
+	reset PasswordReset,
+	guard *LoginGuard,
+	mfa MFAPolicy,
+	oidc OIDCLogin,
+	authenticators []Authenticator,
 ) *AuthService {
-	if len(jwtSecret) == 0 {
-		panic("jwt secret cannot be empty")
+	if tokens == nil {
+		panic("token issuer cannot be empty")
+	}
+	if hasher == nil {
+		hasher = password.Default()
+	}
+	if policy == nil {
+		policy = DefaultCredentialPolicy()
+	}
+	if len(authenticators) == 0 {
+		authenticators = []Authenticator{NewPasswordAuthenticator(userRepo, hasher)}
 	}
 
-	return &AuthService{
+	s := &AuthService{
 		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		apiKeyRepo:  apiKeyRepo,
 		adminToken:  adminToken,
-		jwtSecret:   jwtSecret,
-		tokenExpiry: 24 * time.Hour,
+		hasher:      hasher,
+		policy:      policy,
+		tokens:      tokens,
+		denylist:    denylist,
 		tokenCache:  tokenCache,
+		reset:       reset,
+		resetSlots:  make(chan struct{}, maxPendingResets),
+		guard:       guard,
+		mfa:         mfa,
+		oidc:        oidc,
+
+		authenticators: authenticators,
+	}
+	for _, authenticator := range authenticators {
+		if ldap, ok := authenticator.(*LDAPAuthenticator); ok {
+			ldap.revokeRole = s.revokeRoleAccess
+		}
 	}
+	return s
 }
 
+// Register создает первого администратора по токену начальной настройки.
+// После появления администратора пользователей создают через /api/admin/users
 func (s *AuthService) Register(adminToken, login, password string) error {
-	if adminToken != s.adminToken {
+	if adminToken == "" || adminToken != s.adminToken {
 		return ErrInvalidAdminToken
 	}
 
-	if err := validateLogin(login); err != nil {
-		return err
-	}
-
-	if err := validatePassword(password); err != nil {
-		return err
-	}
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hashNewPassword(login, password)
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		return err
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -92,250 +135,277 @@ func (s *AuthService) Register(adminToken, login, password string) error {
 		return fmt.Errorf("failed to generate user ID: %w", err)
 	}
 
-	return s.userRepo.CreateUser(ctx, userID, login, string(hashedPassword))
+	created, err := s.userRepo.CreateFirstAdmin(ctx, userID, login, hashedPassword)
+	if err != nil {
+		return fmt.Errorf("failed to create admin: %w", err)
+	}
+	if !created {
+		return ErrAdminExists
+	}
+
+	return nil
 }
 
-func (s *AuthService) Authenticate(login, password string) (string, error) {
+// CreateUser создает пользователя (вызывается администратором)
+func (s *AuthService) CreateUser(login, password, email string, isAdmin bool) (*model.User, error) {
+	hashedPassword, err := s.hashNewPassword(login, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateEmail(email); err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Проверяем кеш перед обращением к БД
-	cacheKey := "auth_" + login
-	if cachedToken, found := s.tokenCache.Get(cacheKey); found {
-		if token, ok := cachedToken.(string); ok {
-			return token, nil
-		}
-	}
-
-	user, err := s.userRepo.GetUserByLogin(ctx, login)
+	existing, err := s.userRepo.GetUserByLogin(ctx, login)
 	if err != nil {
-		return "", fmt.Errorf("failed to get user: %w", err)
-	}
-	if user == nil {
-		return "", ErrInvalidCredentials
+		return nil, err
 	}
-
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", ErrInvalidCredentials
+	if existing != nil {
+		return nil, ErrLoginTaken
 	}
 
-	token, err := s.generateJWTToken(user.ID, user.Login)
+	userID, err := generateID()
 	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, fmt.Errorf("failed to generate user ID: %w", err)
 	}
 
-	expiryTime := time.Now().Add(s.tokenExpiry)
-	if err := s.userRepo.UpdateUserToken(ctx, user.ID, token, expiryTime); err != nil {
-		return "", fmt.Errorf("failed to update user token: %w", err)
+	if err := s.userRepo.CreateUser(ctx, userID, login, hashedPassword, email, isAdmin); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Сохраняем токен в кеш
-	s.tokenCache.Set(cacheKey, token, s.tokenExpiry)
-
-	return token, nil
+	return s.userRepo.GetUserByID(ctx, userID)
 }
-func (s *AuthService) ValidateToken(tokenString string) (*model.User, error) {
-	// Проверяем кеш перед валидацией токена
-	cacheKey := "token_" + tokenString
-	if cachedUser, found := s.tokenCache.Get(cacheKey); found {
-		if user, ok := cachedUser.(*model.User); ok {
-			return user, nil
-		}
-	}
 
-	claims, err := s.parseJWTToken(tokenString)
-	if err != nil {
-		return nil, fmt.Errorf("invalid token: %w", err)
+// RevokeUserAccess отзывает все сессии и API-ключи пользователя
+func (s *AuthService) RevokeUserAccess(ctx context.Context, userID string) error {
+	if err := s.RevokeOtherSessions(ctx, userID, ""); err != nil {
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	keys, err := s.apiKeyRepo.ListUserAPIKeys(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return fmt.Errorf("failed to list api keys: %w", err)
 	}
-	if user == nil {
-		return nil, errors.New("user not found")
+	for _, key := range keys {
+		if err := s.apiKeyRepo.RevokeAPIKey(ctx, key.ID); err != nil {
+			return fmt.Errorf("failed to revoke api key: %w", err)
+		}
+		s.tokenCache.Delete("apikey_" + key.KeyHash)
 	}
 
-	if user.Token != tokenString {
-		return nil, errors.New("token mismatch")
+	return nil
+}
+
+// hashNewPassword проверяет логин и пароль нового пользователя и возвращает хеш пароля
+func (s *AuthService) hashNewPassword(login, password string) (string, error) {
+	if err := s.policy.ValidateLogin(login); err != nil {
+		return "", err
 	}
 
-	if time.Now().After(user.TokenExpiry) {
-		return nil, ErrTokenExpired
+	return s.hashPassword(password)
+}
+
+// hashPassword проверяет сложность пароля и возвращает его хеш
+func (s *AuthService) hashPassword(password string) (string, error) {
+	if err := s.policy.ValidatePassword(password); err != nil {
+		return "", err
 	}
 
-	// Сохраняем пользователя в кеш
-	s.tokenCache.Set(cacheKey, user, time.Until(user.TokenExpiry))
+	return s.hasher.Hash(password)
+}
+
+// unusablePassword хеш пользователей без локального пароля: с ним не совпадает ни один пароль
+const unusablePassword = "!"
 
-	return user, nil
+// checkPassword сравнивает пароль с сохраненным хешем
+func (s *AuthService) checkPassword(hashedPassword, password string) bool {
+	return s.hasher.Verify(hashedPassword, password)
 }
-func (s *AuthService) Logout(tokenString string) error {
-	claims, err := s.parseJWTToken(tokenString)
-	if err != nil {
-		return fmt.Errorf("invalid token: %w", err)
-	}
 
+// Authenticate проверяет логин и пароль и открывает новую сессию для устройства.
+// Если требуется второй фактор, вместо сессии возвращается MFAChallenge для CompleteMFA
+func (s *AuthService) Authenticate(login, password, device, ip string) (*model.TokenPair, *model.MFAChallenge, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Инвалидируем кеш
-	s.tokenCache.Delete("auth_" + claims.Login)
-	s.tokenCache.Delete("token_" + tokenString)
+	if s.guard != nil {
+		if err := s.guard.Attempt(ctx, login, ip); err != nil {
+			return nil, nil, err
+		}
+	}
 
-	return s.userRepo.UpdateUserToken(ctx, claims.UserID, "", time.Time{})
-}
+	user, err := s.authenticate(ctx, login, password)
+	if err != nil {
+		// Сбой источника (LDAP, база) не говорит о неверном пароле и не должен вести к блокировке
+		s.releaseAttempt(ctx, login, ip)
+		return nil, nil, err
+	}
 
-func (s *AuthService) generateJWTToken(userID, login string) (string, error) {
-	claims := &jwtClaims{
-		UserID: userID,
-		Login:  login,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.tokenExpiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "docs-server",
-		},
+	// Попытки для несуществующих логинов тоже учитываются, иначе по 429 можно определить существующие
+	if user == nil {
+		return nil, nil, ErrInvalidCredentials
+	}
+	if s.guard != nil {
+		if err := s.guard.Release(ctx, login, ip); err != nil {
+			return nil, nil, fmt.Errorf("failed to record login attempt: %w", err)
+		}
+	}
+	if user.Disabled() {
+		return nil, nil, ErrUserDisabled
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+	return s.startSession(ctx, user, device, ip)
 }
 
-func (s *AuthService) parseJWTToken(tokenString string) (*jwtClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwtClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return s.jwtSecret, nil
-	})
+// releaseAttempt возвращает попытку, зарезервированную Attempt, если проверка прервалась
+// не из-за неверных данных. Ошибка только логируется, чтобы не скрыть исходную
+func (s *AuthService) releaseAttempt(ctx context.Context, login, ip string) {
+	if s.guard == nil {
+		return
+	}
+	if err := s.guard.Release(ctx, login, ip); err != nil {
+		log.Printf("failed to release login attempt for %s: %v", login, err)
+	}
+}
 
+// startSession открывает сессию пользователя, прошедшего первый фактор,
+// или выдает MFAChallenge, если требуется второй
+func (s *AuthService) startSession(ctx context.Context, user *model.User, device, ip string) (*model.TokenPair, *model.MFAChallenge, error) {
+	// Счетчик неудач сбрасывается только после второго фактора, иначе
+	// знание пароля позволило бы подбирать код без ограничений
+	t, required, err := s.requiresMFA(ctx, user)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if required {
+		challenge, err := s.issueMFAChallenge(user, t, device)
+		return nil, challenge, err
 	}
 
-	if claims, ok := token.Claims.(*jwtClaims); ok && token.Valid {
-		return claims, nil
+	if s.guard != nil {
+		if err := s.guard.Succeed(ctx, user.Login); err != nil {
+			return nil, nil, fmt.Errorf("failed to reset login attempts: %w", err)
+		}
 	}
 
-	return nil, errors.New("invalid token")
+	tokens, err := s.createSession(ctx, user, device, ip)
+	return tokens, nil, err
 }
 
-func validateLogin(login string) error {
-	// Проверка минимальной длины
-	if len(login) < 8 {
-		return errors.New("login must be at least 8 characters long")
-	}
-
-	// Проверка на латиницу и цифры
-	matched, err := regexp.MatchString(`^[a-zA-Z0-9]+$`, login)
+// UnlockUser снимает блокировку входа, наложенную после неудачных попыток
+func (s *AuthService) UnlockUser(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to get user: %w", err)
 	}
-	if !matched {
-		return errors.New("login must contain only latin letters and digits")
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if s.guard == nil {
+		return nil
 	}
 
-	return nil
+	return s.guard.Unlock(ctx, user.Login)
 }
 
-func validatePassword(password string) error {
-	// Проверка минимальной длины
-	if len(password) < 8 {
-		return errors.New("password must be at least 8 characters long")
-	}
-
-	var (
-		hasUpper   bool
-		hasLower   bool
-		hasDigit   bool
-		hasSpecial bool
-	)
-
-	for _, char := range password {
-		switch {
-		case unicode.IsUpper(char):
-			hasUpper = true
-		case unicode.IsLower(char):
-			hasLower = true
-		case unicode.IsDigit(char):
-			hasDigit = true
-		case !unicode.IsLetter(char) && !unicode.IsDigit(char):
-			hasSpecial = true
+// ValidateToken проверяет access-токен и возвращает пользователя и его сессию
+func (s *AuthService) ValidateToken(tokenString string) (*model.User, *model.Session, error) {
+	claims, err := s.parseJWTToken(tokenString)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, nil, ErrTokenExpired
 		}
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
 	}
 
-	// Проверка требований
-	if !hasUpper || !hasLower {
-		return errors.New("password must contain at least 2 letters in different cases (upper and lower)")
+	// Токены без сессии (например, токен второго шага входа) не дают доступа к API
+	if claims.SessionID == "" {
+		return nil, nil, ErrInvalidToken
 	}
 
-	if !hasDigit {
-		return errors.New("password must contain at least 1 digit")
+	// Без обращения к БД: подписанных утверждений достаточно, если токен не отозван
+	if s.denylist != nil {
+		if claims.ID == "" {
+			return nil, nil, ErrInvalidToken
+		}
+		if s.denylist.Revoked(claims.ID) {
+			return nil, nil, ErrSessionRevoked
+		}
+		return claims.user(), claims.session(), nil
 	}
 
-	if !hasSpecial {
-		return errors.New("password must contain at least 1 special character")
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	return nil
-}
-func generateSecureKey(length int) []byte {
-	key := make([]byte, length)
-	_, err := rand.Read(key)
+	user, session, err := s.getSession(ctx, claims.SessionID)
 	if err != nil {
-		panic(fmt.Sprintf("failed to generate secure key: %v", err))
+		return nil, nil, err
 	}
-	return key
-}
 
-func GenerateToken(userID, login string) (string, error) {
-	// Создаем claims с данными пользователя
-	claims := &Claims{
-		UserID: userID,
-		Login:  login,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Токен действителен 24 часа
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "docs-server",
-		},
+	// После обновления пары токенов прежний access-токен недействителен
+	if session.AccessTokenHash != hashToken(tokenString) || session.UserID != claims.UserID {
+		return nil, nil, ErrInvalidToken
+	}
+	if s.tokens.Expired(session.AccessExpiry) {
+		return nil, nil, ErrTokenExpired
 	}
 
-	// Создаем токен с методом подписи HS256
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return user, session, nil
+}
 
-	// Подписываем токен секретным ключом
-	signedToken, err := token.SignedString(jwtSecret)
-	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
-	}
+// JWKS открытые ключи, которыми другие сервисы могут проверять access-токены
+func (s *AuthService) JWKS() jose.JWKSet {
+	return s.tokens.JWKS()
+}
+
+// Logout закрывает текущую сессию пользователя
+func (s *AuthService) Logout(session *model.Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	return signedToken, nil
+	return s.revokeSession(ctx, session.ID)
 }
 
-func ParseToken(tokenString string) (*Claims, error) {
-	// Парсим токен с нашими claims
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Проверяем метод подписи
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
+// generateJWTToken выпускает access-токен сессии с идентификатором tokenID и возвращает его срок действия
+func (s *AuthService) generateJWTToken(user *model.User, sessionID, tokenID string) (string, time.Time, error) {
+	return s.tokens.Issue(&jwtClaims{
+		UserID:           user.ID,
+		Login:            user.Login,
+		SessionID:        sessionID,
+		IsAdmin:          user.IsAdmin,
+		RegisteredClaims: jwt.RegisteredClaims{ID: tokenID},
+	}, "", 0)
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
+// user пользователь по утверждениям токена (без почты и даты создания)
+func (c *jwtClaims) user() *model.User {
+	return &model.User{
+		ID:      c.UserID,
+		Login:   c.Login,
+		IsAdmin: c.IsAdmin,
 	}
+}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+// session сессия по утверждениям токена
+func (c *jwtClaims) session() *model.Session {
+	session := &model.Session{
+		ID:            c.SessionID,
+		UserID:        c.UserID,
+		AccessTokenID: c.ID,
 	}
-
-	return nil, fmt.Errorf("invalid token")
+	if c.ExpiresAt != nil {
+		session.AccessExpiry = c.ExpiresAt.Time
+	}
+	return session
 }
 
-// Вспомогательная функция для генерации случайного секрета (можно использовать для инициализации)
-func GenerateRandomSecret() string {
-	return base64.StdEncoding.EncodeToString(generateSecureKey(32))
+func (s *AuthService) parseJWTToken(tokenString string) (*jwtClaims, error) {
+	claims := &jwtClaims{}
+	if err := s.tokens.Verify(tokenString, claims, ""); err != nil {
+		return nil, err
+	}
+	return claims, nil
 }
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"docs-server/internal/model"
+	"docs-server/internal/password"
+	"docs-server/internal/repository"
+)
+
+// Authenticator источник проверки логина и пароля.
+// Authenticate возвращает nil без ошибки, если логин неизвестен источнику или пароль неверен,
+// ошибка означает сбой самого источника
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, login, password string) (*model.User, error)
+}
+
+// PasswordAuthenticator проверяет пароль по хешу из таблицы users.
+// Хеш с устаревшим алгоритмом или параметрами пересчитывается после успешного входа
+type PasswordAuthenticator struct {
+	userRepo *repository.UserRepository
+	hasher   password.Hasher
+}
+
+func NewPasswordAuthenticator(userRepo *repository.UserRepository, hasher password.Hasher) *PasswordAuthenticator {
+	return &PasswordAuthenticator{userRepo: userRepo, hasher: hasher}
+}
+
+func (a *PasswordAuthenticator) Name() string {
+	return "local"
+}
+
+func (a *PasswordAuthenticator) Authenticate(ctx context.Context, login, password string) (*model.User, error) {
+	user, err := a.userRepo.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !a.hasher.Verify(user.Password, password) {
+		return nil, nil
+	}
+
+	if a.hasher.NeedsRehash(user.Password) {
+		a.rehash(ctx, user, password)
+	}
+	return user, nil
+}
+
+// rehash сохраняет хеш пароля с текущими параметрами. Ошибка не мешает входу:
+// пересчет повторится при следующем входе
+func (a *PasswordAuthenticator) rehash(ctx context.Context, user *model.User, plain string) {
+	hashed, err := a.hasher.Hash(plain)
+	if err != nil {
+		log.Printf("failed to rehash password of user %s: %v", user.ID, err)
+		return
+	}
+	if err := a.userRepo.ReplacePasswordHash(ctx, user.ID, user.Password, hashed); err != nil {
+		log.Printf("failed to rehash password of user %s: %v", user.ID, err)
+		return
+	}
+	user.Password = hashed
+}
+
+// authenticate проверяет учетные данные во всех источниках по порядку
+func (s *AuthService) authenticate(ctx context.Context, login, password string) (*model.User, error) {
+	for _, authenticator := range s.authenticators {
+		user, err := authenticator.Authenticate(ctx, login, password)
+		if err != nil {
+			return nil, fmt.Errorf("%s authentication failed: %w", authenticator.Name(), err)
+		}
+		if user != nil {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
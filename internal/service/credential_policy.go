@@ -0,0 +1,223 @@
+package service
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ValidationError логин или пароль не соответствует политике
+type ValidationError struct {
+	Field   string // login или password
+	Rule    string // Нарушенное правило: min_length, max_length, charset, uppercase, lowercase, digit, special, breached
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalidLogin(rule, format string, args ...interface{}) error {
+	return &ValidationError{Field: "login", Rule: rule, Message: fmt.Sprintf(format, args...)}
+}
+
+func invalidPassword(rule, format string, args ...interface{}) error {
+	return &ValidationError{Field: "password", Rule: rule, Message: fmt.Sprintf(format, args...)}
+}
+
+// CredentialPolicy требования к логинам и паролям. Длина считается в символах
+type CredentialPolicy struct {
+	LoginMinLength int
+	LoginMaxLength int
+	LoginCharset   string // ascii - латинские буквы и цифры, unicode - буквы и цифры любого алфавита
+	LoginSymbols   string // Дополнительно разрешенные в логине символы, например "._-"
+	EmailLogins    bool   // Логином может быть адрес электронной почты
+
+	PasswordMinLength int
+	PasswordMaxLength int // 0 - без ограничения
+	RequireUpper      bool
+	RequireLower      bool
+	RequireDigit      bool
+	RequireSpecial    bool
+
+	// Скомпрометированные пароли: SHA-1 пароля и его записи в нижнем регистре
+	breached map[[sha1.Size]byte]struct{}
+}
+
+// DefaultCredentialPolicy логин из 8-50 латинских букв и цифр, пароль от 8 символов
+// с буквами в обоих регистрах, цифрой и спецсимволом
+func DefaultCredentialPolicy() *CredentialPolicy {
+	return &CredentialPolicy{
+		LoginMinLength:    8,
+		LoginMaxLength:    50,
+		LoginCharset:      "ascii",
+		PasswordMinLength: 8,
+		RequireUpper:      true,
+		RequireLower:      true,
+		RequireDigit:      true,
+		RequireSpecial:    true,
+	}
+}
+
+// LoadBlocklist загружает список скомпрометированных паролей: по одному на строку,
+// пароль в открытом виде или SHA-1 в hex (формат Pwned Passwords "HASH:COUNT" допускается)
+func (p *CredentialPolicy) LoadBlocklist(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open password blocklist: %w", err)
+	}
+	defer file.Close()
+
+	breached := make(map[[sha1.Size]byte]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if digest, ok := parseSHA1(line); ok {
+			breached[digest] = struct{}{}
+			continue
+		}
+		breached[sha1.Sum([]byte(strings.ToLower(line)))] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read password blocklist: %w", err)
+	}
+
+	p.breached = breached
+	return nil
+}
+
+// BlocklistSize количество записей в списке скомпрометированных паролей
+func (p *CredentialPolicy) BlocklistSize() int {
+	return len(p.breached)
+}
+
+// ValidateLogin проверяет логин
+func (p *CredentialPolicy) ValidateLogin(login string) error {
+	length := utf8.RuneCountInString(login)
+	if length < p.LoginMinLength {
+		return invalidLogin("min_length", "login must be at least %d characters long", p.LoginMinLength)
+	}
+	if p.LoginMaxLength > 0 && length > p.LoginMaxLength {
+		return invalidLogin("max_length", "login must be at most %d characters long", p.LoginMaxLength)
+	}
+
+	if p.EmailLogins && strings.Contains(login, "@") {
+		if validateEmail(login) != nil {
+			return invalidLogin("charset", "login is not a valid email address")
+		}
+		return nil
+	}
+
+	for _, char := range login {
+		if !p.loginChar(char) {
+			return invalidLogin("charset", "login must contain only %s", p.loginCharsetDescription())
+		}
+	}
+	return nil
+}
+
+func (p *CredentialPolicy) loginChar(char rune) bool {
+	if strings.ContainsRune(p.LoginSymbols, char) {
+		return true
+	}
+	if p.LoginCharset == "unicode" {
+		return unicode.IsLetter(char) || unicode.IsDigit(char)
+	}
+	return char < utf8.RuneSelf && (unicode.IsLetter(char) || unicode.IsDigit(char))
+}
+
+func (p *CredentialPolicy) loginCharsetDescription() string {
+	description := "latin letters and digits"
+	if p.LoginCharset == "unicode" {
+		description = "letters and digits"
+	}
+	if p.LoginSymbols != "" {
+		description += fmt.Sprintf(" and %q", p.LoginSymbols)
+	}
+	return description
+}
+
+// ValidatePassword проверяет длину, классы символов и отсутствие пароля в списке скомпрометированных
+func (p *CredentialPolicy) ValidatePassword(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.PasswordMinLength {
+		return invalidPassword("min_length", "password must be at least %d characters long", p.PasswordMinLength)
+	}
+	if p.PasswordMaxLength > 0 && length > p.PasswordMaxLength {
+		return invalidPassword("max_length", "password must be at most %d characters long", p.PasswordMaxLength)
+	}
+
+	var (
+		hasUpper   bool
+		hasLower   bool
+		hasDigit   bool
+		hasSpecial bool
+	)
+
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case !unicode.IsLetter(char) && !unicode.IsDigit(char):
+			hasSpecial = true
+		}
+	}
+
+	// Проверка требований
+	if p.RequireUpper && !hasUpper {
+		return invalidPassword("uppercase", "password must contain at least 1 uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		return invalidPassword("lowercase", "password must contain at least 1 lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		return invalidPassword("digit", "password must contain at least 1 digit")
+	}
+	if p.RequireSpecial && !hasSpecial {
+		return invalidPassword("special", "password must contain at least 1 special character")
+	}
+
+	if p.isBreached(password) {
+		return invalidPassword("breached", "password is known to be compromised, choose another one")
+	}
+
+	return nil
+}
+
+func (p *CredentialPolicy) isBreached(password string) bool {
+	if len(p.breached) == 0 {
+		return false
+	}
+	if _, ok := p.breached[sha1.Sum([]byte(password))]; ok {
+		return true
+	}
+	_, ok := p.breached[sha1.Sum([]byte(strings.ToLower(password)))]
+	return ok
+}
+
+// parseSHA1 разбирает строку вида "HASH" или "HASH:COUNT"
+func parseSHA1(line string) ([sha1.Size]byte, bool) {
+	var digest [sha1.Size]byte
+
+	hash, _, _ := strings.Cut(line, ":")
+	if len(hash) != hex.EncodedLen(sha1.Size) {
+		return digest, false
+	}
+	if _, err := hex.Decode(digest[:], []byte(hash)); err != nil {
+		return digest, false
+	}
+	return digest, true
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// RevocationStore общее хранилище отозванных токенов для нескольких экземпляров сервиса
+type RevocationStore interface {
+	AddRevokedToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	ListRevokedTokens(ctx context.Context) (map[string]time.Time, error)
+}
+
+// TokenDenylist список отозванных access-токенов (jti) для режима без обращения к БД при проверке токена.
+// Запись хранится до истечения срока токена, поэтому список остается небольшим
+type TokenDenylist struct {
+	store    RevocationStore // nil - список только в памяти этого экземпляра
+	interval time.Duration   // Периодичность синхронизации с хранилищем
+
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+func NewTokenDenylist(store RevocationStore, interval time.Duration) *TokenDenylist {
+	return &TokenDenylist{
+		store:    store,
+		interval: interval,
+		entries:  make(map[string]time.Time),
+	}
+}
+
+// Revoke отзывает токен до истечения его срока действия
+func (d *TokenDenylist) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" || !expiresAt.After(time.Now()) {
+		return nil
+	}
+
+	d.mu.Lock()
+	if current, ok := d.entries[tokenID]; !ok || expiresAt.After(current) {
+		d.entries[tokenID] = expiresAt
+	}
+	d.mu.Unlock()
+
+	if d.store == nil {
+		return nil
+	}
+	return d.store.AddRevokedToken(ctx, tokenID, expiresAt)
+}
+
+// Revoked сообщает, отозван ли токен. Проверка выполняется только в памяти
+func (d *TokenDenylist) Revoked(tokenID string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	_, ok := d.entries[tokenID]
+	return ok
+}
+
+// Len количество записей в списке
+func (d *TokenDenylist) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return len(d.entries)
+}
+
+// Sync загружает записи, добавленные другими экземплярами, и удаляет истекшие
+func (d *TokenDenylist) Sync(ctx context.Context) error {
+	var stored map[string]time.Time
+	if d.store != nil {
+		var err error
+		if stored, err = d.store.ListRevokedTokens(ctx); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for tokenID, expiresAt := range stored {
+		if current, ok := d.entries[tokenID]; !ok || expiresAt.After(current) {
+			d.entries[tokenID] = expiresAt
+		}
+	}
+	for tokenID, expiresAt := range d.entries {
+		if !expiresAt.After(now) {
+			delete(d.entries, tokenID)
+		}
+	}
+
+	return nil
+}
+
+// Run синхронизирует список с хранилищем до отмены контекста
+func (d *TokenDenylist) Run(ctx context.Context) {
+	if err := d.Sync(ctx); err != nil {
+		log.Printf("token denylist: %v", err)
+	}
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Sync(ctx); err != nil {
+				log.Printf("token denylist: %v", err)
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"docs-server/internal/cache"
 	"docs-server/internal/model"
 	"docs-server/internal/repository"
@@ -29,13 +30,27 @@ var (
 	ErrDocumentNotFound     = errors.New("document not found")
 	ErrPermissionDenied     = errors.New("permission denied")
 	ErrInvalidDocumentData  = errors.New("invalid document data")
+	ErrFileTooLarge         = errors.New("file exceeds storage quota")
+	ErrQuotaExceeded        = errors.New("storage quota exceeded")
+	ErrInvalidExpiry        = errors.New("expires_at must be in the future")
+	ErrLegalHold            = errors.New("document is under legal hold")
+	ErrLegalHoldAdminOnly   = errors.New("forbidden: legal hold can be changed only by administrators")
 )
 
+// Quota ограничения хранилища пользователя (0 - без ограничений)
+type Quota struct {
+	MaxBytes     int64
+	MaxDocuments int64
+}
+
 type DocumentService struct {
 	docRepo   *repository.DocumentRepository
 	userRepo  *repository.UserRepository
 	cache     *cache.MemoryCache
 	uploadDir string
+	quota     Quota           // Квота по умолчанию
+	retention RetentionPolicy // Сроки хранения по умолчанию
+	outbox    *Outbox         // Публикация событий документов, nil - события не публикуются
 }
 
 func NewDocumentService(
@@ -43,41 +58,30 @@ func NewDocumentService(
 	userRepo *repository.UserRepository,
 	cache *cache.MemoryCache,
 	uploadDir string,
+	quota Quota,
+	retention RetentionPolicy,
+	outbox *Outbox,
 ) *DocumentService {
 	return &DocumentService{
 		docRepo:   docRepo,
 		userRepo:  userRepo,
 		cache:     cache,
 		uploadDir: uploadDir,
+		quota:     quota,
+		retention: retention,
+		outbox:    outbox,
 	}
 }
 
-// getUserFromToken - внутренний метод для получения пользователя по токену
-func (s *DocumentService) getUserFromToken(token string) (*model.User, error) {
-	user, err := s.userRepo.GetUserByToken(context.Background(), token)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user from token: %w", err)
-	}
-	if user == nil {
-		return nil, ErrInvalidToken
-	}
-	return user, nil
-}
-
-func (s *DocumentService) UploadDocument(token string, meta string, files []*model.UploadedFile) (*model.Document, error) {
-	// Получаем пользователя из токена
-	user, err := s.getUserFromToken(token)
-	if err != nil {
-		return nil, err
-	}
-
+func (s *DocumentService) UploadDocument(user *model.User, meta string, files []*model.UploadedFile) (*model.Document, error) {
 	// Парсинг метаданных
 	var metaData struct {
-		Name   string      `json:"name"`
-		Public bool        `json:"public"`
-		Mime   string      `json:"mime"`
-		Grant  []string    `json:"grant"`
-		JSON   interface{} `json:"json"`
+		Name      string      `json:"name"`
+		Public    bool        `json:"public"`
+		Mime      string      `json:"mime"`
+		Grant     []string    `json:"grant"`
+		JSON      interface{} `json:"json"`
+		ExpiresAt *time.Time  `json:"expires_at"`
 	}
 
 	if err := json.Unmarshal([]byte(meta), &metaData); err != nil {
@@ -88,6 +92,18 @@ func (s *DocumentService) UploadDocument(token string, meta string, files []*mod
 	if metaData.Name == "" {
 		return nil, ErrDocumentNameRequired
 	}
+	if metaData.ExpiresAt != nil && !metaData.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	// Проверка квоты до записи файла
+	var size int64
+	if len(files) > 0 {
+		size = int64(len(files[0].Data))
+	}
+	if err := s.checkQuota(context.Background(), user.ID, size); err != nil {
+		return nil, err
+	}
 
 	// Обработка файла
 	var filePath string
@@ -125,38 +141,142 @@ func (s *DocumentService) UploadDocument(token string, meta string, files []*mod
 	}
 
 	doc := &model.Document{
-		ID:       docID,
-		Name:     metaData.Name,
-		Mime:     metaData.Mime,
-		File:     len(files) > 0,
-		Public:   metaData.Public,
-		Created:  time.Now(),
-		Owner:    user.ID,
-		FilePath: filePath,
-		JSONData: metaData.JSON,
-		Grant:    metaData.Grant,
-	}
-
-	// Сохранение в БД
-	if err := s.docRepo.CreateDocument(context.Background(), doc); err != nil {
+		ID:        docID,
+		Name:      metaData.Name,
+		Mime:      metaData.Mime,
+		File:      len(files) > 0,
+		Public:    metaData.Public,
+		Created:   time.Now(),
+		Size:      size,
+		Owner:     user.ID,
+		FilePath:  filePath,
+		JSONData:  metaData.JSON,
+		Grant:     metaData.Grant,
+		ExpiresAt: metaData.ExpiresAt,
+	}
+
+	// Срок хранения по политике, если не задан явно
+	if doc.ExpiresAt == nil {
+		if ttl := s.retention.TTL(user.Login, doc.Mime); ttl > 0 {
+			expiresAt := doc.Created.Add(ttl)
+			doc.ExpiresAt = &expiresAt
+		}
+	}
+
+	// События записываются в одной транзакции с документом
+	outbox, err := s.eventMessages(model.EventDocumentCreated, doc, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Grant) > 0 {
+		granted, err := s.eventMessages(model.EventGrantChanged, doc, map[string]interface{}{"granted": doc.Grant})
+		if err != nil {
+			return nil, err
+		}
+		outbox = append(outbox, granted...)
+	}
+
+	// Сохранение в БД с повторной проверкой квоты: параллельные загрузки могли ее исчерпать
+	checkQuota := func(bytes, count int64, quotaBytes, quotaDocs sql.NullInt64) error {
+		return s.quotaError(s.newUsage(bytes, count, quotaBytes, quotaDocs), size)
+	}
+	if err := s.docRepo.CreateDocument(context.Background(), doc, checkQuota, outbox...); err != nil {
 		if filePath != "" {
 			os.Remove(filePath)
 		}
-		return nil, fmt.Errorf("failed to create document: %v", err)
+		return nil, fmt.Errorf("failed to create document: %w", err)
 	}
 
 	// Инвалидация кеша
 	s.cache.Delete("docs_" + user.ID)
+	s.cache.Delete("usage_" + user.ID)
+
+	s.outbox.Notify()
 
 	return doc, nil
 }
 
-func (s *DocumentService) GetDocumentsList(token, login, key, value string, limit int) ([]*model.Document, error) {
-	user, err := s.getUserFromToken(token)
+// GetUsage возвращает текущее использование хранилища пользователем
+func (s *DocumentService) GetUsage(user *model.User) (*model.Usage, error) {
+	return s.getUsage(context.Background(), user.ID)
+}
+
+// getUsage считает использование и действующие квоты пользователя
+func (s *DocumentService) getUsage(ctx context.Context, userID string) (*model.Usage, error) {
+	cacheKey := "usage_" + userID
+	if cached, found := s.cache.Get(cacheKey); found {
+		return cached.(*model.Usage), nil
+	}
+
+	bytes, count, err := s.docRepo.GetUserUsage(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	quotaBytes, quotaDocs, err := s.userRepo.GetUserQuota(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := s.newUsage(bytes, count, quotaBytes, quotaDocs)
+	s.cache.Set(cacheKey, usage, time.Minute)
+
+	return usage, nil
+}
+
+// InvalidateUsage сбрасывает кешированное использование хранилища пользователя,
+// например после изменения его квот
+func (s *DocumentService) InvalidateUsage(userID string) {
+	s.cache.Delete("usage_" + userID)
+}
+
+// newUsage формирует использование хранилища с действующими квотами: персональными, если заданы, иначе по умолчанию
+func (s *DocumentService) newUsage(bytes, count int64, quotaBytes, quotaDocs sql.NullInt64) *model.Usage {
+	usage := &model.Usage{
+		Bytes:          bytes,
+		Documents:      count,
+		QuotaBytes:     s.quota.MaxBytes,
+		QuotaDocuments: s.quota.MaxDocuments,
+	}
+	if quotaBytes.Valid {
+		usage.QuotaBytes = quotaBytes.Int64
+	}
+	if quotaDocs.Valid {
+		usage.QuotaDocuments = quotaDocs.Int64
+	}
+	return usage
+}
+
+// checkQuota проверяет, поместится ли новый документ размером size в квоту пользователя
+func (s *DocumentService) checkQuota(ctx context.Context, userID string, size int64) error {
+	// Кешированное значение может отставать, поэтому считаем заново
+	s.cache.Delete("usage_" + userID)
+
+	usage, err := s.getUsage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.quotaError(usage, size)
+}
+
+// quotaError возвращает ошибку, если документ размером size не помещается в квоту usage
+func (s *DocumentService) quotaError(usage *model.Usage, size int64) error {
+	if usage.QuotaBytes > 0 {
+		if size > usage.QuotaBytes {
+			return ErrFileTooLarge
+		}
+		if usage.Bytes+size > usage.QuotaBytes {
+			return fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, usage.Bytes, usage.QuotaBytes)
+		}
+	}
+	if usage.QuotaDocuments > 0 && usage.Documents+1 > usage.QuotaDocuments {
+		return fmt.Errorf("%w: %d of %d documents used", ErrQuotaExceeded, usage.Documents, usage.QuotaDocuments)
+	}
+
+	return nil
+}
+
+func (s *DocumentService) GetDocumentsList(user *model.User, login, key, value string, limit int) ([]*model.Document, error) {
 	// Проверка кеша
 	cacheKey := "docs_" + user.ID
 	if cached, found := s.cache.Get(cacheKey); found {
@@ -164,6 +284,7 @@ func (s *DocumentService) GetDocumentsList(token, login, key, value string, limi
 	}
 
 	var docs []*model.Document
+	var err error
 	if login == "" || login == user.Login {
 		// Собственные документы
 		docs, err = s.docRepo.GetUserDocuments(context.Background(), user.ID, limit)
@@ -187,24 +308,26 @@ func (s *DocumentService) GetDocumentsList(token, login, key, value string, limi
 	return docs, nil
 }
 
-func (s *DocumentService) GetDocument(token, id string) (*model.Document, error) {
-	user, err := s.getUserFromToken(token)
-	if err != nil {
-		return nil, err
-	}
-
+func (s *DocumentService) GetDocument(user *model.User, id string) (*model.Document, error) {
 	// Проверка кеша
 	cacheKey := "doc_" + id
-	if cached, found := s.cache.Get(cacheKey); found {
-		return cached.(*model.Document), nil
+	var doc *model.Document
+	cached, found := s.cache.Get(cacheKey)
+	if found {
+		doc = cached.(*model.Document)
+	} else {
+		// Получение документа
+		var err error
+		doc, err = s.docRepo.GetDocumentByID(context.Background(), id)
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	// Получение документа
-	doc, err := s.docRepo.GetDocumentByID(context.Background(), id)
-	if err != nil {
-		return nil, err
+	// Документы в корзине и с истекшим сроком хранения недоступны
+	if doc == nil || doc.DeletedAt != nil || doc.Expired(time.Now()) {
+		return nil, ErrDocumentNotFound
 	}
-	// Проверка прав доступа
+	// Проверка прав доступа, в том числе для документа из кеша
 	hasAccess := doc.Owner == user.ID || doc.Public
 	if !hasAccess {
 		for _, grant := range doc.Grant {
@@ -220,35 +343,35 @@ func (s *DocumentService) GetDocument(token, id string) (*model.Document, error)
 	}
 
 	// Сохранение в кеш
-	s.cache.Set(cacheKey, doc, 10*time.Minute)
+	if !found {
+		s.cache.Set(cacheKey, doc, 10*time.Minute)
+	}
 
 	return doc, nil
 }
 
-func (s *DocumentService) DeleteDocument(token, id string) (bool, error) {
-	user, err := s.getUserFromToken(token)
-	if err != nil {
-		return false, err
-	}
-
+// DeleteDocument перемещает документ в корзину
+func (s *DocumentService) DeleteDocument(user *model.User, id string) (bool, error) {
 	// Проверка владельца документа
 	doc, err := s.docRepo.GetDocumentByID(context.Background(), id)
 	if err != nil {
 		return false, err
 	}
+	if doc == nil || doc.DeletedAt != nil {
+		return false, ErrDocumentNotFound
+	}
 	if doc.Owner != user.ID {
 		return false, ErrNotDocumentOwner
 	}
-
-	// Удаление файла
-	if doc.File && doc.FilePath != "" {
-		if err := os.Remove(doc.FilePath); err != nil {
-			return false, fmt.Errorf("%w: %v", ErrFailedToDeleteFile, err)
-		}
+	if doc.LegalHold {
+		return false, ErrLegalHold
 	}
 
-	// Удаление из БД
-	if err := s.docRepo.DeleteDocument(context.Background(), id); err != nil {
+	outbox, err := s.eventMessages(model.EventDocumentDeleted, doc, map[string]interface{}{"trashed": true})
+	if err != nil {
+		return false, err
+	}
+	if err := s.docRepo.TrashDocument(context.Background(), id, time.Now(), outbox...); err != nil {
 		return false, fmt.Errorf("failed to delete document: %w", err)
 	}
 
@@ -256,6 +379,8 @@ func (s *DocumentService) DeleteDocument(token, id string) (bool, error) {
 	s.cache.Delete("doc_" + id)
 	s.cache.Delete("docs_" + user.ID)
 
+	s.outbox.Notify()
+
 	return true, nil
 }
 
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"docs-server/internal/model"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// eventStreamPage количество событий журнала, читаемых за один раз
+	eventStreamPage = 100
+	// eventGapWait сколько ждать событие с пропущенным номером: при общем журнале номер
+	// выдается при вставке, и запись другого экземпляра может стать видна позже следующей
+	eventGapWait = 2 * time.Second
+)
+
+// ErrEventsLost события после позиции читателя уже удалены из журнала
+var ErrEventsLost = errors.New("events after cursor are no longer retained")
+
+// EventLogStore общий журнал событий документов для нескольких экземпляров сервиса
+type EventLogStore interface {
+	AppendEvent(ctx context.Context, event *model.DocumentEvent) (int64, error)
+	ListEventsAfter(ctx context.Context, seq int64, limit int) ([]*model.DocumentEvent, error)
+	EventLogBounds(ctx context.Context) (first, last int64, err error)
+	TrimEvents(ctx context.Context, keep int) error
+}
+
+// EventStream хранит последние capacity событий документов и будит подписчиков потока
+// при появлении новых. Номер события в журнале служит идентификатором для Last-Event-ID
+type EventStream struct {
+	store    EventLogStore // nil - журнал только в памяти этого экземпляра
+	capacity int
+	interval time.Duration // Периодичность проверки событий других экземпляров
+
+	mu      sync.Mutex
+	events  []*model.DocumentEvent
+	nextSeq int64
+	waiters map[chan struct{}]struct{}
+}
+
+func NewEventStream(store EventLogStore, capacity int, interval time.Duration) *EventStream {
+	return &EventStream{
+		store:    store,
+		capacity: capacity,
+		interval: interval,
+		// Номера журнала в памяти продолжают расти после перезапуска,
+		// поэтому Last-Event-ID прежнего процесса не совпадет с новыми событиями
+		nextSeq: time.Now().UnixMicro(),
+		waiters: make(map[chan struct{}]struct{}),
+	}
+}
+
+// Publish добавляет событие в журнал и будит подписчиков. Используется как приемник outbox
+func (s *EventStream) Publish(ctx context.Context, event *model.DocumentEvent) error {
+	if s.store != nil {
+		if _, err := s.store.AppendEvent(ctx, event); err != nil {
+			return err
+		}
+	} else {
+		s.mu.Lock()
+		stored := *event
+		stored.Seq = s.nextSeq
+		stored.AppendedAt = time.Now()
+		s.nextSeq++
+		s.events = append(s.events, &stored)
+		if len(s.events) > s.capacity {
+			s.events = append(s.events[:0:0], s.events[len(s.events)-s.capacity:]...)
+		}
+		s.mu.Unlock()
+	}
+
+	s.notify()
+	return nil
+}
+
+// Subscribe возвращает канал, в который приходит сигнал о новых событиях, и функцию отписки
+func (s *EventStream) Subscribe() (<-chan struct{}, func()) {
+	wake := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	s.waiters[wake] = struct{}{}
+	s.mu.Unlock()
+
+	return wake, func() {
+		s.mu.Lock()
+		delete(s.waiters, wake)
+		s.mu.Unlock()
+	}
+}
+
+// Resume возвращает позицию, с которой читать журнал. Без lastID поток начинается с новых событий.
+// reset сообщает, что событий после lastID в журнале уже нет и клиенту нужно перечитать данные заново
+func (s *EventStream) Resume(ctx context.Context, lastID int64, resume bool) (cursor int64, reset bool, err error) {
+	first, last, err := s.bounds(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !resume {
+		return last, false, nil
+	}
+	if lastID > last || lastID < first-1 {
+		return last, true, nil
+	}
+	return lastID, false, nil
+}
+
+// Read возвращает события журнала после cursor, видимые пользователю userID, и новую позицию.
+// Позиция сдвигается и на невидимые события, поэтому повторное чтение их не вернет.
+// Если читатель отстал от журнала, возвращается ErrEventsLost и позиция последнего события
+func (s *EventStream) Read(ctx context.Context, userID string, cursor int64) ([]*model.DocumentEvent, int64, error) {
+	events, err := s.eventsAfter(ctx, cursor)
+	if err != nil {
+		return nil, cursor, err
+	}
+	if len(events) > 0 && events[0].Seq != cursor+1 {
+		first, last, err := s.bounds(ctx)
+		if err != nil {
+			return nil, cursor, err
+		}
+		if cursor < first-1 {
+			return nil, last, ErrEventsLost
+		}
+	}
+
+	var visible []*model.DocumentEvent
+	for _, event := range events {
+		// Пропуск номера ждет запаздывающую запись, но не дольше eventGapWait после записи
+		// следующего события в журнал. Время изменения документа не подходит: через outbox
+		// событие попадает в журнал с задержкой, и пропуск не ждал бы вовсе
+		if event.Seq != cursor+1 && time.Since(event.AppendedAt) < eventGapWait {
+			break
+		}
+		cursor = event.Seq
+		if event.VisibleTo(userID) {
+			visible = append(visible, event)
+		}
+	}
+	return visible, cursor, nil
+}
+
+// Run будит подписчиков, чтобы они прочитали события других экземпляров, и сокращает общий журнал
+func (s *EventStream) Run(ctx context.Context) {
+	if s.store == nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.store.TrimEvents(ctx, s.capacity); err != nil {
+				log.Printf("event stream: %v", err)
+			}
+			s.notify()
+		}
+	}
+}
+
+func (s *EventStream) notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for wake := range s.waiters {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// bounds возвращает номера первого и последнего событий журнала
+func (s *EventStream) bounds(ctx context.Context) (int64, int64, error) {
+	if s.store != nil {
+		return s.store.EventLogBounds(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.events) == 0 {
+		return s.nextSeq, s.nextSeq - 1, nil
+	}
+	return s.events[0].Seq, s.nextSeq - 1, nil
+}
+
+func (s *EventStream) eventsAfter(ctx context.Context, seq int64) ([]*model.DocumentEvent, error) {
+	if s.store != nil {
+		return s.store.ListEventsAfter(ctx, seq, eventStreamPage)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := sort.Search(len(s.events), func(i int) bool { return s.events[i].Seq > seq })
+	end := min(start+eventStreamPage, len(s.events))
+	return append([]*model.DocumentEvent(nil), s.events[start:end]...), nil
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"docs-server/internal/model"
+	"time"
+)
+
+// eventMessages возвращает сообщения outbox о событии документа doc для записи
+// в транзакции его изменения. Без outbox события не записываются
+func (s *DocumentService) eventMessages(eventType string, doc *model.Document, changes map[string]interface{}) ([]*model.OutboxMessage, error) {
+	if s.outbox == nil {
+		return nil, nil
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return nil, err
+	}
+
+	// Копия, чтобы событие не менялось вместе с документом в кеше
+	snapshot := *doc
+	return s.outbox.Messages(&model.DocumentEvent{
+		ID:         id,
+		Type:       eventType,
+		Time:       time.Now().UTC(),
+		DocumentID: doc.ID,
+		Owner:      doc.Owner,
+		Document:   &snapshot,
+		Changes:    changes,
+	})
+}
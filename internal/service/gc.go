@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"docs-server/internal/model"
+)
+
+// CollectGarbage сверяет каталог загрузок с таблицей documents.
+// Файлы моложе grace не считаются потерянными: их загрузка может быть еще не завершена.
+// При fix = false только формирует отчет. Записи под удержанием не удаляются и при fix = true.
+func (s *DocumentService) CollectGarbage(ctx context.Context, grace time.Duration, fix bool) (*model.GCReport, error) {
+	docs, err := s.docRepo.GetFileDocuments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list file documents: %w", err)
+	}
+
+	report := &model.GCReport{}
+
+	// Записи, ссылающиеся на отсутствующие файлы
+	referenced := make(map[string]struct{}, len(docs))
+	var dangling []*model.Document
+	for _, doc := range docs {
+		if doc.FilePath == "" {
+			dangling = append(dangling, doc)
+			continue
+		}
+		referenced[absPath(doc.FilePath)] = struct{}{}
+
+		if _, err := os.Stat(doc.FilePath); os.IsNotExist(err) {
+			dangling = append(dangling, doc)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", doc.FilePath, err)
+		}
+	}
+	for _, doc := range dangling {
+		report.DanglingDocuments = append(report.DanglingDocuments, doc.ID)
+	}
+
+	// Файлы без записи в БД
+	entries, err := os.ReadDir(s.uploadDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read upload directory: %w", err)
+	}
+	threshold := time.Now().Add(-grace)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(s.uploadDir, entry.Name())
+		if _, ok := referenced[absPath(path)]; ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(threshold) {
+			continue
+		}
+		report.OrphanFiles = append(report.OrphanFiles, path)
+	}
+
+	if !fix {
+		return report, nil
+	}
+
+	for _, path := range report.OrphanFiles {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return report, fmt.Errorf("%w: %v", ErrFailedToDeleteFile, err)
+		}
+		report.RemovedFiles++
+	}
+	for _, candidate := range dangling {
+		// В списке файлов только ID, путь и владелец: для удаления и события нужна полная запись
+		doc, err := s.docRepo.GetDocumentByID(ctx, candidate.ID)
+		if err != nil {
+			return report, fmt.Errorf("failed to get dangling document %s: %w", candidate.ID, err)
+		}
+		if doc == nil {
+			continue
+		}
+		if doc.LegalHold {
+			report.HeldDocuments = append(report.HeldDocuments, doc.ID)
+			continue
+		}
+
+		if err := s.purgeDocument(ctx, doc); err != nil {
+			return report, fmt.Errorf("failed to remove dangling document %s: %w", doc.ID, err)
+		}
+		s.cache.Delete("docs_" + doc.Owner)
+		report.RemovedDocuments++
+	}
+
+	return report, nil
+}
+
+// absPath приводит путь к абсолютному виду, чтобы относительные и абсолютные
+// пути к одному файлу совпадали при сверке
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// GarbageCollector периодически сверяет каталог загрузок с таблицей documents
+type GarbageCollector struct {
+	docService *DocumentService
+	interval   time.Duration
+	grace      time.Duration
+	fix        bool
+}
+
+func NewGarbageCollector(docService *DocumentService, interval, grace time.Duration, fix bool) *GarbageCollector {
+	return &GarbageCollector{
+		docService: docService,
+		interval:   interval,
+		grace:      grace,
+		fix:        fix,
+	}
+}
+
+// Run запускает сверку до отмены контекста
+func (g *GarbageCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := g.docService.CollectGarbage(ctx, g.grace, g.fix)
+			if err != nil {
+				log.Printf("gc: %v", err)
+			}
+			if report == nil {
+				continue
+			}
+			if len(report.OrphanFiles) > 0 || len(report.DanglingDocuments) > 0 {
+				log.Printf("gc: %d orphan files, %d dangling documents, removed %d files and %d documents",
+					len(report.OrphanFiles), len(report.DanglingDocuments), report.RemovedFiles, report.RemovedDocuments)
+			}
+		}
+	}
+}
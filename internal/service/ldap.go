@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"docs-server/internal/model"
+	"docs-server/internal/repository"
+)
+
+// LDAPConfig настройки каталога LDAP / Active Directory
+type LDAPConfig struct {
+	URL                string // ldap://host:389 или ldaps://host:636
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string // Служебная учетная запись для поиска пользователя
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // Фильтр поиска, %s заменяется экранированным логином
+	LoginAttribute     string
+	EmailAttribute     string
+	GroupAttribute     string
+	AdminGroups        []string // Члены этих групп получают роль администратора
+	AllowedGroups      []string // Если задано, входить могут только члены этих групп
+	AutoProvision      bool     // Создавать пользователя при первом входе
+	Timeout            time.Duration
+}
+
+// LDAPIdentity пользователь, найденный в каталоге и подтвердивший пароль
+type LDAPIdentity struct {
+	DN     string
+	Login  string
+	Email  string
+	Groups []string // DN групп
+}
+
+// LDAPAuthenticator проверяет пароль привязкой (bind) к каталогу от имени пользователя.
+// Пользователь сопоставляется с users.login, роль администратора синхронизируется с группами.
+// Входить и синхронизироваться могут только пользователи, созданные через каталог:
+// совпадение логина с локальной учетной записью не дает права войти под ней
+type LDAPAuthenticator struct {
+	cfg      LDAPConfig
+	userRepo *repository.UserRepository
+	policy   *CredentialPolicy // Проверка логина при создании пользователя
+
+	// revokeRole отзывает доступ, выданный с прежней ролью. Задается в NewAuthService
+	revokeRole func(ctx context.Context, userID string) error
+}
+
+func NewLDAPAuthenticator(cfg LDAPConfig, userRepo *repository.UserRepository, policy *CredentialPolicy) *LDAPAuthenticator {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(&(objectClass=person)(uid=%s))"
+	}
+	if cfg.LoginAttribute == "" {
+		cfg.LoginAttribute = "uid"
+	}
+	if cfg.GroupAttribute == "" {
+		cfg.GroupAttribute = "memberOf"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if policy == nil {
+		policy = DefaultCredentialPolicy()
+	}
+
+	return &LDAPAuthenticator{
+		cfg:      cfg,
+		userRepo: userRepo,
+		policy:   policy,
+	}
+}
+
+func (a *LDAPAuthenticator) Name() string {
+	return "ldap"
+}
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, login, password string) (*model.User, error) {
+	identity, err := a.Lookup(login, password)
+	if err != nil || identity == nil {
+		return nil, err
+	}
+	if len(a.cfg.AllowedGroups) > 0 && !memberOfAny(identity.Groups, a.cfg.AllowedGroups) {
+		return nil, nil
+	}
+
+	user, err := a.userRepo.GetUserByLogin(ctx, identity.Login)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		if !a.cfg.AutoProvision {
+			return nil, nil
+		}
+		return a.provision(ctx, identity)
+	}
+	if user.Source != model.UserSourceLDAP {
+		log.Printf("ldap: user %q is not managed by the directory", user.Login)
+		return nil, nil
+	}
+
+	return a.syncUser(ctx, user, identity)
+}
+
+// Lookup находит пользователя в каталоге и проверяет пароль.
+// nil без ошибки - пользователь не найден или пароль неверен
+func (a *LDAPAuthenticator) Lookup(login, password string) (*LDAPIdentity, error) {
+	// Привязка с пустым паролем в LDAP считается анонимной и всегда успешна
+	if login == "" || password == "" {
+		return nil, nil
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("service bind failed: %w", err)
+		}
+	}
+
+	attributes := []string{a.cfg.LoginAttribute, a.cfg.GroupAttribute}
+	if a.cfg.EmailAttribute != "" {
+		attributes = append(attributes, a.cfg.EmailAttribute)
+	}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(a.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(login)), attributes, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("user search failed: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		// Неоднозначный фильтр не должен приводить к входу под чужой записью
+		if len(result.Entries) > 1 {
+			log.Printf("ldap: filter matched %d entries for login %q", len(result.Entries), login)
+		}
+		return nil, nil
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("user bind failed: %w", err)
+	}
+
+	identity := &LDAPIdentity{
+		DN:     entry.DN,
+		Login:  entry.GetAttributeValue(a.cfg.LoginAttribute),
+		Groups: entry.GetAttributeValues(a.cfg.GroupAttribute),
+	}
+	if a.cfg.EmailAttribute != "" {
+		identity.Email = entry.GetAttributeValue(a.cfg.EmailAttribute)
+	}
+	if identity.Login == "" {
+		identity.Login = login
+	}
+
+	return identity, nil
+}
+
+func (a *LDAPAuthenticator) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: a.cfg.InsecureSkipVerify}
+
+	conn, err := ldap.DialURL(a.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: a.cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap: %w", err)
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+
+	if a.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls failed: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (a *LDAPAuthenticator) provision(ctx context.Context, identity *LDAPIdentity) (*model.User, error) {
+	if err := a.policy.ValidateLogin(identity.Login); err != nil {
+		return nil, fmt.Errorf("cannot provision %q: %v", identity.Login, err)
+	}
+
+	email := identity.Email
+	if validateEmail(email) != nil {
+		email = ""
+	}
+
+	userID, err := generateID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate user ID: %w", err)
+	}
+	if err := a.userRepo.CreateExternalUser(ctx, &model.User{
+		ID:         userID,
+		Login:      identity.Login,
+		Password:   unusablePassword,
+		Email:      email,
+		IsAdmin:    memberOfAny(identity.Groups, a.cfg.AdminGroups),
+		Source:     model.UserSourceLDAP,
+		ExternalID: identity.DN,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return a.userRepo.GetUserByID(ctx, userID)
+}
+
+// syncUser обновляет почту, роль и DN пользователя по данным каталога
+func (a *LDAPAuthenticator) syncUser(ctx context.Context, user *model.User, identity *LDAPIdentity) (*model.User, error) {
+	if identity.DN != user.ExternalID {
+		if err := a.userRepo.SetUserExternalID(ctx, user.ID, model.UserSourceLDAP, identity.DN); err != nil {
+			return nil, fmt.Errorf("failed to sync user: %w", err)
+		}
+		user.ExternalID = identity.DN
+	}
+
+	updated := *user
+	if identity.Email != "" && validateEmail(identity.Email) == nil {
+		updated.Email = identity.Email
+	}
+	if len(a.cfg.AdminGroups) > 0 {
+		updated.IsAdmin = memberOfAny(identity.Groups, a.cfg.AdminGroups)
+	}
+
+	if updated.Email == user.Email && updated.IsAdmin == user.IsAdmin {
+		return user, nil
+	}
+
+	if user.IsAdmin && !updated.IsAdmin && !user.Disabled() {
+		admins, err := a.userRepo.CountActiveAdmins(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if admins <= 1 {
+			log.Printf("ldap: keeping admin role of %q: %v", user.Login, ErrLastAdmin)
+			updated.IsAdmin = true
+		}
+	}
+
+	if err := a.userRepo.UpdateUser(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("failed to sync user: %w", err)
+	}
+	// Роль, снятая в каталоге, не должна сохраняться в прежних токенах и кеше ключей
+	if updated.IsAdmin != user.IsAdmin && a.revokeRole != nil {
+		if err := a.revokeRole(ctx, user.ID); err != nil {
+			return nil, fmt.Errorf("failed to sync user: %w", err)
+		}
+	}
+	return &updated, nil
+}
+
+// memberOfAny сообщает, состоит ли пользователь в одной из групп.
+// Группа в настройках задается полным DN или значением первого RDN (например, CN)
+func memberOfAny(groups, wanted []string) bool {
+	for _, group := range groups {
+		name := group
+		if dn, err := ldap.ParseDN(group); err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+			name = dn.RDNs[0].Attributes[0].Value
+		}
+		for _, w := range wanted {
+			if strings.EqualFold(w, group) || strings.EqualFold(w, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var errLDAPNotConfigured = errors.New("ldap url and base dn are required")
+
+// Validate проверяет обязательные настройки
+func (c LDAPConfig) Validate() error {
+	if c.URL == "" || c.BaseDN == "" {
+		return errLDAPNotConfigured
+	}
+	return nil
+}
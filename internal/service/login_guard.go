@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"docs-server/internal/cache"
+	"docs-server/internal/model"
+)
+
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// LockoutError отказ во входе из-за неудачных попыток с указанием, когда можно повторить
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// AttemptStore хранилище счетчиков неудачных попыток входа
+type AttemptStore interface {
+	// UpdateAttempts атомарно применяет update к счетчикам по ключу и сохраняет их на срок ttl.
+	// update получает нулевые счетчики, если попыток не было; при ошибке update ничего не сохраняется
+	UpdateAttempts(ctx context.Context, key string, ttl time.Duration, update func(*model.LoginAttempts) error) error
+	DeleteAttempts(ctx context.Context, key string) error
+}
+
+// LoginPolicy ограничения попыток входа (0 - ограничение отключено)
+type LoginPolicy struct {
+	MaxAttempts      int           // Неудачных попыток по логину до блокировки
+	MaxAttemptsPerIP int           // Неудачных попыток с одного IP до блокировки
+	FreeAttempts     int           // Попыток по логину без задержки
+	BaseDelay        time.Duration // Начальная задержка, удваивается с каждой неудачей
+	MaxDelay         time.Duration // Максимальная задержка между попытками
+	LockoutDuration  time.Duration // Длительность блокировки
+	Window           time.Duration // Счетчик сбрасывается, если неудач не было дольше этого срока
+}
+
+// delay возвращает задержку перед следующей попыткой после failures неудач
+func (p LoginPolicy) delay(failures int) time.Duration {
+	if p.BaseDelay <= 0 || failures <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(failures-p.FreeAttempts-1)))
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// LoginGuard отслеживает неудачные попытки входа по логину и IP-адресу.
+// Попытка учитывается как неудачная еще до проверки пароля, одной атомарной операцией
+// вместе с проверкой блокировки: иначе параллельные попытки успевали бы пройти проверку
+// до того, как первая из них будет учтена
+type LoginGuard struct {
+	store  AttemptStore
+	policy LoginPolicy
+}
+
+func NewLoginGuard(store AttemptStore, policy LoginPolicy) *LoginGuard {
+	return &LoginGuard{
+		store:  store,
+		policy: policy,
+	}
+}
+
+// Attempt учитывает попытку входа или возвращает *LockoutError, если вход для логина или IP
+// временно запрещен. Если попытка окажется удачной, ее нужно вернуть через Release
+func (g *LoginGuard) Attempt(ctx context.Context, login, ip string) error {
+	if err := g.store.UpdateAttempts(ctx, loginAttemptKey(login), g.ttl(), g.reserve(g.policy.MaxAttempts, true)); err != nil {
+		return err
+	}
+
+	// Задержка между попытками применяется только к логину:
+	// за одним IP могут находиться много пользователей
+	if err := g.store.UpdateAttempts(ctx, ipAttemptKey(ip), g.ttl(), g.reserve(g.policy.MaxAttemptsPerIP, false)); err != nil {
+		if releaseErr := g.store.UpdateAttempts(ctx, loginAttemptKey(login), g.ttl(), release); releaseErr != nil {
+			return errors.Join(err, releaseErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Release возвращает попытку, которая не была неудачной, не сбрасывая остальные неудачи
+func (g *LoginGuard) Release(ctx context.Context, login, ip string) error {
+	if err := g.store.UpdateAttempts(ctx, loginAttemptKey(login), g.ttl(), release); err != nil {
+		return err
+	}
+	return g.store.UpdateAttempts(ctx, ipAttemptKey(ip), g.ttl(), release)
+}
+
+// Succeed сбрасывает счетчик логина после успешного входа.
+// Счетчик IP не сбрасывается, чтобы вход в свою учетную запись не снимал ограничение перебора чужих
+func (g *LoginGuard) Succeed(ctx context.Context, login string) error {
+	return g.store.DeleteAttempts(ctx, loginAttemptKey(login))
+}
+
+// Unlock снимает блокировку логина (вызывается администратором)
+func (g *LoginGuard) Unlock(ctx context.Context, login string) error {
+	return g.store.DeleteAttempts(ctx, loginAttemptKey(login))
+}
+
+func (g *LoginGuard) ttl() time.Duration {
+	return g.policy.Window + g.policy.LockoutDuration
+}
+
+// reserve возвращает проверку блокировки с учетом новой попытки.
+// Блокировка наступает при следующей попытке после maxAttempts учтенных, поэтому
+// возвращенная удачная попытка не блокирует вход
+func (g *LoginGuard) reserve(maxAttempts int, withDelay bool) func(*model.LoginAttempts) error {
+	return func(attempts *model.LoginAttempts) error {
+		now := time.Now()
+
+		if maxAttempts > 0 && attempts.Failures >= maxAttempts {
+			// После блокировки отсчет начинается заново
+			attempts.LockedUntil = attempts.LastFailure.Add(g.policy.LockoutDuration)
+			attempts.Failures = 0
+		}
+		if now.Before(attempts.LockedUntil) {
+			return &LockoutError{RetryAfter: attempts.LockedUntil.Sub(now)}
+		}
+		if g.policy.Window > 0 && now.Sub(attempts.LastFailure) > g.policy.Window {
+			attempts.Failures = 0
+		}
+
+		if withDelay {
+			next := attempts.LastFailure.Add(g.policy.delay(attempts.Failures))
+			if now.Before(next) {
+				return &LockoutError{RetryAfter: next.Sub(now)}
+			}
+		}
+
+		attempts.Failures++
+		attempts.LastFailure = now
+		return nil
+	}
+}
+
+// release возвращает учтенную попытку
+func release(attempts *model.LoginAttempts) error {
+	if attempts.Failures > 0 {
+		attempts.Failures--
+	}
+	return nil
+}
+
+func loginAttemptKey(login string) string {
+	return "login_attempts_login_" + login
+}
+
+func ipAttemptKey(ip string) string {
+	return "login_attempts_ip_" + ip
+}
+
+// cacheAttemptStore хранит счетчики попыток в кеше приложения
+type cacheAttemptStore struct {
+	cache *cache.MemoryCache
+	mu    sync.Mutex // Чтение и запись счетчика выполняются как одна операция
+}
+
+func NewCacheAttemptStore(cache *cache.MemoryCache) AttemptStore {
+	return &cacheAttemptStore{cache: cache}
+}
+
+func (s *cacheAttemptStore) UpdateAttempts(ctx context.Context, key string, ttl time.Duration, update func(*model.LoginAttempts) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := &model.LoginAttempts{}
+	if cached, found := s.cache.Get(key); found {
+		*attempts = *cached.(*model.LoginAttempts)
+	}
+	if err := update(attempts); err != nil {
+		return err
+	}
+
+	s.cache.Set(key, attempts, ttl)
+	return nil
+}
+
+func (s *cacheAttemptStore) DeleteAttempts(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache.Delete(key)
+	return nil
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"docs-server/internal/model"
+	"docs-server/internal/oidc"
+)
+
+var (
+	ErrOIDCDisabled     = errors.New("oidc login is not configured")
+	ErrInvalidOIDCState = errors.New("invalid or expired oidc state")
+	ErrOIDCLoginFailed  = errors.New("oidc login failed")
+	ErrOIDCUnknownUser  = errors.New("user is not provisioned")
+	ErrOIDCNotLinked    = errors.New("account exists but is not linked to this identity")
+	ErrOIDCLinked       = errors.New("identity is already linked to another account")
+	ErrOIDCLDAPUser     = errors.New("account is managed by the ldap directory")
+)
+
+// OIDCStateTTL время, за которое пользователь должен вернуться от провайдера
+const OIDCStateTTL = 10 * time.Minute
+
+// OIDCLogin настройки входа через OpenID Connect (Provider nil - вход отключен)
+type OIDCLogin struct {
+	Provider      *oidc.Provider
+	LoginClaim    string // Утверждение ID-токена, задающее логин нового пользователя
+	AutoProvision bool   // Создавать пользователя при первом входе
+}
+
+// oidcState данные запроса авторизации, хранятся до возврата пользователя от провайдера
+type oidcState struct {
+	Nonce      string
+	Verifier   string
+	Device     string
+	LinkUserID string // Пользователь, к которому привязывается учетная запись провайдера
+}
+
+// BeginOIDCLogin возвращает адрес страницы входа провайдера и привязку state к браузеру.
+// Привязку нужно сохранить в браузере (cookie) и передать в CompleteOIDCLogin: без нее
+// злоумышленник мог бы подсунуть пользователю ответ провайдера на свой собственный вход
+func (s *AuthService) BeginOIDCLogin(device string) (string, string, error) {
+	return s.beginOIDC(device, "")
+}
+
+// BeginOIDCLink возвращает адрес страницы входа провайдера для привязки его учетной записи
+// к пользователю и привязку state к браузеру, как BeginOIDCLogin.
+// После привязки пользователь сможет входить через провайдера
+func (s *AuthService) BeginOIDCLink(user *model.User, device string) (string, string, error) {
+	return s.beginOIDC(device, user.ID)
+}
+
+func (s *AuthService) beginOIDC(device, linkUserID string) (string, string, error) {
+	if s.oidc.Provider == nil {
+		return "", "", ErrOIDCDisabled
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var values [3]string
+	for i := range values {
+		value, err := oidc.RandomString()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate oidc state: %w", err)
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := s.oidc.Provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.tokenCache.Set("oidc_state_"+state, &oidcState{
+		Nonce:      nonce,
+		Verifier:   verifier,
+		Device:     device,
+		LinkUserID: linkUserID,
+	}, OIDCStateTTL)
+
+	return authURL, hashToken(state), nil
+}
+
+// CompleteOIDCLogin проверяет ответ провайдера и открывает сессию пользователя, привязанного
+// к учетной записи провайдера (iss и sub). binding - привязка state, выданная при начале входа
+// тому же браузеру. Второй фактор проверяется так же, как при входе по паролю
+func (s *AuthService) CompleteOIDCLogin(state, binding, code, ip string) (*model.TokenPair, *model.MFAChallenge, error) {
+	if s.oidc.Provider == nil {
+		return nil, nil, ErrOIDCDisabled
+	}
+
+	// Ответ, пришедший в браузер, который не начинал этот вход, отклоняется
+	if binding == "" || subtle.ConstantTimeCompare([]byte(binding), []byte(hashToken(state))) != 1 {
+		return nil, nil, ErrInvalidOIDCState
+	}
+
+	cached, found := s.tokenCache.Get("oidc_state_" + state)
+	if !found || state == "" {
+		return nil, nil, ErrInvalidOIDCState
+	}
+	// state одноразовый
+	s.tokenCache.Delete("oidc_state_" + state)
+	pending := cached.(*oidcState)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	claims, err := s.oidc.Provider.Exchange(ctx, code, pending.Verifier, pending.Nonce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	subject := oidcSubject(claims)
+	if subject == "" {
+		return nil, nil, fmt.Errorf("%w: claim \"sub\" is missing", ErrOIDCLoginFailed)
+	}
+
+	var user *model.User
+	if pending.LinkUserID != "" {
+		user, err = s.linkOIDCUser(ctx, pending.LinkUserID, subject)
+	} else {
+		user, err = s.findOIDCUser(ctx, subject, claims)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Disabled() {
+		return nil, nil, ErrUserDisabled
+	}
+
+	return s.startSession(ctx, user, pending.Device, ip)
+}
+
+// findOIDCUser возвращает пользователя, привязанного к учетной записи провайдера, или создает его.
+// Совпадение логина с существующим пользователем не дает права войти под ним:
+// утверждение с логином контролирует провайдер, а не владелец учетной записи
+func (s *AuthService) findOIDCUser(ctx context.Context, subject string, claims oidc.Claims) (*model.User, error) {
+	user, err := s.userRepo.GetUserByExternalID(ctx, model.UserSourceOIDC, subject)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		return user, nil
+	}
+
+	login := claims.String(s.oidc.LoginClaim)
+	if login == "" {
+		return nil, fmt.Errorf("%w: claim %q is missing", ErrOIDCLoginFailed, s.oidc.LoginClaim)
+	}
+
+	existing, err := s.userRepo.GetUserByLogin(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrOIDCNotLinked
+	}
+	if !s.oidc.AutoProvision {
+		return nil, ErrOIDCUnknownUser
+	}
+
+	return s.provisionOIDCUser(ctx, login, subject, claims)
+}
+
+// linkOIDCUser привязывает учетную запись провайдера к пользователю, начавшему привязку
+func (s *AuthService) linkOIDCUser(ctx context.Context, userID, subject string) (*model.User, error) {
+	linked, err := s.userRepo.GetUserByExternalID(ctx, model.UserSourceOIDC, subject)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil && linked.ID != userID {
+		return nil, ErrOIDCLinked
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	// Привязка сменила бы источник, и пользователь перестал бы синхронизироваться с каталогом
+	if user.Source == model.UserSourceLDAP {
+		return nil, ErrOIDCLDAPUser
+	}
+
+	if err := s.userRepo.SetUserExternalID(ctx, user.ID, model.UserSourceOIDC, subject); err != nil {
+		return nil, fmt.Errorf("failed to link user: %w", err)
+	}
+	user.Source, user.ExternalID = model.UserSourceOIDC, subject
+	return user, nil
+}
+
+// oidcSubject идентификатор учетной записи провайдера: sub уникален только в пределах издателя
+func oidcSubject(claims oidc.Claims) string {
+	iss, sub := claims.String("iss"), claims.String("sub")
+	if iss == "" || sub == "" {
+		return ""
+	}
+	return iss + "#" + sub
+}
+
+// provisionOIDCUser создает пользователя при первом входе через провайдера.
+// Локальный пароль не задается: войти по паролю можно только после его сброса
+func (s *AuthService) provisionOIDCUser(ctx context.Context, login, subject string, claims oidc.Claims) (*model.User, error) {
+	if err := s.policy.ValidateLogin(login); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+
+	var email string
+	if verified, _ := claims["email_verified"].(bool); verified {
+		email = claims.String("email")
+	}
+	if validateEmail(email) != nil {
+		email = ""
+	}
+
+	userID, err := generateID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate user ID: %w", err)
+	}
+	if err := s.userRepo.CreateExternalUser(ctx, &model.User{
+		ID:         userID,
+		Login:      login,
+		Password:   unusablePassword,
+		Email:      email,
+		Source:     model.UserSourceOIDC,
+		ExternalID: subject,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return s.userRepo.GetUserByID(ctx, userID)
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"docs-server/internal/model"
+	"docs-server/internal/sink"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	outboxBaseDelay = 5 * time.Second
+	outboxMaxDelay  = 10 * time.Minute
+	outboxBatch     = 100
+	maxOutboxError  = 1024
+)
+
+// OutboxStore неопубликованные события документов. Сообщения добавляются
+// в транзакции изменения документа, см. DocumentRepository
+type OutboxStore interface {
+	ClaimOutboxMessages(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.OutboxMessage, error)
+	UpdateOutboxMessage(ctx context.Context, message *model.OutboxMessage) error
+	DeletePublishedOutboxMessages(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OutboxPolicy настройки публикации событий из outbox
+type OutboxPolicy struct {
+	Timeout     time.Duration // Время на публикацию одного сообщения
+	MaxAttempts int           // Попыток до перевода сообщения в failed
+	Retention   time.Duration // Срок хранения опубликованных сообщений
+}
+
+// Outbox публикует события документов в приемники. Для каждого приемника событие записывается
+// отдельным сообщением в транзакции изменения документа, поэтому оно не теряется при сбое
+// после коммита и публикуется в каждый приемник не менее одного раза независимо от остальных
+type Outbox struct {
+	store  OutboxStore
+	sinks  map[string]sink.Sink
+	names  []string
+	policy OutboxPolicy
+	wake   chan struct{}
+}
+
+func NewOutbox(store OutboxStore, sinks map[string]sink.Sink, policy OutboxPolicy) *Outbox {
+	names := make([]string, 0, len(sinks))
+	for name := range sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return &Outbox{
+		store:  store,
+		sinks:  sinks,
+		names:  names,
+		policy: policy,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Messages возвращает сообщения о событии для всех приемников
+func (o *Outbox) Messages(event *model.DocumentEvent) ([]*model.OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	messages := make([]*model.OutboxMessage, 0, len(o.names))
+	for _, name := range o.names {
+		messages = append(messages, &model.OutboxMessage{
+			Sink:          name,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			Status:        model.OutboxPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	return messages, nil
+}
+
+// Notify будит OutboxRelay после коммита новых сообщений. Для nil-outbox ничего не делает
+func (o *Outbox) Notify() {
+	if o == nil {
+		return
+	}
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RelayDue публикует сообщения, срок попытки которых наступил к now. Возвращает количество опубликованных
+func (o *Outbox) RelayDue(ctx context.Context, now time.Time) (int, error) {
+	// Сообщения закрепляются за этим экземпляром на время публикации пакета;
+	// если он завершится, они снова станут доступны по истечении аренды
+	lease := outboxBatch*o.policy.Timeout + time.Minute
+	messages, err := o.store.ClaimOutboxMessages(ctx, now, lease, outboxBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+
+	published := 0
+	for _, message := range messages {
+		if o.publish(ctx, message, now) {
+			published++
+		}
+	}
+	return published, nil
+}
+
+// publish выполняет одну попытку публикации и сохраняет ее результат
+func (o *Outbox) publish(ctx context.Context, message *model.OutboxMessage, now time.Time) bool {
+	message.Attempts++
+	err := o.send(ctx, message)
+
+	if err == nil {
+		message.Status = model.OutboxPublished
+		message.LastError = ""
+		publishedAt := time.Now().Truncate(time.Second)
+		message.PublishedAt = &publishedAt
+	} else {
+		message.LastError = err.Error()
+		if len(message.LastError) > maxOutboxError {
+			message.LastError = message.LastError[:maxOutboxError]
+		}
+		if message.Attempts >= o.policy.MaxAttempts {
+			message.Status = model.OutboxFailed
+			log.Printf("outbox: event %s to %s failed after %d attempts: %v", message.EventID, message.Sink, message.Attempts, err)
+		} else {
+			message.NextAttemptAt = now.Add(outboxBackoff(message.Attempts)).Truncate(time.Second)
+		}
+	}
+
+	if err := o.store.UpdateOutboxMessage(ctx, message); err != nil {
+		log.Printf("outbox: failed to update message %d: %v", message.ID, err)
+	}
+	return message.Status == model.OutboxPublished
+}
+
+func (o *Outbox) send(ctx context.Context, message *model.OutboxMessage) error {
+	target, ok := o.sinks[message.Sink]
+	if !ok {
+		// Приемник убран из настроек: сообщение ждет его возвращения до исчерпания попыток
+		return fmt.Errorf("sink %q is not configured", message.Sink)
+	}
+
+	event := &model.DocumentEvent{}
+	if err := json.Unmarshal(message.Payload, event); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.policy.Timeout)
+	defer cancel()
+	return target.Publish(ctx, event)
+}
+
+// Cleanup удаляет опубликованные сообщения старше срока хранения
+func (o *Outbox) Cleanup(ctx context.Context, now time.Time) (int64, error) {
+	return o.store.DeletePublishedOutboxMessages(ctx, now.Add(-o.policy.Retention))
+}
+
+// outboxBackoff задержка после неудачной попытки номер attempt
+func outboxBackoff(attempt int) time.Duration {
+	delay := outboxBaseDelay
+	for i := 1; i < attempt && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxDelay {
+		delay = outboxMaxDelay
+	}
+	return delay
+}
+
+// OutboxRelay публикует события из outbox в фоне: сразу после коммита изменений
+// и периодически для повторных попыток и сообщений, оставшихся после сбоя
+type OutboxRelay struct {
+	outbox   *Outbox
+	interval time.Duration
+}
+
+func NewOutboxRelay(outbox *Outbox, interval time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		outbox:   outbox,
+		interval: interval,
+	}
+}
+
+// Run запускает публикацию до отмены контекста
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.outbox.wake:
+		}
+
+		// Пакет за пакетом, пока есть сообщения к отправке
+		for {
+			published, err := r.outbox.RelayDue(ctx, time.Now())
+			if err != nil {
+				log.Printf("outbox relay: %v", err)
+			}
+			if err != nil || published < outboxBatch {
+				break
+			}
+		}
+
+		if time.Since(lastCleanup) >= time.Hour {
+			if _, err := r.outbox.Cleanup(ctx, time.Now()); err != nil {
+				log.Printf("outbox relay: %v", err)
+			}
+			lastCleanup = time.Now()
+		}
+	}
+}
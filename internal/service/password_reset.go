@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"time"
+
+	"docs-server/internal/model"
+	"docs-server/internal/notify"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+	ErrResetUnavailable  = errors.New("password reset delivery is not configured")
+	ErrInvalidEmail      = errors.New("invalid email address")
+)
+
+// PasswordReset настройки сброса пароля
+type PasswordReset struct {
+	Notifier notify.Notifier // Доставка токена, nil - только сброс администратором
+	TTL      time.Duration   // Срок действия токена
+	URL      string          // Адрес страницы сброса, к которому дописывается токен
+}
+
+// maxPendingResets сколько запросов сброса пароля обрабатывается в фоне одновременно
+const maxPendingResets = 16
+
+// RequestPasswordReset отправляет пользователю одноразовый токен сброса пароля.
+// Поиск пользователя, выпуск токена и отправка письма выполняются в фоне, поэтому ни ответ,
+// ни время ответа не зависят от существования пользователя и не раскрывают логины
+func (s *AuthService) RequestPasswordReset(login string) error {
+	if s.reset.Notifier == nil {
+		return ErrResetUnavailable
+	}
+
+	select {
+	case s.resetSlots <- struct{}{}:
+		go func() {
+			defer func() { <-s.resetSlots }()
+			s.sendPasswordReset(login)
+		}()
+	default:
+		log.Printf("password reset: too many pending requests, request dropped")
+	}
+
+	return nil
+}
+
+// sendPasswordReset выпускает токен сброса и отправляет его пользователю login, если он может его получить
+func (s *AuthService) sendPasswordReset(login string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user, err := s.userRepo.GetUserByLogin(ctx, login)
+	if err != nil {
+		log.Printf("password reset: failed to get user: %v", err)
+		return
+	}
+	if user == nil || user.Disabled() || user.Email == "" {
+		return
+	}
+
+	token, expiresAt, err := s.issuePasswordReset(ctx, user)
+	if err != nil {
+		log.Printf("password reset: %v", err)
+		return
+	}
+
+	if err := s.reset.Notifier.Send(ctx, s.passwordResetMessage(user, token, expiresAt)); err != nil {
+		log.Printf("password reset: failed to notify user %s: %v", user.ID, err)
+	}
+}
+
+// AdminPasswordReset выдает токен сброса пароля по запросу администратора.
+// Если доставка настроена и у пользователя указана почта, токен отправляется ему
+// и не возвращается, иначе возвращается для передачи пользователю
+func (s *AuthService) AdminPasswordReset(userID string) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return "", false, ErrUserNotFound
+	}
+
+	token, expiresAt, err := s.issuePasswordReset(ctx, user)
+	if err != nil {
+		return "", false, err
+	}
+
+	if s.reset.Notifier == nil || user.Email == "" {
+		return token, false, nil
+	}
+
+	if err := s.reset.Notifier.Send(ctx, s.passwordResetMessage(user, token, expiresAt)); err != nil {
+		return "", false, fmt.Errorf("failed to send password reset: %w", err)
+	}
+
+	return "", true, nil
+}
+
+// ResetPassword устанавливает новый пароль по токену сброса и завершает все сессии пользователя
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+
+	// Пароль проверяется до использования токена, чтобы ошибка не сжигала токен
+	hashedPassword, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userID, err := s.userRepo.ConsumePasswordReset(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return err
+	}
+	if userID == "" {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return s.RevokeOtherSessions(ctx, userID, "")
+}
+
+// issuePasswordReset создает токен сброса пароля, в БД сохраняется только его хеш
+func (s *AuthService) issuePasswordReset(ctx context.Context, user *model.User) (string, time.Time, error) {
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	expiresAt := time.Now().Add(s.reset.TTL).Truncate(time.Second)
+	if err := s.userRepo.CreatePasswordReset(ctx, hashToken(token), user.ID, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// passwordResetMessage формирует письмо со ссылкой или кодом сброса пароля
+func (s *AuthService) passwordResetMessage(user *model.User, token string, expiresAt time.Time) *notify.Message {
+	link := "Код для сброса пароля: " + token
+	if s.reset.URL != "" {
+		link = "Для смены пароля перейдите по ссылке:\n" + s.reset.URL + token
+	}
+
+	return &notify.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Запрошен сброс пароля пользователя %s.\n\n%s\n\n"+
+			"Токен действителен до %s и может быть использован один раз.\n"+
+			"Если вы не запрашивали сброс, проигнорируйте это письмо.\n",
+			user.Login, link, expiresAt.Format("2006-01-02 15:04:05 MST")),
+	}
+}
+
+// validateEmail проверяет адрес электронной почты (пустой адрес допустим)
+func validateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"docs-server/internal/model"
+)
+
+const (
+	pendingDeleteBaseDelay = 30 * time.Second
+	pendingDeleteMaxDelay  = time.Hour
+)
+
+// ProcessPendingDeletes удаляет файлы из очереди pending_deletes, срок попытки которых наступил к now
+func (s *DocumentService) ProcessPendingDeletes(ctx context.Context, now time.Time) (int, error) {
+	pending, err := s.docRepo.GetPendingDeletes(ctx, now, 100)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pending deletes: %w", err)
+	}
+
+	removed := 0
+	for _, p := range pending {
+		if s.removePendingFile(ctx, p, now) {
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// removePendingFile удаляет файл из очереди. При ошибке откладывает
+// следующую попытку с экспоненциальной задержкой и возвращает false.
+func (s *DocumentService) removePendingFile(ctx context.Context, p *model.PendingDelete, now time.Time) bool {
+	err := os.Remove(p.FilePath)
+	if err == nil || os.IsNotExist(err) {
+		if err := s.docRepo.CompletePendingDelete(ctx, p.ID); err != nil {
+			log.Printf("failed to complete pending delete %s: %v", p.FilePath, err)
+			return false
+		}
+		return true
+	}
+
+	nextAttempt := now.Add(pendingDeleteBackoff(p.Attempts + 1))
+	if err := s.docRepo.FailPendingDelete(ctx, p.ID, err.Error(), nextAttempt); err != nil {
+		log.Printf("failed to reschedule pending delete %s: %v", p.FilePath, err)
+	}
+	log.Printf("%v: %s: %v", ErrFailedToDeleteFile, p.FilePath, err)
+
+	return false
+}
+
+// pendingDeleteBackoff задержка перед попыткой номер attempt
+func pendingDeleteBackoff(attempt int) time.Duration {
+	delay := pendingDeleteBaseDelay
+	for i := 1; i < attempt && delay < pendingDeleteMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > pendingDeleteMaxDelay {
+		delay = pendingDeleteMaxDelay
+	}
+	return delay
+}
+
+// PendingDeleteWorker периодически повторяет удаление файлов из очереди pending_deletes
+type PendingDeleteWorker struct {
+	docService *DocumentService
+	interval   time.Duration
+}
+
+func NewPendingDeleteWorker(docService *DocumentService, interval time.Duration) *PendingDeleteWorker {
+	return &PendingDeleteWorker{
+		docService: docService,
+		interval:   interval,
+	}
+}
+
+// Run запускает обработку очереди до отмены контекста
+func (w *PendingDeleteWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			removed, err := w.docService.ProcessPendingDeletes(ctx, time.Now())
+			if err != nil {
+				log.Printf("pending deletes: %v", err)
+			}
+			if removed > 0 {
+				log.Printf("pending deletes: removed %d files", removed)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"docs-server/internal/model"
+)
+
+// RetentionPolicy сроки хранения документов по умолчанию (0 - бессрочно).
+// Приоритет: пользователь, затем MIME-тип, затем общий срок.
+type RetentionPolicy struct {
+	Default time.Duration
+	ByMime  map[string]time.Duration // MIME-тип или шаблон вида "image/*"
+	ByUser  map[string]time.Duration // Логин пользователя
+}
+
+// TTL возвращает срок хранения документа пользователя login с MIME-типом mimeType
+func (p RetentionPolicy) TTL(login, mimeType string) time.Duration {
+	if ttl, ok := p.ByUser[login]; ok {
+		return ttl
+	}
+
+	// Отбрасываем параметры вида "; charset=utf-8"
+	mimeType = strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0])
+	if ttl, ok := p.ByMime[mimeType]; ok {
+		return ttl
+	}
+	if i := strings.Index(mimeType, "/"); i > 0 {
+		if ttl, ok := p.ByMime[mimeType[:i]+"/*"]; ok {
+			return ttl
+		}
+	}
+
+	return p.Default
+}
+
+// UpdateDocument изменяет срок хранения и юридическое удержание документа.
+// Срок хранения меняет владелец, удержание - только администратор (в том числе для чужих документов)
+func (s *DocumentService) UpdateDocument(user *model.User, id string, patch *model.DocumentPatch) (*model.Document, error) {
+	if patch.LegalHold != nil && !user.IsAdmin {
+		return nil, ErrLegalHoldAdminOnly
+	}
+
+	doc, err := s.docRepo.GetDocumentByID(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil || doc.DeletedAt != nil || doc.Expired(time.Now()) {
+		return nil, ErrDocumentNotFound
+	}
+	changesExpiry := patch.ClearExpiry || patch.ExpiresAt != nil
+	if doc.Owner != user.ID && (changesExpiry || !user.IsAdmin) {
+		return nil, ErrNotDocumentOwner
+	}
+
+	if patch.ClearExpiry {
+		doc.ExpiresAt = nil
+	} else if patch.ExpiresAt != nil {
+		if !patch.ExpiresAt.After(time.Now()) {
+			return nil, ErrInvalidExpiry
+		}
+		doc.ExpiresAt = patch.ExpiresAt
+	}
+	if patch.LegalHold != nil {
+		doc.LegalHold = *patch.LegalHold
+	}
+
+	changes := make(map[string]interface{})
+	if changesExpiry {
+		changes["expires_at"] = doc.ExpiresAt
+	}
+	if patch.LegalHold != nil {
+		changes["legal_hold"] = doc.LegalHold
+	}
+	outbox, err := s.eventMessages(model.EventDocumentUpdated, doc, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.docRepo.UpdateDocumentRetention(context.Background(), id, doc.ExpiresAt, doc.LegalHold, outbox...); err != nil {
+		return nil, fmt.Errorf("failed to update document: %w", err)
+	}
+
+	// Инвалидация кеша
+	s.cache.Delete("doc_" + id)
+	s.cache.Delete("docs_" + doc.Owner)
+
+	s.outbox.Notify()
+
+	return doc, nil
+}
+
+// PurgeExpired окончательно удаляет документы с истекшим сроком хранения
+func (s *DocumentService) PurgeExpired(ctx context.Context) (int, error) {
+	ids, err := s.docRepo.GetExpiredDocuments(ctx, time.Now(), 100)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired documents: %w", err)
+	}
+
+	purged := 0
+	for _, id := range ids {
+		doc, err := s.docRepo.GetDocumentByID(ctx, id)
+		if err != nil {
+			return purged, err
+		}
+		if doc == nil || !doc.Expired(time.Now()) {
+			continue
+		}
+
+		if err := s.purgeDocument(ctx, doc); err != nil {
+			return purged, fmt.Errorf("failed to purge document %s: %w", id, err)
+		}
+		s.cache.Delete("docs_" + doc.Owner)
+		purged++
+	}
+
+	return purged, nil
+}
+
+// ExpiryScheduler периодически удаляет документы с истекшим сроком хранения
+type ExpiryScheduler struct {
+	docService *DocumentService
+	interval   time.Duration
+}
+
+func NewExpiryScheduler(docService *DocumentService, interval time.Duration) *ExpiryScheduler {
+	return &ExpiryScheduler{
+		docService: docService,
+		interval:   interval,
+	}
+}
+
+// Run запускает удаление истекших документов до отмены контекста
+func (e *ExpiryScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := e.docService.PurgeExpired(ctx)
+			if err != nil {
+				log.Printf("expiry scheduler: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("expiry scheduler: deleted %d expired documents", purged)
+			}
+		}
+	}
+}
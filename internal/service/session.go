@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"docs-server/internal/model"
+)
+
+// sessionCacheTTL время жизни сессии в кеше; ограничивает задержку,
+// с которой отзыв сессии в другом экземпляре сервиса становится виден здесь
+const sessionCacheTTL = time.Minute
+
+type cachedSession struct {
+	user    *model.User
+	session *model.Session
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов.
+// Повторное предъявление уже использованного токена отзывает всю сессию.
+func (s *AuthService) Refresh(refreshToken, ip string) (*model.TokenPair, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	oldHash := hashToken(refreshToken)
+	stored, err := s.sessionRepo.GetRefreshToken(ctx, oldHash)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if stored.UsedAt != nil {
+		if err := s.revokeSession(ctx, stored.SessionID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, session, err := s.getSession(ctx, stored.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	previousTokenID, previousExpiry := session.AccessTokenID, session.AccessExpiry
+
+	pair, newHash, err := s.issueTokens(user, session)
+	if err != nil {
+		return nil, err
+	}
+	if ip != "" {
+		session.IP = ip
+	}
+
+	rotated, err := s.sessionRepo.RotateRefreshToken(ctx, oldHash, newHash, session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		// Токен был использован параллельно
+		if err := s.revokeSession(ctx, session.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	s.tokenCache.Delete("session_" + session.ID)
+
+	// Прежний access-токен недействителен и без сверки с сессией
+	if err := s.denyToken(ctx, previousTokenID, previousExpiry); err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// ListSessions возвращает действующие сессии пользователя, помечая текущую
+func (s *AuthService) ListSessions(userID, currentSessionID string) ([]*model.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sessions, err := s.sessionRepo.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession отзывает сессию пользователя
+func (s *AuthService) RevokeSession(userID, sessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session == nil || session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+
+	return s.revokeSession(ctx, sessionID)
+}
+
+// RevokeOtherSessions отзывает все сессии пользователя, кроме exceptSessionID
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID, exceptSessionID string) error {
+	sessions, err := s.sessionRepo.RevokeUserSessions(ctx, userID, exceptSessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	for _, session := range sessions {
+		s.tokenCache.Delete("session_" + session.ID)
+		if err := s.denyToken(ctx, session.AccessTokenID, session.AccessExpiry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeAccessTokens отзывает текущие access-токены всех сессий пользователя, не завершая сессии.
+// Нужен после изменения логина или роли: в режиме без сессий они передаются в утверждениях токена,
+// а новые значения клиент получит при обновлении пары токенов
+func (s *AuthService) RevokeAccessTokens(ctx context.Context, userID string) error {
+	sessions, err := s.sessionRepo.ListUserSessions(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for _, session := range sessions {
+		s.tokenCache.Delete("session_" + session.ID)
+		if err := s.denyToken(ctx, session.AccessTokenID, session.AccessExpiry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeRoleAccess отзывает access-токены и сбрасывает кеш API-ключей пользователя
+// после смены роли вне UserService.UpdateUser, например при синхронизации с каталогом
+func (s *AuthService) revokeRoleAccess(ctx context.Context, userID string) error {
+	if err := s.RevokeAccessTokens(ctx, userID); err != nil {
+		return err
+	}
+	return s.ForgetAPIKeys(ctx, userID)
+}
+
+// userAccess сессии и API-ключи пользователя, запомненные до удаления учетной записи:
+// после удаления их строки удаляются каскадно, и найти их для отзыва уже нельзя
+type userAccess struct {
+	sessions []*model.Session
+	keys     []*model.APIKey
+}
+
+// listUserAccess запоминает сессии и API-ключи пользователя для revokeDeletedUserAccess
+func (s *AuthService) listUserAccess(ctx context.Context, userID string) (*userAccess, error) {
+	sessions, err := s.sessionRepo.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	keys, err := s.apiKeyRepo.ListUserAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return &userAccess{sessions: sessions, keys: keys}, nil
+}
+
+// revokeDeletedUserAccess отзывает access-токены и сбрасывает кеш сессий и ключей удаленного пользователя.
+// Сами строки сессий и ключей уже удалены вместе с учетной записью
+func (s *AuthService) revokeDeletedUserAccess(ctx context.Context, access *userAccess) error {
+	for _, key := range access.keys {
+		s.tokenCache.Delete("apikey_" + key.KeyHash)
+	}
+	for _, session := range access.sessions {
+		s.tokenCache.Delete("session_" + session.ID)
+		if err := s.denyToken(ctx, session.AccessTokenID, session.AccessExpiry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSession открывает новую сессию и выдает для нее пару токенов
+func (s *AuthService) createSession(ctx context.Context, user *model.User, device, ip string) (*model.TokenPair, error) {
+	sessionID, err := generateID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &model.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		Device:     device,
+		IP:         ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+
+	pair, refreshHash, err := s.issueTokens(user, session)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionRepo.CreateSession(ctx, session, refreshHash); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return pair, nil
+}
+
+// issueTokens выпускает пару токенов для сессии и обновляет в ней хеш и сроки действия.
+// Возвращает пару и хеш refresh-токена.
+func (s *AuthService) issueTokens(user *model.User, session *model.Session) (*model.TokenPair, string, error) {
+	tokenID, err := generateID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	accessToken, accessExpiry, err := s.generateJWTToken(user, session.ID, tokenID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	session.AccessTokenHash = hashToken(accessToken)
+	session.AccessTokenID = tokenID
+	session.AccessExpiry = accessExpiry
+	session.RefreshExpiry = now.Add(s.tokens.RefreshTTL())
+	session.LastUsedAt = now
+
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    accessExpiry,
+		SessionID:    session.ID,
+		UserID:       user.ID,
+	}, hashToken(refreshToken), nil
+}
+
+// getSession возвращает действующую сессию и ее пользователя
+func (s *AuthService) getSession(ctx context.Context, sessionID string) (*model.User, *model.Session, error) {
+	cacheKey := "session_" + sessionID
+	if cached, found := s.tokenCache.Get(cacheKey); found {
+		if entry, ok := cached.(*cachedSession); ok {
+			return entry.user, entry.session, nil
+		}
+	}
+
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session == nil {
+		return nil, nil, ErrInvalidToken
+	}
+	if session.RevokedAt != nil {
+		return nil, nil, ErrSessionRevoked
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, nil, ErrUserNotFound
+	}
+	if user.Disabled() {
+		return nil, nil, ErrUserDisabled
+	}
+
+	s.tokenCache.Set(cacheKey, &cachedSession{user: user, session: session}, sessionCacheTTL)
+
+	return user, session, nil
+}
+
+// revokeSession отзывает сессию, удаляет ее из кеша и вносит ее access-токен в список отозванных
+func (s *AuthService) revokeSession(ctx context.Context, sessionID string) error {
+	if err := s.sessionRepo.RevokeSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	s.tokenCache.Delete("session_" + sessionID)
+
+	if s.denylist == nil {
+		return nil
+	}
+	session, err := s.sessionRepo.GetSession(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	if session == nil {
+		return nil
+	}
+	return s.denyToken(ctx, session.AccessTokenID, session.AccessExpiry)
+}
+
+// denyToken вносит access-токен в список отозванных, если включен режим без сессий
+func (s *AuthService) denyToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if s.denylist == nil {
+		return nil
+	}
+	// Токен принимается еще leeway после истечения срока
+	if err := s.denylist.Revoke(ctx, tokenID, expiresAt.Add(s.tokens.Leeway())); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return nil
+}
+
+// generateOpaqueToken генерирует случайный непрозрачный токен
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken возвращает SHA-256 хеш токена для хранения в БД
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
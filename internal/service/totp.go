@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"docs-server/internal/model"
+	"docs-server/internal/totp"
+)
+
+var (
+	ErrInvalidMFAToken    = errors.New("invalid or expired mfa token")
+	ErrInvalidMFACode     = errors.New("invalid two-factor code")
+	ErrInvalidTOTPCode    = errors.New("invalid totp code")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrTOTPNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrTOTPRequired       = errors.New("two-factor authentication is required by policy")
+)
+
+const (
+	recoveryCodeCount = 10
+	mfaAudience       = "mfa"
+	totpSkew          = 1 // Допустимое расхождение часов в шагах
+)
+
+// MFAPolicy настройки второго фактора
+type MFAPolicy struct {
+	Required     bool          // Второй фактор обязателен для всех пользователей
+	Issuer       string        // Название сервиса в приложении-аутентификаторе
+	ChallengeTTL time.Duration // Срок действия токена между вводом пароля и кода
+}
+
+type mfaClaims struct {
+	UserID string `json:"user_id"`
+	Device string `json:"device"`
+	jwt.RegisteredClaims
+}
+
+func (c *mfaClaims) Registered() *jwt.RegisteredClaims {
+	return &c.RegisteredClaims
+}
+
+// requiresMFA возвращает подключенный второй фактор пользователя и признак того,
+// что вход возможен только после его проверки
+func (s *AuthService) requiresMFA(ctx context.Context, user *model.User) (*model.TOTP, bool, error) {
+	t, err := s.userRepo.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	return t, t.Enabled() || s.mfa.Required, nil
+}
+
+// issueMFAChallenge выдает короткоживущий токен для второго шага входа
+func (s *AuthService) issueMFAChallenge(user *model.User, t *model.TOTP, device string) (*model.MFAChallenge, error) {
+	claims := &mfaClaims{
+		UserID: user.ID,
+		Device: device,
+	}
+
+	token, expiresAt, err := s.tokens.Issue(claims, mfaAudience, s.mfa.ChallengeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign mfa token: %w", err)
+	}
+
+	return &model.MFAChallenge{
+		MFAToken:           token,
+		ExpiresAt:          expiresAt,
+		EnrollmentRequired: !t.Enabled(),
+	}, nil
+}
+
+// parseMFAChallenge проверяет токен второго шага и возвращает пользователя
+func (s *AuthService) parseMFAChallenge(ctx context.Context, tokenString string) (*model.User, *mfaClaims, error) {
+	claims := &mfaClaims{}
+	if err := s.tokens.Verify(tokenString, claims, mfaAudience); err != nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	if user.Disabled() {
+		return nil, nil, ErrUserDisabled
+	}
+
+	return user, claims, nil
+}
+
+// CompleteMFA завершает вход проверкой кода из приложения или кода восстановления.
+// Если второй фактор обязателен, но не подключен, код подтверждает подключение,
+// начатое через BeginMFAEnrollment, и в ответе возвращаются коды восстановления
+func (s *AuthService) CompleteMFA(mfaToken, code, ip string) (*model.TokenPair, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, claims, err := s.parseMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Подбор кода ограничивается так же, как подбор пароля
+	if s.guard != nil {
+		if err := s.guard.Attempt(ctx, user.Login, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	t, err := s.userRepo.GetTOTP(ctx, user.ID)
+	if err != nil {
+		s.releaseAttempt(ctx, user.Login, ip)
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if t.Enabled() {
+		err = s.verifySecondFactor(ctx, t, code)
+	} else if t != nil && s.mfa.Required {
+		recoveryCodes, err = s.enableTOTP(ctx, t, code)
+	} else {
+		s.releaseAttempt(ctx, user.Login, ip)
+		return nil, ErrTOTPNotEnrolled
+	}
+	if errors.Is(err, ErrInvalidTOTPCode) || errors.Is(err, ErrInvalidMFACode) {
+		return nil, ErrInvalidMFACode
+	}
+	if err != nil {
+		// Неудачей считается только неверный код
+		s.releaseAttempt(ctx, user.Login, ip)
+		return nil, err
+	}
+
+	if s.guard != nil {
+		if err := s.guard.Release(ctx, user.Login, ip); err != nil {
+			return nil, fmt.Errorf("failed to record login attempt: %w", err)
+		}
+		if err := s.guard.Succeed(ctx, user.Login); err != nil {
+			return nil, fmt.Errorf("failed to reset login attempts: %w", err)
+		}
+	}
+
+	tokens, err := s.createSession(ctx, user, claims.Device, ip)
+	if err != nil {
+		return nil, err
+	}
+	tokens.RecoveryCodes = recoveryCodes
+
+	return tokens, nil
+}
+
+// BeginMFAEnrollment выдает секрет при входе пользователя, которому политика
+// требует второй фактор, но который его еще не подключил
+func (s *AuthService) BeginMFAEnrollment(mfaToken string) (*model.TOTPEnrollment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, _, err := s.parseMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.BeginTOTPEnrollment(ctx, user)
+}
+
+// BeginTOTPEnrollment создает новый секрет, который нужно подтвердить кодом через ConfirmTOTP
+func (s *AuthService) BeginTOTPEnrollment(ctx context.Context, user *model.User) (*model.TOTPEnrollment, error) {
+	t, err := s.userRepo.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Enabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.SaveTOTPSecret(ctx, user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	return &model.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.mfa.Issuer, user.Login, secret),
+	}, nil
+}
+
+// ConfirmTOTP подключает второй фактор после проверки первого кода и возвращает коды восстановления
+func (s *AuthService) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	t, err := s.userRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Enabled() {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if t == nil {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	return s.enableTOTP(ctx, t, code)
+}
+
+// DisableTOTP отключает второй фактор после проверки пароля
+func (s *AuthService) DisableTOTP(ctx context.Context, userID, password string) error {
+	if s.mfa.Required {
+		return ErrTOTPRequired
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if !s.checkPassword(user.Password, password) {
+		return ErrWrongPassword
+	}
+
+	return s.userRepo.DeleteTOTP(ctx, userID)
+}
+
+// ResetTOTP отключает второй фактор пользователя, потерявшего устройство (вызывается администратором).
+// При обязательном втором факторе пользователь подключит его заново при следующем входе
+func (s *AuthService) ResetTOTP(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	return s.userRepo.DeleteTOTP(ctx, userID)
+}
+
+func (s *AuthService) enableTOTP(ctx context.Context, t *model.TOTP, code string) ([]string, error) {
+	step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.EnableTOTP(ctx, t.UserID, step, hashes, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// verifySecondFactor принимает код из приложения (каждый не более одного раза) или код восстановления
+func (s *AuthService) verifySecondFactor(ctx context.Context, t *model.TOTP, code string) error {
+	if step, ok := totp.Validate(t.Secret, code, time.Now(), totpSkew); ok {
+		used, err := s.userRepo.UseTOTPStep(ctx, t.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	consumed, err := s.userRepo.ConsumeRecoveryCode(ctx, t.UserID, hashToken(normalizeRecoveryCode(code)), time.Now())
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// generateRecoveryCodes возвращает коды восстановления и их хеши для хранения
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := strings.ToLower(encoding.EncodeToString(raw))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
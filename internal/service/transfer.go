@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"docs-server/internal/model"
+)
+
+// DeleteOwner удаляет пользователя userID вместе с документами, включая корзину, в одной транзакции.
+// Документы передаются пользователю transferTo (ID), а если он пустой - удаляются окончательно.
+// Если хотя бы один удаляемый документ находится на юридическом удержании, ничего не удаляется
+func (s *DocumentService) DeleteOwner(ctx context.Context, userID, transferTo string) error {
+	ids, err := s.docRepo.GetOwnedDocumentIDs(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	var outbox []*model.OutboxMessage
+	if transferTo == "" {
+		for _, id := range ids {
+			doc, err := s.docRepo.GetDocumentByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			if doc == nil {
+				continue
+			}
+			if doc.LegalHold {
+				return fmt.Errorf("%w: %s", ErrLegalHold, doc.ID)
+			}
+
+			// Для документа из корзины событие удаления уже было при перемещении в нее
+			if doc.DeletedAt == nil {
+				messages, err := s.eventMessages(model.EventDocumentDeleted, doc, map[string]interface{}{"purged": true})
+				if err != nil {
+					return err
+				}
+				outbox = append(outbox, messages...)
+			}
+		}
+	}
+
+	pending, err := s.userRepo.DeleteUserWithDocuments(ctx, userID, transferTo, outbox...)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	// Файлы удаляются после коммита; неудачные попытки повторит PendingDeleteWorker
+	now := time.Now()
+	for _, p := range pending {
+		s.removePendingFile(ctx, p, now)
+	}
+
+	// Инвалидация кеша: в кешированных документах указан прежний владелец
+	for _, id := range ids {
+		s.cache.Delete("doc_" + id)
+	}
+	for _, id := range []string{userID, transferTo} {
+		s.cache.Delete("docs_" + id)
+		s.cache.Delete("usage_" + id)
+	}
+
+	s.outbox.Notify()
+
+	return nil
+}
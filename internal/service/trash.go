@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"docs-server/internal/model"
+)
+
+// GetTrash возвращает документы пользователя, находящиеся в корзине
+func (s *DocumentService) GetTrash(user *model.User, limit int) ([]*model.Document, error) {
+	return s.docRepo.GetTrashedDocuments(context.Background(), user.ID, limit)
+}
+
+// RestoreDocument возвращает документ из корзины
+func (s *DocumentService) RestoreDocument(user *model.User, id string) (*model.Document, error) {
+	doc, err := s.getTrashedDocument(context.Background(), user.ID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.DeletedAt = nil
+	outbox, err := s.eventMessages(model.EventDocumentUpdated, doc, map[string]interface{}{"restored": true})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.docRepo.RestoreDocument(context.Background(), id, outbox...); err != nil {
+		return nil, fmt.Errorf("failed to restore document: %w", err)
+	}
+
+	s.cache.Delete("docs_" + user.ID)
+
+	s.outbox.Notify()
+
+	return doc, nil
+}
+
+// PurgeDocument окончательно удаляет документ из корзины
+func (s *DocumentService) PurgeDocument(user *model.User, id string) (bool, error) {
+	doc, err := s.getTrashedDocument(context.Background(), user.ID, id)
+	if err != nil {
+		return false, err
+	}
+	if doc.LegalHold {
+		return false, ErrLegalHold
+	}
+
+	if err := s.purgeDocument(context.Background(), doc); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// PurgeTrash окончательно удаляет документы, находящиеся в корзине дольше retention
+func (s *DocumentService) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	ids, err := s.docRepo.GetTrashedBefore(ctx, time.Now().Add(-retention), 100)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list trashed documents: %w", err)
+	}
+
+	purged := 0
+	for _, id := range ids {
+		doc, err := s.docRepo.GetDocumentByID(ctx, id)
+		if err != nil {
+			return purged, err
+		}
+		if doc == nil || doc.LegalHold {
+			continue
+		}
+
+		if err := s.purgeDocument(ctx, doc); err != nil {
+			return purged, fmt.Errorf("failed to purge document %s: %w", id, err)
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// getTrashedDocument возвращает документ из корзины, проверяя владельца
+func (s *DocumentService) getTrashedDocument(ctx context.Context, userID, id string) (*model.Document, error) {
+	doc, err := s.docRepo.GetDocumentByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil || doc.DeletedAt == nil {
+		return nil, ErrDocumentNotFound
+	}
+	if doc.Owner != userID {
+		return nil, ErrNotDocumentOwner
+	}
+	return doc, nil
+}
+
+// purgeDocument удаляет запись документа, а затем его файл.
+// Если файл удалить не удалось, он остается в очереди pending_deletes
+// и удаляется повторно в фоне.
+func (s *DocumentService) purgeDocument(ctx context.Context, doc *model.Document) error {
+	// Для документа из корзины событие удаления уже было при перемещении в нее
+	var outbox []*model.OutboxMessage
+	if doc.DeletedAt == nil {
+		var err error
+		if outbox, err = s.eventMessages(model.EventDocumentDeleted, doc, map[string]interface{}{"purged": true}); err != nil {
+			return err
+		}
+	}
+
+	// Удаление из БД вместе с правами доступа, постановкой файла в очередь и событием
+	pending, err := s.docRepo.DeleteDocument(ctx, doc.ID, outbox...)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	// Инвалидация кеша
+	s.cache.Delete("doc_" + doc.ID)
+	s.cache.Delete("usage_" + doc.Owner)
+
+	// Удаление файла после коммита
+	if pending != nil {
+		s.removePendingFile(ctx, pending, time.Now())
+	}
+
+	s.outbox.Notify()
+
+	return nil
+}
+
+// TrashPurger периодически очищает корзину от устаревших документов
+type TrashPurger struct {
+	docService *DocumentService
+	retention  time.Duration
+	interval   time.Duration
+}
+
+func NewTrashPurger(docService *DocumentService, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{
+		docService: docService,
+		retention:  retention,
+		interval:   interval,
+	}
+}
+
+// Run запускает очистку корзины до отмены контекста
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := p.docService.PurgeTrash(ctx, p.retention)
+			if err != nil {
+				log.Printf("trash purger: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("trash purger: purged %d documents", purged)
+			}
+		}
+	}
+}
@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"docs-server/internal/model"
 	"docs-server/internal/repository"
 	"errors"
+	"fmt"
+	"time"
 )
 
 var (
@@ -13,15 +16,21 @@ var (
 	ErrUserNil       = errors.New("user cannot be nil")
 	ErrInvalidLimit  = errors.New("limit must be positive")
 	ErrInvalidOffset = errors.New("offset cannot be negative")
+	ErrLastAdmin     = errors.New("cannot disable, demote or delete the last active admin")
+	ErrSameUser      = errors.New("cannot transfer documents to the deleted user")
 )
 
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo    *repository.UserRepository
+	authService *AuthService
+	docService  *DocumentService
 }
 
-func NewUserService(userRepo *repository.UserRepository) *UserService {
+func NewUserService(userRepo *repository.UserRepository, authService *AuthService, docService *DocumentService) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		authService: authService,
+		docService:  docService,
 	}
 }
 
@@ -59,56 +68,173 @@ func (s *UserService) GetUserByLogin(ctx context.Context, login string) (*model.
 	return user, nil
 }
 
-// UpdateUser обновляет данные пользователя
-func (s *UserService) UpdateUser(ctx context.Context, user *model.User) error {
-	if user == nil {
-		return ErrUserNil
-	}
-	if user.ID == "" {
-		return ErrUserIDEmpty
+// UpdateUser переименовывает, блокирует, меняет роль или квоты пользователя
+func (s *UserService) UpdateUser(ctx context.Context, id string, patch *model.UserPatch) (*model.User, error) {
+	if patch == nil {
+		return nil, ErrUserNil
 	}
 
-	// Проверяем, существует ли пользователь
-	existingUser, err := s.userRepo.GetUserByID(ctx, user.ID)
+	user, err := s.GetUserByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if existingUser == nil {
-		return ErrUserNotFound
+	previousLogin, wasAdmin := user.Login, user.IsAdmin
+
+	if patch.Login != nil && *patch.Login != user.Login {
+		if err := s.authService.policy.ValidateLogin(*patch.Login); err != nil {
+			return nil, err
+		}
+		existing, err := s.userRepo.GetUserByLogin(ctx, *patch.Login)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, ErrLoginTaken
+		}
+		user.Login = *patch.Login
+	}
+	if patch.Email != nil {
+		if err := validateEmail(*patch.Email); err != nil {
+			return nil, err
+		}
+		user.Email = *patch.Email
+	}
+
+	// Снятие роли или блокировка не должны оставить систему без администратора
+	demoted := patch.IsAdmin != nil && !*patch.IsAdmin
+	disabled := patch.Disabled != nil && *patch.Disabled
+	if user.IsAdmin && !user.Disabled() && (demoted || disabled) {
+		if err := s.checkNotLastAdmin(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if patch.IsAdmin != nil {
+		user.IsAdmin = *patch.IsAdmin
+	}
+	wasDisabled := user.Disabled()
+	if patch.Disabled != nil {
+		if *patch.Disabled && !wasDisabled {
+			now := time.Now().Truncate(time.Second)
+			user.DisabledAt = &now
+		} else if !*patch.Disabled {
+			user.DisabledAt = nil
+		}
+	}
+
+	// Квоты сохраняются тем же запросом, чтобы изменение не применилось частично
+	if err := s.userRepo.UpdateUserWithQuota(ctx, user, quotaValue(patch.QuotaBytes), quotaValue(patch.QuotaDocuments)); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	if patch.QuotaBytes != nil || patch.QuotaDocuments != nil {
+		s.docService.InvalidateUsage(user.ID)
+	}
+
+	// Заблокированный пользователь теряет все сессии и ключи
+	if user.Disabled() && !wasDisabled {
+		if err := s.authService.RevokeUserAccess(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	} else if user.Login != previousLogin || user.IsAdmin != wasAdmin {
+		// Логин и роль из утверждений прежних access-токенов устарели
+		if err := s.authService.RevokeAccessTokens(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	// Владелец API-ключа кешируется вместе с ключом
+	if user.Login != previousLogin || user.IsAdmin != wasAdmin || user.Disabled() != wasDisabled {
+		if err := s.authService.ForgetAPIKeys(ctx, user.ID); err != nil {
+			return nil, err
+		}
 	}
 
-	// Обновляем только разрешенные поля
-	existingUser.Login = user.Login
+	return user, nil
+}
 
-	return s.userRepo.UpdateUser(ctx, existingUser)
+// quotaValue переводит квоту из UserPatch в значение столбца: отрицательная возвращает квоту по умолчанию
+func quotaValue(quota *int64) *sql.NullInt64 {
+	if quota == nil {
+		return nil
+	}
+	return &sql.NullInt64{Int64: *quota, Valid: *quota >= 0}
 }
 
-// DeleteUser удаляет пользователя по ID
-func (s *UserService) DeleteUser(ctx context.Context, id string) error {
-	if id == "" {
-		return ErrUserIDEmpty
+// DeleteUser удаляет пользователя. Документы передаются пользователю transferTo (логин),
+// а если он не указан - удаляются окончательно
+func (s *UserService) DeleteUser(ctx context.Context, id, transferTo string) error {
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return err
 	}
 
-	// Проверяем, существует ли пользователь
-	user, err := s.userRepo.GetUserByID(ctx, id)
+	if user.IsAdmin && !user.Disabled() {
+		if err := s.checkNotLastAdmin(ctx); err != nil {
+			return err
+		}
+	}
+
+	return s.deleteUser(ctx, user, transferTo)
+}
+
+// deleteUser удаляет учетную запись вместе с документами (или передает их) и отзывает доступ пользователя.
+// Удаление выполняется одной транзакцией, а доступ отзывается только после ее коммита:
+// если удаление не удалось (например, из-за юридического удержания), сессии и ключи остаются,
+// и его можно повторить
+func (s *UserService) deleteUser(ctx context.Context, user *model.User, transferTo string) error {
+	var targetID string
+	if transferTo != "" {
+		target, err := s.GetUserByLogin(ctx, transferTo)
+		if err != nil {
+			return err
+		}
+		if target.ID == user.ID {
+			return ErrSameUser
+		}
+		targetID = target.ID
+	}
+
+	access, err := s.authService.listUserAccess(ctx, user.ID)
 	if err != nil {
 		return err
 	}
-	if user == nil {
-		return ErrUserNotFound
+
+	if err := s.docService.DeleteOwner(ctx, user.ID, targetID); err != nil {
+		return err
 	}
 
-	return s.userRepo.DeleteUser(ctx, id)
+	return s.authService.revokeDeletedUserAccess(ctx, access)
 }
 
-// ListUsers возвращает список пользователей с пагинацией
-func (s *UserService) ListUsers(ctx context.Context, limit, offset int) ([]*model.User, error) {
+// ListUsers возвращает список пользователей с пагинацией и общее количество
+func (s *UserService) ListUsers(ctx context.Context, limit, offset int) ([]*model.User, int, error) {
 	if limit <= 0 {
-		return nil, ErrInvalidLimit
+		return nil, 0, ErrInvalidLimit
 	}
 	if offset < 0 {
-		return nil, ErrInvalidOffset
+		return nil, 0, ErrInvalidOffset
+	}
+
+	users, err := s.userRepo.ListUsers(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return s.userRepo.ListUsers(ctx, limit, offset)
+	total, err := s.userRepo.CountUsers(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// checkNotLastAdmin проверяет, что в системе останется хотя бы один активный администратор
+func (s *UserService) checkNotLastAdmin(ctx context.Context) error {
+	admins, err := s.userRepo.CountActiveAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
 }
@@ -0,0 +1,490 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"docs-server/internal/model"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	webhookBaseDelay  = 30 * time.Second
+	webhookMaxDelay   = time.Hour
+	maxWebhookURL     = 2048
+	maxDeliveryError  = 1024
+	maxDeliveriesPage = 1000
+)
+
+var (
+	ErrWebhookNotFound     = errors.New("webhook not found")
+	ErrDeliveryNotFound    = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL   = errors.New("invalid webhook url")
+	ErrInvalidWebhookEvent = errors.New("unknown webhook event type")
+	ErrInvalidSignature    = errors.New("invalid webhook signature")
+)
+
+// webhookEventTypes события, на которые можно подписать webhook
+var webhookEventTypes = map[string]bool{
+	model.EventDocumentCreated: true,
+	model.EventDocumentUpdated: true,
+	model.EventDocumentDeleted: true,
+	model.EventGrantChanged:    true,
+}
+
+// WebhookStore хранилище webhook и журнала доставок
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, hook *model.Webhook) error
+	GetWebhook(ctx context.Context, id string) (*model.Webhook, error)
+	ListWebhooks(ctx context.Context, ownerID string, global bool) ([]*model.Webhook, error)
+	ListAllWebhooks(ctx context.Context) ([]*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	CreateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*model.WebhookDelivery, error)
+	DeliveryExists(ctx context.Context, webhookID, eventID string) (bool, error)
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+}
+
+// WebhookPolicy настройки доставки webhook
+type WebhookPolicy struct {
+	Timeout      time.Duration // Время ожидания ответа получателя
+	MaxAttempts  int           // Попыток до перевода доставки в failed
+	AllowPrivate bool          // Разрешить адреса локальной сети и loopback
+}
+
+// WebhookService регистрация webhook и доставка событий документов с подписью HMAC
+type WebhookService struct {
+	store  WebhookStore
+	policy WebhookPolicy
+	client *http.Client
+	wake   chan struct{}
+}
+
+func NewWebhookService(store WebhookStore, policy WebhookPolicy) *WebhookService {
+	dialer := &net.Dialer{Timeout: policy.Timeout}
+	if !policy.AllowPrivate {
+		// Проверяется адрес, к которому действительно идет подключение, после разрешения имени
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return fmt.Errorf("%w: private address %s", ErrInvalidWebhookURL, host)
+			}
+			return nil
+		}
+	}
+
+	return &WebhookService{
+		store:  store,
+		policy: policy,
+		client: &http.Client{
+			Timeout:   policy.Timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			// Перенаправления не выполняются: ответ 3xx считается неудачей
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// CreateWebhook регистрирует webhook. Ключ подписи возвращается только здесь
+func (s *WebhookService) CreateWebhook(ownerID string, global bool, rawURL string, events []string) (*model.Webhook, string, error) {
+	if err := s.validateURL(rawURL); err != nil {
+		return nil, "", err
+	}
+	for _, event := range events {
+		if !webhookEventTypes[event] {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidWebhookEvent, event)
+		}
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	hook := &model.Webhook{
+		ID:        id,
+		OwnerID:   ownerID,
+		Global:    global,
+		URL:       rawURL,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.store.CreateWebhook(ctx, hook); err != nil {
+		return nil, "", err
+	}
+	return hook, secret, nil
+}
+
+// ListWebhooks возвращает webhook пользователя или глобальные webhook
+func (s *WebhookService) ListWebhooks(ownerID string, global bool) ([]*model.Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.store.ListWebhooks(ctx, ownerID, global)
+}
+
+// DeleteWebhook удаляет webhook вместе с журналом его доставок
+func (s *WebhookService) DeleteWebhook(ownerID string, global bool, id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.getWebhook(ctx, ownerID, global, id); err != nil {
+		return err
+	}
+	return s.store.DeleteWebhook(ctx, id)
+}
+
+// ListDeliveries возвращает последние доставки webhook, новые первыми
+func (s *WebhookService) ListDeliveries(ownerID string, global bool, webhookID string, limit int) ([]*model.WebhookDelivery, error) {
+	if limit <= 0 || limit > maxDeliveriesPage {
+		return nil, ErrInvalidLimit
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.getWebhook(ctx, ownerID, global, webhookID); err != nil {
+		return nil, err
+	}
+	return s.store.ListDeliveries(ctx, webhookID, limit)
+}
+
+// Redeliver ставит событие доставки в очередь повторно. Создается новая доставка,
+// прежняя остается в журнале без изменений
+func (s *WebhookService) Redeliver(ownerID string, global bool, webhookID, deliveryID string) (*model.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.getWebhook(ctx, ownerID, global, webhookID); err != nil {
+		return nil, err
+	}
+	original, err := s.store.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil || original.WebhookID != webhookID {
+		return nil, ErrDeliveryNotFound
+	}
+
+	delivery, err := s.enqueue(ctx, webhookID, original.EventID, original.EventType, original.Payload)
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return delivery, nil
+}
+
+// Publish ставит событие в очередь доставки всем подписанным на него webhook, которым доступен
+// документ. Повторно переданное событие не ставится в очередь webhook, уже получивших его.
+// Используется как приемник outbox
+func (s *WebhookService) Publish(ctx context.Context, event *model.DocumentEvent) error {
+	hooks, err := s.store.ListAllWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	var payload []byte
+	var firstErr error
+	queued := 0
+	for _, hook := range hooks {
+		if !hook.Accepts(event.Type) || !hook.Global && !event.VisibleTo(hook.OwnerID) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return fmt.Errorf("failed to encode event %s: %w", event.ID, err)
+			}
+		}
+
+		exists, err := s.store.DeliveryExists(ctx, hook.ID, event.ID)
+		if err == nil && !exists {
+			if _, err = s.enqueue(ctx, hook.ID, event.ID, event.Type, payload); err == nil {
+				queued++
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to queue event %s for webhook %s: %w", event.ID, hook.ID, err)
+		}
+	}
+
+	if queued > 0 {
+		s.notify()
+	}
+	return firstErr
+}
+
+// DeliverDue отправляет доставки, срок попытки которых наступил к now. Возвращает количество успешных
+func (s *WebhookService) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	// На время попыток доставки закрепляются за этим экземпляром; если он завершится,
+	// доставки снова станут доступны по истечении аренды. Пакет небольшой, чтобы аренда
+	// не истекла, пока ответы получателей ожидаются по очереди
+	const batch = 10
+	lease := batch*s.policy.Timeout + time.Minute
+	deliveries, err := s.store.ClaimDueDeliveries(ctx, now, lease, batch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	hooks := make(map[string]*model.Webhook)
+	delivered := 0
+	for _, delivery := range deliveries {
+		hook, ok := hooks[delivery.WebhookID]
+		if !ok {
+			if hook, err = s.store.GetWebhook(ctx, delivery.WebhookID); err != nil {
+				return delivered, err
+			}
+			hooks[delivery.WebhookID] = hook
+		}
+		if hook == nil {
+			continue
+		}
+
+		if s.deliver(ctx, hook, delivery, now) {
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+// deliver выполняет одну попытку доставки и сохраняет ее результат
+func (s *WebhookService) deliver(ctx context.Context, hook *model.Webhook, delivery *model.WebhookDelivery, now time.Time) bool {
+	delivery.Attempts++
+	code, err := s.send(ctx, hook, delivery, now)
+	delivery.ResponseCode = code
+
+	if err == nil {
+		delivery.Status = model.DeliveryDelivered
+		delivery.LastError = ""
+		deliveredAt := time.Now().Truncate(time.Second)
+		delivery.DeliveredAt = &deliveredAt
+	} else {
+		delivery.LastError = err.Error()
+		if len(delivery.LastError) > maxDeliveryError {
+			delivery.LastError = delivery.LastError[:maxDeliveryError]
+		}
+		if delivery.Attempts >= s.policy.MaxAttempts {
+			delivery.Status = model.DeliveryFailed
+		} else {
+			delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts)).Truncate(time.Second)
+		}
+	}
+
+	if err := s.store.UpdateDelivery(ctx, delivery); err != nil {
+		log.Printf("webhooks: failed to update delivery %s: %v", delivery.ID, err)
+	}
+	return delivery.Status == model.DeliveryDelivered
+}
+
+// send отправляет событие получателю. Успешной считается доставка с ответом 2xx
+func (s *WebhookService) send(ctx context.Context, hook *model.Webhook, delivery *model.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "docs-server-webhook")
+	req.Header.Set("X-Webhook-ID", delivery.ID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Signature", SignWebhookPayload(hook.Secret, now.Unix(), delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *WebhookService) enqueue(ctx context.Context, webhookID, eventID, eventType string, payload []byte) (*model.WebhookDelivery, error) {
+	id, err := generateID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	delivery := &model.WebhookDelivery{
+		ID:            id,
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if err := s.store.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// notify будит WebhookDispatcher, не дожидаясь очередного интервала
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// getWebhook возвращает webhook, если он принадлежит пользователю (или является глобальным для global)
+func (s *WebhookService) getWebhook(ctx context.Context, ownerID string, global bool, id string) (*model.Webhook, error) {
+	hook, err := s.store.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil || hook.Global != global || !global && hook.OwnerID != ownerID {
+		return nil, ErrWebhookNotFound
+	}
+	return hook, nil
+}
+
+// validateURL допускает абсолютные адреса http и https. Адреса локальной сети,
+// заданные IP-адресом, отклоняются сразу, заданные именем - при подключении
+func (s *WebhookService) validateURL(rawURL string) error {
+	if len(rawURL) > maxWebhookURL {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidWebhookURL, maxWebhookURL)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("%w: absolute http or https url required", ErrInvalidWebhookURL)
+	}
+
+	if !s.policy.AllowPrivate {
+		host := u.Hostname()
+		if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) || strings.EqualFold(host, "localhost") {
+			return fmt.Errorf("%w: private address %s", ErrInvalidWebhookURL, host)
+		}
+	}
+	return nil
+}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// webhookBackoff задержка после неудачной попытки номер attempt
+func webhookBackoff(attempt int) time.Duration {
+	delay := webhookBaseDelay
+	for i := 1; i < attempt && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxDelay {
+		delay = webhookMaxDelay
+	}
+	return delay
+}
+
+// SignWebhookPayload значение заголовка X-Webhook-Signature: "t=<unix time>,v1=<hex>",
+// где v1 - HMAC-SHA256 ключом webhook от строки "<unix time>.<тело запроса>"
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	return "t=" + ts + ",v1=" + webhookMAC(secret, ts, body)
+}
+
+// VerifyWebhookSignature проверяет заголовок X-Webhook-Signature на стороне получателя.
+// Подпись старше tolerance отклоняется, чтобы перехваченный запрос нельзя было повторить
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || signature == "" {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(signature), []byte(webhookMAC(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func webhookMAC(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookDispatcher доставляет события webhook в фоне: сразу после появления новых
+// доставок и периодически для повторных попыток
+type WebhookDispatcher struct {
+	webhookService *WebhookService
+	interval       time.Duration
+}
+
+func NewWebhookDispatcher(webhookService *WebhookService, interval time.Duration) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		webhookService: webhookService,
+		interval:       interval,
+	}
+}
+
+// Run запускает доставку до отмены контекста
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.webhookService.wake:
+		}
+
+		if _, err := d.webhookService.DeliverDue(ctx, time.Now()); err != nil {
+			log.Printf("webhook dispatcher: %v", err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package sink
+
+import (
+	"context"
+	"docs-server/internal/model"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// FileSink дописывает события в файл в формате JSON Lines
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Publish дописывает событие строкой JSON и сбрасывает файл на диск. Файл открывается
+// при каждой записи, поэтому его можно ротировать переименованием
+func (s *FileSink) Publish(ctx context.Context, event *model.DocumentEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(line); err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync event log: %w", err)
+	}
+	return nil
+}
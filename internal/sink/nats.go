@@ -0,0 +1,111 @@
+package sink
+
+import (
+	"context"
+	"docs-server/internal/model"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSConfig подключение к серверу NATS с JetStream
+type NATSConfig struct {
+	URL      string // nats://host:4222 или tls://host:4222
+	Subject  string // Префикс темы, к нему через точку дописывается тип события
+	Token    string
+	Username string
+	Password string
+	Timeout  time.Duration
+}
+
+// NATSSink публикует события в поток JetStream. Тема должна входить в поток: публикация
+// считается успешной только после подтверждения (PubAck) о сохранении сообщения.
+// Сообщение передается с Nats-Msg-Id, равным ID события, по которому JetStream отбрасывает повторы
+type NATSSink struct {
+	config NATSConfig
+
+	mu   sync.Mutex
+	conn *nats.Conn
+	js   nats.JetStreamContext
+}
+
+func NewNATSSink(config NATSConfig) *NATSSink {
+	return &NATSSink{config: config}
+}
+
+// Publish отправляет событие и ждет подтверждения JetStream. Без него (нет потока для темы,
+// ошибка хранилища, таймаут) возвращается ошибка, и outbox повторит публикацию
+func (s *NATSSink) Publish(ctx context.Context, event *model.DocumentEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	js, err := s.jetStream()
+	if err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	// Повтор уже сохраненного события подтверждается с Duplicate и тоже считается успехом
+	if _, err := js.Publish(s.config.Subject+"."+event.Type, payload, nats.MsgId(event.ID), nats.Context(ctx)); err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	return nil
+}
+
+// jetStream возвращает контекст JetStream, подключаясь к серверу при первом вызове
+// или после окончательного закрытия соединения. Обрывы связи клиент восстанавливает сам
+func (s *NATSSink) jetStream() (nats.JetStreamContext, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil && !s.conn.IsClosed() {
+		return s.js, nil
+	}
+
+	options := []nats.Option{nats.Name("docs-server")}
+	if s.config.Timeout > 0 {
+		options = append(options, nats.Timeout(s.config.Timeout))
+	}
+	if s.config.Token != "" {
+		options = append(options, nats.Token(s.config.Token))
+	}
+	if s.config.Username != "" {
+		options = append(options, nats.UserInfo(s.config.Username, s.config.Password))
+	}
+
+	conn, err := nats.Connect(s.config.URL, options...)
+	if err != nil {
+		return nil, err
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	s.conn, s.js = conn, js
+	return js, nil
+}
+
+// Close закрывает соединение с сервером
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.js = nil
+	}
+	return nil
+}
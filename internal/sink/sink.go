@@ -0,0 +1,12 @@
+package sink
+
+import (
+	"context"
+	"docs-server/internal/model"
+)
+
+// Sink приемник событий документов. Событие публикуется не менее одного раза: после сбоя
+// оно отправляется повторно с тем же ID, по которому получатель отбрасывает повторы
+type Sink interface {
+	Publish(ctx context.Context, event *model.DocumentEvent) error
+}
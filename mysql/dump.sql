@@ -3,13 +3,19 @@
 
 CREATE TABLE `users` (
   `id` binary(16) NOT NULL,
-  `login` varchar(50) NOT NULL,
+  `login` varchar(255) NOT NULL,
   `password` varchar(255) NOT NULL,
-  `token` varchar(255) DEFAULT NULL,
-  `token_expiry` datetime DEFAULT NULL,
+  `email` varchar(255) DEFAULT NULL,
+  `quota_bytes` bigint(20) DEFAULT NULL,
+  `quota_docs` int(11) DEFAULT NULL,
+  `is_admin` tinyint(1) NOT NULL DEFAULT 0,
+  `disabled_at` datetime DEFAULT NULL,
   `created_at` datetime DEFAULT current_timestamp(),
+  `auth_source` varchar(16) NOT NULL DEFAULT 'local',
+  `external_id` varchar(512) DEFAULT NULL,
   PRIMARY KEY (`id`),
-  UNIQUE KEY `login` (`login`)
+  UNIQUE KEY `login` (`login`),
+  UNIQUE KEY `external_id` (`auth_source`,`external_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 
 CREATE TABLE `documents` (
@@ -22,8 +28,14 @@ CREATE TABLE `documents` (
   `owner_id` binary(16) NOT NULL,
   `file_path` varchar(255) DEFAULT NULL,
   `json_data` text DEFAULT NULL,
+  `size` bigint(20) NOT NULL DEFAULT 0,
+  `deleted_at` datetime DEFAULT NULL,
+  `expires_at` datetime DEFAULT NULL,
+  `legal_hold` tinyint(1) NOT NULL DEFAULT 0,
   PRIMARY KEY (`id`),
   KEY `owner_id` (`owner_id`),
+  KEY `deleted_at` (`deleted_at`),
+  KEY `expires_at` (`expires_at`),
   CONSTRAINT `documents_ibfk_1` FOREIGN KEY (`owner_id`) REFERENCES `users` (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
 
@@ -34,4 +46,214 @@ CREATE TABLE `document_grants` (
   KEY `user_id` (`user_id`),
   CONSTRAINT `document_grants_ibfk_1` FOREIGN KEY (`document_id`) REFERENCES `documents` (`id`),
   CONSTRAINT `document_grants_ibfk_2` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
\ No newline at end of file
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `pending_deletes` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `file_path` varchar(255) NOT NULL,
+  `document_id` binary(16) NOT NULL,
+  `attempts` int(11) NOT NULL DEFAULT 0,
+  `last_error` text DEFAULT NULL,
+  `next_attempt_at` datetime NOT NULL,
+  `created_at` datetime DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `file_path` (`file_path`),
+  KEY `next_attempt_at` (`next_attempt_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `sessions` (
+  `id` binary(16) NOT NULL,
+  `user_id` binary(16) NOT NULL,
+  `device` varchar(255) DEFAULT NULL,
+  `ip` varchar(45) DEFAULT NULL,
+  `access_token_hash` char(64) NOT NULL,
+  `access_token_id` varchar(36) NOT NULL DEFAULT '',
+  `access_expiry` datetime NOT NULL,
+  `refresh_expiry` datetime NOT NULL,
+  `created_at` datetime NOT NULL,
+  `last_used_at` datetime NOT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `user_id` (`user_id`),
+  KEY `access_token_hash` (`access_token_hash`),
+  CONSTRAINT `sessions_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `refresh_tokens` (
+  `token_hash` char(64) NOT NULL,
+  `session_id` binary(16) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`token_hash`),
+  KEY `session_id` (`session_id`),
+  CONSTRAINT `refresh_tokens_ibfk_1` FOREIGN KEY (`session_id`) REFERENCES `sessions` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `api_keys` (
+  `id` binary(16) NOT NULL,
+  `user_id` binary(16) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `prefix` varchar(16) NOT NULL,
+  `key_hash` char(64) NOT NULL,
+  `read_only` tinyint(1) NOT NULL DEFAULT 0,
+  `expires_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  `last_used_at` datetime DEFAULT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `key_hash` (`key_hash`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `api_keys_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `password_resets` (
+  `token_hash` char(64) NOT NULL,
+  `user_id` binary(16) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` datetime DEFAULT current_timestamp(),
+  PRIMARY KEY (`token_hash`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `password_resets_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `user_totp` (
+  `user_id` binary(16) NOT NULL,
+  `secret` varchar(64) NOT NULL,
+  `enabled_at` datetime DEFAULT NULL,
+  `last_step` bigint(20) NOT NULL DEFAULT 0,
+  `created_at` datetime DEFAULT current_timestamp(),
+  PRIMARY KEY (`user_id`),
+  CONSTRAINT `user_totp_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `totp_recovery_codes` (
+  `code_hash` char(64) NOT NULL,
+  `user_id` binary(16) NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`code_hash`),
+  KEY `user_id` (`user_id`),
+  CONSTRAINT `totp_recovery_codes_ibfk_1` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `login_attempts` (
+  `attempt_key` varchar(255) NOT NULL,
+  `failures` int(11) NOT NULL DEFAULT 0,
+  `last_failure` datetime NOT NULL,
+  `locked_until` datetime DEFAULT NULL,
+  `expires_at` datetime NOT NULL,
+  PRIMARY KEY (`attempt_key`),
+  KEY `expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `revoked_tokens` (
+  `token_id` varchar(36) NOT NULL,
+  `expires_at` datetime NOT NULL,
+  PRIMARY KEY (`token_id`),
+  KEY `expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `audit_events` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL,
+  `action` varchar(64) NOT NULL,
+  `outcome` varchar(16) NOT NULL,
+  `actor_id` varchar(36) DEFAULT NULL,
+  `actor_login` varchar(255) DEFAULT NULL,
+  `ip` varchar(45) DEFAULT NULL,
+  `user_agent` varchar(512) DEFAULT NULL,
+  `document_id` varchar(36) DEFAULT NULL,
+  `details` json DEFAULT NULL,
+  `prev_hash` char(64) NOT NULL DEFAULT '',
+  `hash` char(64) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  KEY `created_at` (`created_at`),
+  KEY `actor_id` (`actor_id`, `created_at`),
+  KEY `document_id` (`document_id`, `created_at`),
+  KEY `action` (`action`, `created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Журнал аудита только дополняется
+CREATE TRIGGER `audit_events_no_update` BEFORE UPDATE ON `audit_events`
+  FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
+CREATE TRIGGER `audit_events_no_delete` BEFORE DELETE ON `audit_events`
+  FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
+
+-- Последняя запись цепочки хешей журнала аудита: блокируется при добавлении записи
+CREATE TABLE `audit_chain` (
+  `id` tinyint(4) NOT NULL,
+  `last_id` bigint(20) NOT NULL DEFAULT 0,
+  `last_hash` char(64) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+INSERT INTO `audit_chain` (`id`, `last_id`, `last_hash`) VALUES (1, 0, '');
+
+CREATE TABLE `audit_checkpoints` (
+  `day` date NOT NULL,
+  `last_id` bigint(20) NOT NULL,
+  `last_hash` char(64) NOT NULL,
+  `events` int(11) NOT NULL,
+  `signature` text NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`day`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `webhooks` (
+  `id` binary(16) NOT NULL,
+  `owner_id` binary(16) NOT NULL,
+  `is_global` tinyint(1) NOT NULL DEFAULT 0,
+  `url` varchar(2048) NOT NULL,
+  `events` json NOT NULL,
+  `secret` varchar(64) NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `owner_id` (`owner_id`),
+  CONSTRAINT `webhooks_ibfk_1` FOREIGN KEY (`owner_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `webhook_deliveries` (
+  `id` binary(16) NOT NULL,
+  `webhook_id` binary(16) NOT NULL,
+  `event_id` char(36) NOT NULL,
+  `event_type` varchar(50) NOT NULL,
+  `payload` mediumtext NOT NULL,
+  `status` varchar(16) NOT NULL,
+  `attempts` int(11) NOT NULL DEFAULT 0,
+  `response_code` int(11) DEFAULT NULL,
+  `last_error` varchar(1024) DEFAULT NULL,
+  `next_attempt_at` datetime NOT NULL,
+  `delivered_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `webhook_id` (`webhook_id`, `created_at`),
+  KEY `webhook_event` (`webhook_id`, `event_id`),
+  KEY `due` (`status`, `next_attempt_at`),
+  CONSTRAINT `webhook_deliveries_ibfk_1` FOREIGN KEY (`webhook_id`) REFERENCES `webhooks` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `document_events` (
+  `seq` bigint(20) NOT NULL AUTO_INCREMENT,
+  `event_id` char(36) NOT NULL,
+  `payload` mediumtext NOT NULL,
+  `created_at` datetime(6) NOT NULL,
+  PRIMARY KEY (`seq`),
+  UNIQUE KEY `event_id` (`event_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE `event_outbox` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `sink` varchar(32) NOT NULL,
+  `event_id` char(36) NOT NULL,
+  `event_type` varchar(50) NOT NULL,
+  `payload` mediumtext NOT NULL,
+  `status` varchar(16) NOT NULL,
+  `attempts` int(11) NOT NULL DEFAULT 0,
+  `last_error` varchar(1024) DEFAULT NULL,
+  `next_attempt_at` datetime NOT NULL,
+  `published_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `event_sink` (`event_id`, `sink`),
+  KEY `due` (`status`, `next_attempt_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;